@@ -0,0 +1,121 @@
+package render
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/Bornholm/amatl/pkg/resolver"
+	"github.com/pkg/errors"
+	"github.com/urfave/cli/v2"
+)
+
+type depsManifest struct {
+	Output       string                `json:"output"`
+	Dependencies []resolver.Dependency `json:"dependencies"`
+}
+
+// writeDepsFile writes the dependencies recorded by the given tracker to
+// the file specified by the --deps-file flag, if any. A file with the .json
+// extension receives a JSON manifest, any other a Make compatible rule
+// targeting the output file, or the input file if written to stdout.
+func writeDepsFile(ctx *cli.Context, tracker *resolver.Tracker) error {
+	depsFile := ctx.String(paramDepsFile)
+	if depsFile == "" {
+		return nil
+	}
+
+	output := ctx.String(paramOutput)
+	dependencies := tracker.Dependencies()
+
+	file, err := os.Create(depsFile)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	defer func() {
+		if err := file.Close(); err != nil {
+			panic(errors.WithStack(err))
+		}
+	}()
+
+	if filepath.Ext(depsFile) == ".json" {
+		manifest := depsManifest{
+			Output:       output,
+			Dependencies: dependencies,
+		}
+
+		encoder := json.NewEncoder(file)
+		encoder.SetIndent("", "  ")
+
+		if err := encoder.Encode(manifest); err != nil {
+			return errors.WithStack(err)
+		}
+
+		return nil
+	}
+
+	// Without output file, the rule targets the
+	// rendered document, as given to the command
+	target := output
+	if target == "-" {
+		target = ctx.Args().First()
+	}
+
+	if err := writeMakeRule(file, target, dependencies); err != nil {
+		return errors.WithStack(err)
+	}
+
+	return nil
+}
+
+// writeMakeRule writes a Make rule with the local file dependencies as
+// prerequisites of the target, followed by an empty rule for each of them
+// so that make does not fail when a dependency is removed.
+func writeMakeRule(w io.Writer, target string, dependencies []resolver.Dependency) error {
+	files := watchableFiles(dependencyPaths(dependencies))
+
+	if _, err := fmt.Fprintf(w, "%s:", escapeMakePath(target)); err != nil {
+		return errors.WithStack(err)
+	}
+
+	for _, f := range files {
+		if _, err := fmt.Fprintf(w, " \\\n  %s", escapeMakePath(f)); err != nil {
+			return errors.WithStack(err)
+		}
+	}
+
+	if _, err := fmt.Fprintln(w); err != nil {
+		return errors.WithStack(err)
+	}
+
+	for _, f := range files {
+		if _, err := fmt.Fprintf(w, "\n%s:\n", escapeMakePath(f)); err != nil {
+			return errors.WithStack(err)
+		}
+	}
+
+	return nil
+}
+
+func dependencyPaths(dependencies []resolver.Dependency) []resolver.Path {
+	paths := make([]resolver.Path, 0, len(dependencies))
+	for _, d := range dependencies {
+		paths = append(paths, d.Path)
+	}
+	return paths
+}
+
+var makePathReplacer = strings.NewReplacer(
+	" ", `\ `,
+	"#", `\#`,
+	"$", "$$",
+	":", `\:`,
+)
+
+func escapeMakePath(path string) string {
+	return makePathReplacer.Replace(filepath.ToSlash(path))
+}
@@ -5,15 +5,21 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"log/slog"
 	"net/url"
 	"os"
 	"path/filepath"
 	"regexp"
+	"slices"
 	"strings"
 	"time"
 
+	"github.com/Bornholm/amatl/pkg/diagram"
 	"github.com/Bornholm/amatl/pkg/html/layout"
 	"github.com/Bornholm/amatl/pkg/html/layout/resolver/amatl"
+	"github.com/Bornholm/amatl/pkg/markdown/directive"
+	"github.com/Bornholm/amatl/pkg/markdown/directive/include"
+	"github.com/Bornholm/amatl/pkg/markdown/directive/plugin"
 	"github.com/Bornholm/amatl/pkg/resolver"
 	"github.com/Bornholm/amatl/pkg/transform"
 	"gopkg.in/yaml.v3"
@@ -25,17 +31,23 @@ import (
 	// Register resolver schemes
 
 	_ "github.com/Bornholm/amatl/pkg/resolver/file"
-	_ "github.com/Bornholm/amatl/pkg/resolver/http"
+	httpResolver "github.com/Bornholm/amatl/pkg/resolver/http"
 	_ "github.com/Bornholm/amatl/pkg/resolver/stdin"
 )
 
 const (
+	paramConfig                 = "config"
 	paramOutput                 = "output"
 	paramTemplateVars           = "vars"
 	paramTemplateLeftDelimiter  = "template-left-delimiter"
 	paramTemplateRightDelimiter = "template-right-delimiter"
 	paramLinkReplacements       = "link-replacements"
 	paramHTMLLayout             = "html-layout"
+	paramHTMLMermaidURL         = "html-mermaid-url"
+	paramHTMLAssetURLs          = "html-asset-urls"
+	paramHTMLMermaidMode        = "html-mermaid-mode"
+	paramHTMLDotPath            = "html-dot-path"
+	paramHTMLD2Path             = "html-d2-path"
 	paramHTMLLayoutVars         = "html-layout-vars"
 	paramPDFMarginTop           = "pdf-margin-top"
 	paramPDFMarginLeft          = "pdf-margin-left"
@@ -49,15 +61,122 @@ const (
 	paramPDFHeaderTemplate      = "pdf-header-template"
 	paramPDFFooterTemplate      = "pdf-footer-template"
 	paramPDFNoSandbox           = "pdf-no-sandbox"
+	paramPDFSectionDepth        = "pdf-section-depth"
+	paramPDFOutline             = "pdf-outline"
+	paramDepsFile               = "deps-file"
+	paramMaxIncludeDepth        = "max-include-depth"
+	paramStrict                 = "strict"
+	paramDirectivePlugins       = "directive-plugins"
+	paramServeAddress           = "address"
+	paramServeWatchInterval     = "watch-interval"
+	paramOffline                = "offline"
+	paramHTTPCacheDir           = "http-cache-dir"
+	paramNoHTTPCache            = "no-http-cache"
+	paramHTTPTimeout            = "http-timeout"
+	paramHTTPRetries            = "http-retries"
+	paramHTTPUserAgent          = "http-user-agent"
+	paramHTTPNetrc              = "http-netrc"
+	paramResolverAllowedSchemes = "resolver-allowed-schemes"
+	paramResolverAllowedHosts   = "resolver-allowed-hosts"
+	paramResolverDeniedHosts    = "resolver-denied-hosts"
+	paramResolverRoot           = "resolver-root"
+	paramResolverMaxSize        = "resolver-max-size"
 )
 
 var (
+	flagConfig = &cli.StringFlag{
+		Name:    paramConfig,
+		Aliases: []string{"c"},
+		Usage:   "configuration file to use",
+	}
 	flagOutput = altsrc.NewStringFlag(&cli.StringFlag{
 		Name:    paramOutput,
 		Aliases: []string{"o"},
 		Value:   "-",
 		Usage:   "output generated content to given file, '-' to write to stdout",
 	})
+	flagDepsFile = altsrc.NewStringFlag(&cli.StringFlag{
+		Name:  paramDepsFile,
+		Value: "",
+		Usage: "write the list of resolved resources to the given file, as a json manifest if the file has the '.json' extension, as a make rule otherwise",
+	})
+	flagMaxIncludeDepth = altsrc.NewIntFlag(&cli.IntFlag{
+		Name:  paramMaxIncludeDepth,
+		Value: include.DefaultMaxDepth,
+		Usage: "maximum number of nested includes, 0 to disable the limit",
+	})
+	flagStrict = altsrc.NewBoolFlag(&cli.BoolFlag{
+		Name:  paramStrict,
+		Value: false,
+		Usage: "fail on malformed or unknown directives instead of printing warnings",
+	})
+	flagDirectivePlugins = altsrc.NewStringSliceFlag(&cli.StringSliceFlag{
+		Name:  paramDirectivePlugins,
+		Usage: "process the directives of the given type with an external executable, expected format <type>::<executable>",
+		Value: cli.NewStringSlice(),
+	})
+	flagOffline = altsrc.NewBoolFlag(&cli.BoolFlag{
+		Name:    paramOffline,
+		EnvVars: []string{"AMATL_OFFLINE"},
+		Value:   false,
+		Usage:   "only use cached remote resources, fail if a resource is not cached",
+	})
+	flagHTTPCacheDir = altsrc.NewStringFlag(&cli.StringFlag{
+		Name:  paramHTTPCacheDir,
+		Value: httpResolver.DefaultCacheDir(),
+		Usage: "directory where resources fetched over http(s) are cached",
+	})
+	flagNoHTTPCache = altsrc.NewBoolFlag(&cli.BoolFlag{
+		Name:  paramNoHTTPCache,
+		Value: false,
+		Usage: "disable the cache of resources fetched over http(s)",
+	})
+	flagHTTPTimeout = altsrc.NewDurationFlag(&cli.DurationFlag{
+		Name:  paramHTTPTimeout,
+		Value: httpResolver.DefaultTimeout,
+		Usage: "timeout of the http(s) requests",
+	})
+	flagHTTPRetries = altsrc.NewIntFlag(&cli.IntFlag{
+		Name:  paramHTTPRetries,
+		Value: httpResolver.DefaultRetries,
+		Usage: "number of retries of the failed http(s) requests, with an exponential backoff",
+	})
+	flagHTTPUserAgent = altsrc.NewStringFlag(&cli.StringFlag{
+		Name:  paramHTTPUserAgent,
+		Value: "",
+		Usage: "user agent of the http(s) requests, 'amatl/<version>' by default",
+	})
+	flagHTTPNetrc = altsrc.NewStringFlag(&cli.StringFlag{
+		Name:    paramHTTPNetrc,
+		EnvVars: []string{"NETRC"},
+		Value:   "",
+		Usage:   "netrc file providing the credentials of the http(s) hosts",
+	})
+	flagResolverAllowedSchemes = altsrc.NewStringSliceFlag(&cli.StringSliceFlag{
+		Name:  paramResolverAllowedSchemes,
+		Value: cli.NewStringSlice(),
+		Usage: "only resolve resources with the given schemes ('file' for local paths), all schemes are allowed if empty",
+	})
+	flagResolverAllowedHosts = altsrc.NewStringSliceFlag(&cli.StringSliceFlag{
+		Name:  paramResolverAllowedHosts,
+		Value: cli.NewStringSlice(),
+		Usage: "only resolve remote resources from the hosts matching the given glob patterns, all hosts are allowed if empty",
+	})
+	flagResolverDeniedHosts = altsrc.NewStringSliceFlag(&cli.StringSliceFlag{
+		Name:  paramResolverDeniedHosts,
+		Value: cli.NewStringSlice(),
+		Usage: "never resolve remote resources from the hosts matching the given glob patterns",
+	})
+	flagResolverRoot = altsrc.NewStringFlag(&cli.StringFlag{
+		Name:  paramResolverRoot,
+		Value: "",
+		Usage: "directory local resources can not escape, local resources are not restricted if empty",
+	})
+	flagResolverMaxSize = altsrc.NewInt64Flag(&cli.Int64Flag{
+		Name:  paramResolverMaxSize,
+		Value: 0,
+		Usage: "maximum size in bytes of a resolved resource, 0 to disable the limit",
+	})
 	flagTemplateVars = altsrc.NewStringFlag(&cli.StringFlag{
 		Name:  paramTemplateVars,
 		Value: "",
@@ -83,6 +202,31 @@ var (
 		Usage: "enable layout templating and use url resource as json injected data",
 		Value: "",
 	})
+	flagHTMLMermaidURL = altsrc.NewStringFlag(&cli.StringFlag{
+		Name:  paramHTMLMermaidURL,
+		Usage: "url of the mermaid script loaded by the html document, the bundled script is embedded in the document if empty",
+		Value: "",
+	})
+	flagHTMLAssetURLs = altsrc.NewStringSliceFlag(&cli.StringSliceFlag{
+		Name:  paramHTMLAssetURLs,
+		Usage: "load the given bundled layout asset from a remote url instead of embedding it in the html document, expected format <asset>::<url>",
+		Value: cli.NewStringSlice(),
+	})
+	flagHTMLMermaidMode = altsrc.NewStringFlag(&cli.StringFlag{
+		Name:  paramHTMLMermaidMode,
+		Usage: fmt.Sprintf("mermaid diagrams rendering mode, '%s' (rendered by the browser) or '%s' (rendered as inline svg with chromium)", MermaidModeClient, MermaidModeServer),
+		Value: MermaidModeClient,
+	})
+	flagHTMLDotPath = altsrc.NewStringFlag(&cli.StringFlag{
+		Name:  paramHTMLDotPath,
+		Usage: "path of the graphviz 'dot' executable rendering the dot code blocks as inline svg, the code blocks are left as is if empty",
+		Value: "",
+	})
+	flagHTMLD2Path = altsrc.NewStringFlag(&cli.StringFlag{
+		Name:  paramHTMLD2Path,
+		Usage: "path of the 'd2' executable rendering the d2 code blocks as inline svg when amatl is built without the 'd2' build tag, the code blocks are left as is if empty",
+		Value: "",
+	})
 	flagLinkReplacements = altsrc.NewStringSliceFlag(&cli.StringSliceFlag{
 		Name:  paramLinkReplacements,
 		Usage: "replace the given link prefix by the string provided, expected format <prefix>::<replacement>",
@@ -148,9 +292,30 @@ var (
 		Usage: "disable chrome sandboxing",
 		Value: DefaultPDFHeaderTemplate,
 	})
+	flagPDFSectionDepth = altsrc.NewIntFlag(&cli.IntFlag{
+		Name:  paramPDFSectionDepth,
+		Usage: "pdf maximum depth of the headings exposed to the header and footer templates",
+		Value: DefaultPDFSectionDepth,
+	})
+	flagPDFOutline = altsrc.NewBoolFlag(&cli.BoolFlag{
+		Name:  paramPDFOutline,
+		Usage: "pdf generate the outline (bookmarks) from the document headings",
+		Value: DefaultPDFOutline,
+	})
+	flagServeAddress = altsrc.NewStringFlag(&cli.StringFlag{
+		Name:    paramServeAddress,
+		Aliases: []string{"a"},
+		Usage:   "preview server listening address",
+		Value:   "127.0.0.1:3000",
+	})
+	flagServeWatchInterval = altsrc.NewDurationFlag(&cli.DurationFlag{
+		Name:  paramServeWatchInterval,
+		Usage: "interval between two checks of the watched files",
+		Value: 500 * time.Millisecond,
+	})
 )
 
-func getVars(ctx *cli.Context, param string) (map[string]any, error) {
+func getVars(ctx *cli.Context, renderCtx context.Context, param string) (map[string]any, error) {
 	rawUrl := ctx.String(param)
 
 	if rawUrl == "" {
@@ -159,7 +324,7 @@ func getVars(ctx *cli.Context, param string) (map[string]any, error) {
 
 	path := resolver.Path(rawUrl)
 
-	reader, err := resolver.Resolve(ctx.Context, path.String())
+	reader, err := resolver.Resolve(renderCtx, path.String())
 	if err != nil {
 		return nil, errors.WithStack(err)
 	}
@@ -204,6 +369,83 @@ func getHTMLLayout(ctx *cli.Context) (resolver.Path, error) {
 	return absLayoutPath, nil
 }
 
+func getHTMLMermaidURL(ctx *cli.Context) string {
+	return ctx.String(paramHTMLMermaidURL)
+}
+
+// getHTMLAssetURLs returns the remote urls of the bundled
+// assets, indexed by asset name, i.e. "bulma.min.css"
+func getHTMLAssetURLs(ctx *cli.Context) (map[string]string, error) {
+	rawAssetURLs := ctx.StringSlice(paramHTMLAssetURLs)
+
+	assetURLs := make(map[string]string)
+	for _, r := range rawAssetURLs {
+		parts := strings.SplitN(r, "::", 2)
+		if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+			return nil, errors.Errorf("invalid asset url format '%s'", r)
+		}
+
+		assetURLs[parts[0]] = parts[1]
+	}
+
+	return assetURLs, nil
+}
+
+const (
+	MermaidModeClient = "client"
+	MermaidModeServer = "server"
+)
+
+// getMermaidCompiler returns the compiler used to render the mermaid
+// diagrams as inline svg, or nil if the diagrams are rendered by the browser
+func getMermaidCompiler(ctx *cli.Context, funcs ...diagram.MermaidOptionFunc) (*diagram.MermaidCompiler, error) {
+	switch mode := ctx.String(paramHTMLMermaidMode); mode {
+	case MermaidModeClient:
+		return nil, nil
+
+	case MermaidModeServer:
+		script, err := amatl.Asset("mermaid.min.js")
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+
+		funcs = append([]diagram.MermaidOptionFunc{diagram.WithMermaidScript(string(script))}, funcs...)
+
+		return diagram.NewMermaidCompiler(funcs...), nil
+
+	default:
+		return nil, errors.Errorf("unexpected mermaid rendering mode '%s'", mode)
+	}
+}
+
+// getDiagramCompilers returns the compilers of the diagram
+// code blocks, indexed by language
+func getDiagramCompilers(ctx *cli.Context) map[string]diagram.Compiler {
+	compilers := make(map[string]diagram.Compiler)
+
+	if dotPath := ctx.String(paramHTMLDotPath); dotPath != "" {
+		graphviz := diagram.NewGraphvizCompiler(dotPath)
+		compilers["dot"] = graphviz
+		compilers["graphviz"] = graphviz
+	}
+
+	if d2 := diagram.NewD2Compiler(ctx.String(paramHTMLD2Path)); d2 != nil {
+		compilers["d2"] = d2
+	}
+
+	return compilers
+}
+
+func closeMermaidCompiler(ctx *cli.Context, compiler *diagram.MermaidCompiler) {
+	if compiler == nil {
+		return
+	}
+
+	if err := compiler.Close(); err != nil {
+		slog.ErrorContext(ctx.Context, "could not close mermaid renderer", slog.Any("error", errors.WithStack(err)))
+	}
+}
+
 func withCommonFlags(flags ...cli.Flag) []cli.Flag {
 	return append([]cli.Flag{
 		flagTemplateVars,
@@ -211,6 +453,22 @@ func withCommonFlags(flags ...cli.Flag) []cli.Flag {
 		flagTemplateRightDelimiter,
 		flagOutput,
 		flagLinkReplacements,
+		flagDepsFile,
+		flagMaxIncludeDepth,
+		flagStrict,
+		flagDirectivePlugins,
+		flagOffline,
+		flagHTTPCacheDir,
+		flagNoHTTPCache,
+		flagHTTPTimeout,
+		flagHTTPRetries,
+		flagHTTPUserAgent,
+		flagHTTPNetrc,
+		flagResolverAllowedSchemes,
+		flagResolverAllowedHosts,
+		flagResolverDeniedHosts,
+		flagResolverRoot,
+		flagResolverMaxSize,
 	}, flags...)
 }
 
@@ -218,6 +476,11 @@ func withHTMLFlags(flags ...cli.Flag) []cli.Flag {
 	flags = append(flags,
 		flagHTMLLayout,
 		flagHTMLLayoutVars,
+		flagHTMLMermaidURL,
+		flagHTMLAssetURLs,
+		flagHTMLMermaidMode,
+		flagHTMLDotPath,
+		flagHTMLD2Path,
 	)
 
 	return withCommonFlags(flags...)
@@ -237,6 +500,18 @@ func withPDFFlags(flags ...cli.Flag) []cli.Flag {
 		flagPDFFHeaderTemplate,
 		flagPDFFooterTemplate,
 		flagPDFNoSandbox,
+		flagPDFSectionDepth,
+		flagPDFOutline,
+	)
+
+	return withHTMLFlags(flags...)
+}
+
+func withServeFlags(flags ...cli.Flag) []cli.Flag {
+	flags = append(flags,
+		flagConfig,
+		flagServeAddress,
+		flagServeWatchInterval,
 	)
 
 	return withHTMLFlags(flags...)
@@ -272,7 +547,29 @@ func getLinkReplacements(ctx *cli.Context) (map[string]string, error) {
 	return linkReplacements, nil
 }
 
-func getMarkdownSource(ctx *cli.Context) (resolver.Path, []byte, error) {
+// getDirectiveRegistry returns the default directive registry extended
+// with the directives processed by external executables
+func getDirectiveRegistry(ctx *cli.Context) (*directive.Registry, error) {
+	rawPlugins := ctx.StringSlice(paramDirectivePlugins)
+
+	extensions := make([]func() (directive.Type, directive.Definition), 0, len(rawPlugins))
+	for _, r := range rawPlugins {
+		parts := strings.SplitN(r, "::", 2)
+		if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+			return nil, errors.Errorf("invalid directive plugin format '%s'", r)
+		}
+
+		directiveType, execPath := directive.Type(parts[0]), parts[1]
+
+		extensions = append(extensions, func() (directive.Type, directive.Definition) {
+			return directiveType, plugin.NewDefinition(plugin.NewPlugin(execPath))
+		})
+	}
+
+	return directive.DefaultRegistry.Extend(extensions...), nil
+}
+
+func getMarkdownSource(ctx *cli.Context, renderCtx context.Context) (resolver.Path, []byte, error) {
 	filename := ctx.Args().First()
 	if filename == "" {
 		return "", nil, errors.New("you must provide the path or url to a markdown file")
@@ -283,7 +580,7 @@ func getMarkdownSource(ctx *cli.Context) (resolver.Path, []byte, error) {
 		return "", nil, errors.WithStack(err)
 	}
 
-	reader, err := resolver.Resolve(ctx.Context, path.String())
+	reader, err := resolver.Resolve(renderCtx, path.String())
 	if err != nil {
 		return "", nil, errors.WithStack(err)
 	}
@@ -337,6 +634,85 @@ func getPDFNoSandbox(ctx *cli.Context) bool {
 	return ctx.Bool(paramPDFNoSandbox)
 }
 
+func getPDFSectionDepth(ctx *cli.Context) int {
+	return ctx.Int(paramPDFSectionDepth)
+}
+
+func getPDFOutline(ctx *cli.Context) bool {
+	return ctx.Bool(paramPDFOutline)
+}
+
+func getMaxIncludeDepth(ctx *cli.Context) int {
+	return ctx.Int(paramMaxIncludeDepth)
+}
+
+func getStrict(ctx *cli.Context) bool {
+	return ctx.Bool(paramStrict)
+}
+
+func getServeAddress(ctx *cli.Context) string {
+	return ctx.String(paramServeAddress)
+}
+
+func getServeWatchInterval(ctx *cli.Context) time.Duration {
+	return ctx.Duration(paramServeWatchInterval)
+}
+
+func getOffline(ctx *cli.Context) bool {
+	return ctx.Bool(paramOffline)
+}
+
+func getHTTPCacheDir(ctx *cli.Context) string {
+	if ctx.Bool(paramNoHTTPCache) {
+		return ""
+	}
+
+	return ctx.String(paramHTTPCacheDir)
+}
+
+func getHTTPTimeout(ctx *cli.Context) time.Duration {
+	return ctx.Duration(paramHTTPTimeout)
+}
+
+func getHTTPRetries(ctx *cli.Context) int {
+	return ctx.Int(paramHTTPRetries)
+}
+
+func getHTTPUserAgent(ctx *cli.Context) string {
+	if userAgent := ctx.String(paramHTTPUserAgent); userAgent != "" {
+		return userAgent
+	}
+
+	return fmt.Sprintf("%s/%s", httpResolver.DefaultUserAgent, ctx.App.Version)
+}
+
+func getHTTPNetrc(ctx *cli.Context) string {
+	return ctx.String(paramHTTPNetrc)
+}
+
+// getResolverPolicy returns the resolver policy configured by the flags,
+// or nil if no restriction is configured
+func getResolverPolicy(ctx *cli.Context) *resolver.Policy {
+	policy := &resolver.Policy{
+		AllowedSchemes: ctx.StringSlice(paramResolverAllowedSchemes),
+		AllowedHosts:   ctx.StringSlice(paramResolverAllowedHosts),
+		DeniedHosts:    ctx.StringSlice(paramResolverDeniedHosts),
+		Root:           ctx.String(paramResolverRoot),
+		MaxSize:        ctx.Int64(paramResolverMaxSize),
+	}
+
+	if len(policy.AllowedSchemes) == 0 && len(policy.AllowedHosts) == 0 && len(policy.DeniedHosts) == 0 && policy.Root == "" && policy.MaxSize <= 0 {
+		return nil
+	}
+
+	// Embedded layouts are always allowed
+	if len(policy.AllowedSchemes) > 0 && !slices.Contains(policy.AllowedSchemes, amatl.Scheme) {
+		policy.AllowedSchemes = append(policy.AllowedSchemes, amatl.Scheme)
+	}
+
+	return policy
+}
+
 func NewResolverSourceFromFlagFunc(flag string) func(cCtx *cli.Context) (altsrc.InputSourceContext, error) {
 	return func(cCtx *cli.Context) (altsrc.InputSourceContext, error) {
 		if urlStr := cCtx.String(flag); urlStr != "" {
@@ -348,8 +724,30 @@ func NewResolverSourceFromFlagFunc(flag string) func(cCtx *cli.Context) (altsrc.
 }
 
 func NewResolvedInputSource(ctx context.Context, urlStr string) (altsrc.InputSourceContext, error) {
+	values, err := readConfig(ctx, urlStr)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	values, err = rewriteRelativePath(resolver.Path(urlStr), values)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	return altsrc.NewMapInputSource(urlStr, values), nil
+}
+
+// readConfig resolves and decodes the given configuration file
+func readConfig(ctx context.Context, urlStr string) (map[any]any, error) {
 	path := resolver.Path(urlStr)
 
+	ext := filepath.Ext(urlStr)
+	switch ext {
+	case ".json", ".yaml", ".yml":
+	default:
+		return nil, errors.Errorf("no parser associated with '%s' file extension", ext)
+	}
+
 	reader, err := resolver.Resolve(ctx, path.String())
 	if err != nil {
 		return nil, errors.WithStack(err)
@@ -368,30 +766,17 @@ func NewResolvedInputSource(ctx context.Context, urlStr string) (altsrc.InputSou
 		return nil, errors.WithStack(err)
 	}
 
-	ext := filepath.Ext(urlStr)
-	switch ext {
-	case ".json":
-		fallthrough
-	case ".yaml":
-		fallthrough
-	case ".yml":
-		var values map[any]any
-
-		if err := yaml.Unmarshal(data, &values); err != nil {
-			return nil, errors.WithStack(err)
-		}
+	var values map[any]any
 
-		values, err = rewriteRelativePath(path, values)
-		if err != nil {
-			return nil, errors.WithStack(err)
-		}
-
-		return altsrc.NewMapInputSource(urlStr, values), nil
+	if err := yaml.Unmarshal(data, &values); err != nil {
+		return nil, errors.WithStack(err)
+	}
 
-	default:
-		return nil, errors.Errorf("no parser associated with '%s' file extension", ext)
+	if values == nil {
+		values = map[any]any{}
 	}
 
+	return values, nil
 }
 
 func rewriteRelativePath(sourcePath resolver.Path, values map[any]any) (map[any]any, error) {
@@ -1,17 +1,19 @@
 package render
 
 import (
+	"context"
 	"io"
 	"log/slog"
 
+	"github.com/Bornholm/amatl/pkg/diagram"
 	"github.com/Bornholm/amatl/pkg/log"
 	"github.com/Bornholm/amatl/pkg/markdown/directive/attrs"
+	"github.com/Bornholm/amatl/pkg/markdown/directive/include"
 	"github.com/Bornholm/amatl/pkg/markdown/directive/toc"
 	"github.com/Bornholm/amatl/pkg/pipeline"
 	"github.com/Bornholm/amatl/pkg/resolver"
 	"github.com/pkg/errors"
 	"github.com/urfave/cli/v2"
-	"github.com/urfave/cli/v2/altsrc"
 )
 
 func HTML() *cli.Command {
@@ -19,73 +21,26 @@ func HTML() *cli.Command {
 	return &cli.Command{
 		Name:   "html",
 		Flags:  flags,
-		Before: altsrc.InitInputSourceWithContext(flags, NewResolverSourceFromFlagFunc("config")),
+		Before: newBeforeFunc(flags),
 		Action: func(ctx *cli.Context) error {
-			sourcePath, source, err := getMarkdownSource(ctx)
-			if err != nil {
-				return errors.WithStack(err)
-			}
-
-			layoutVars, err := getVars(ctx, paramHTMLLayoutVars)
-			if err != nil {
-				return errors.WithStack(err)
-			}
-
-			vars, err := getVars(ctx, paramTemplateVars)
-			if err != nil {
-				return errors.WithStack(err)
-			}
-
-			leftDelimiter, rightDelimiter := getTemplateDelimiters(ctx)
+			tracker := resolver.NewTracker()
+			ctx.Context = resolver.WithTracker(ctx.Context, tracker)
 
-			linkReplacements, err := getLinkReplacements(ctx)
+			mermaidCompiler, err := getMermaidCompiler(ctx)
 			if err != nil {
 				return errors.WithStack(err)
 			}
 
-			htmlLayoutPath, err := getHTMLLayout(ctx)
-			if err != nil {
-				return errors.Wrap(err, "could not retrieve html layout")
-			}
+			defer closeMermaidCompiler(ctx, mermaidCompiler)
 
-			baseDir, err := sourcePath.Dir().Abs()
+			payload, err := renderHTML(ctx, ctx.Context, &renderCaches{
+				Mermaid:  mermaidCompiler,
+				Diagrams: getDiagramCompilers(ctx),
+			})
 			if err != nil {
 				return errors.WithStack(err)
 			}
 
-			pipelineCtx := log.WithAttrs(ctx.Context, slog.Any("source", sourcePath.String()))
-			pipelineCtx = resolver.WithWorkDir(pipelineCtx, baseDir)
-
-			transformer := pipeline.Pipeline(
-				// Preprocess the markdown entrypoint
-				// document to include potential directives
-				MarkdownMiddleware(
-					WithSourcePath(sourcePath),
-					WithLinkReplacements(linkReplacements),
-					WithIgnoredDirectives(toc.Type, attrs.Type),
-				),
-				TemplateMiddleware(
-					WithVars(vars),
-					WithDelimiters(leftDelimiter, rightDelimiter),
-				),
-				// Render the consolidated document
-				// as HTML
-				HTMLMiddleware(
-					WithMarkdownTransformerOptions(
-						WithSourcePath(sourcePath),
-						WithLinkReplacements(linkReplacements),
-					),
-					WithLayoutURL(htmlLayoutPath.String()),
-					WithLayoutVars(layoutVars),
-				),
-			)
-
-			payload := pipeline.NewPayload(source)
-
-			if err := transformer.Transform(pipelineCtx, payload); err != nil {
-				return errors.WithStack(err)
-			}
-
 			output, err := getOutput(ctx)
 			if err != nil {
 				return errors.WithStack(err)
@@ -101,7 +56,123 @@ func HTML() *cli.Command {
 				return errors.WithStack(err)
 			}
 
+			if err := writeDepsFile(ctx, tracker); err != nil {
+				return errors.Wrap(err, "could not write dependency file")
+			}
+
 			return nil
 		},
 	}
 }
+
+// renderCaches holds the include caches reused between the renderings
+// of a document, one for each parsing stage of the pipeline, and the
+// compilers of the diagrams rendered as inline svg
+type renderCaches struct {
+	Markdown *include.SourceCache
+	HTML     *include.SourceCache
+	Mermaid  *diagram.MermaidCompiler
+	Diagrams map[string]diagram.Compiler
+}
+
+func newRenderCaches() *renderCaches {
+	return &renderCaches{
+		Markdown: include.NewSourceCache(),
+		HTML:     include.NewSourceCache(),
+	}
+}
+
+func renderHTML(ctx *cli.Context, renderCtx context.Context, caches *renderCaches) (*pipeline.Payload, error) {
+	if caches == nil {
+		caches = &renderCaches{}
+	}
+
+	sourcePath, source, err := getMarkdownSource(ctx, renderCtx)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	layoutVars, err := getVars(ctx, renderCtx, paramHTMLLayoutVars)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	vars, err := getVars(ctx, renderCtx, paramTemplateVars)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	leftDelimiter, rightDelimiter := getTemplateDelimiters(ctx)
+	maxIncludeDepth := getMaxIncludeDepth(ctx)
+
+	linkReplacements, err := getLinkReplacements(ctx)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	assetURLs, err := getHTMLAssetURLs(ctx)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	directives, err := getDirectiveRegistry(ctx)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	htmlLayoutPath, err := getHTMLLayout(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not retrieve html layout")
+	}
+
+	baseDir, err := sourcePath.Dir().Abs()
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	pipelineCtx := log.WithAttrs(renderCtx, slog.Any("source", sourcePath.String()))
+	pipelineCtx = resolver.WithWorkDir(pipelineCtx, baseDir)
+
+	transformer := pipeline.Pipeline(
+		// Preprocess the markdown entrypoint
+		// document to include potential directives
+		MarkdownMiddleware(
+			WithSourcePath(sourcePath),
+			WithLinkReplacements(linkReplacements),
+			WithDirectives(directives),
+			WithMaxIncludeDepth(maxIncludeDepth),
+			WithStrict(getStrict(ctx)),
+			WithSourceCache(caches.Markdown),
+			WithIgnoredDirectives(toc.Type, attrs.Type),
+		),
+		TemplateMiddleware(
+			WithVars(vars),
+			WithDelimiters(leftDelimiter, rightDelimiter),
+		),
+		// Render the consolidated document
+		// as HTML
+		HTMLMiddleware(
+			WithMarkdownTransformerOptions(
+				WithSourcePath(sourcePath),
+				WithLinkReplacements(linkReplacements),
+				WithDirectives(directives),
+				WithMaxIncludeDepth(maxIncludeDepth),
+				WithSourceCache(caches.HTML),
+			),
+			WithLayoutURL(htmlLayoutPath.String()),
+			WithLayoutVars(layoutVars),
+			WithMermaidURL(getHTMLMermaidURL(ctx)),
+			WithAssetURLs(assetURLs),
+			WithMermaidCompiler(caches.Mermaid),
+			WithDiagramCompilers(caches.Diagrams),
+		),
+	)
+
+	payload := pipeline.NewPayload(source)
+
+	if err := transformer.Transform(pipelineCtx, payload); err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	return payload, nil
+}
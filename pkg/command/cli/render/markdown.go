@@ -9,7 +9,6 @@ import (
 	"github.com/Bornholm/amatl/pkg/resolver"
 	"github.com/pkg/errors"
 	"github.com/urfave/cli/v2"
-	"github.com/urfave/cli/v2/altsrc"
 )
 
 func Markdown() *cli.Command {
@@ -17,25 +16,34 @@ func Markdown() *cli.Command {
 	return &cli.Command{
 		Name:   "markdown",
 		Flags:  flags,
-		Before: altsrc.InitInputSourceWithContext(flags, NewResolverSourceFromFlagFunc("config")),
+		Before: newBeforeFunc(flags),
 		Action: func(ctx *cli.Context) error {
-			sourcePath, source, err := getMarkdownSource(ctx)
+			tracker := resolver.NewTracker()
+			ctx.Context = resolver.WithTracker(ctx.Context, tracker)
+
+			sourcePath, source, err := getMarkdownSource(ctx, ctx.Context)
 			if err != nil {
 				return errors.WithStack(err)
 			}
 
-			vars, err := getVars(ctx, paramTemplateVars)
+			vars, err := getVars(ctx, ctx.Context, paramTemplateVars)
 			if err != nil {
 				return errors.WithStack(err)
 			}
 
 			leftDelimiter, rightDelimiter := getTemplateDelimiters(ctx)
+			maxIncludeDepth := getMaxIncludeDepth(ctx)
 
 			linkReplacements, err := getLinkReplacements(ctx)
 			if err != nil {
 				return errors.WithStack(err)
 			}
 
+			directives, err := getDirectiveRegistry(ctx)
+			if err != nil {
+				return errors.WithStack(err)
+			}
+
 			sourcePath, err = sourcePath.Abs()
 			if err != nil {
 				return errors.WithStack(err)
@@ -45,6 +53,9 @@ func Markdown() *cli.Command {
 				MarkdownMiddleware(
 					WithSourcePath(sourcePath),
 					WithLinkReplacements(linkReplacements),
+					WithDirectives(directives),
+					WithMaxIncludeDepth(maxIncludeDepth),
+					WithStrict(getStrict(ctx)),
 				),
 				TemplateMiddleware(
 					WithVars(vars),
@@ -81,6 +92,10 @@ func Markdown() *cli.Command {
 				return errors.WithStack(err)
 			}
 
+			if err := writeDepsFile(ctx, tracker); err != nil {
+				return errors.Wrap(err, "could not write dependency file")
+			}
+
 			return nil
 		},
 	}
@@ -3,47 +3,70 @@ package render
 import (
 	"slices"
 
+	"github.com/Bornholm/amatl/pkg/diagram"
+	"github.com/Bornholm/amatl/pkg/markdown/alert"
 	"github.com/Bornholm/amatl/pkg/markdown/dataurl"
 	"github.com/Bornholm/amatl/pkg/markdown/directive"
+	"github.com/Bornholm/amatl/pkg/markdown/directive/abbr"
 	"github.com/Bornholm/amatl/pkg/markdown/directive/attrs"
 	"github.com/Bornholm/amatl/pkg/markdown/directive/include"
+	"github.com/Bornholm/amatl/pkg/markdown/directive/kbd"
 	"github.com/Bornholm/amatl/pkg/markdown/directive/toc"
 	"github.com/Bornholm/amatl/pkg/markdown/linkrewriter"
+	"github.com/Bornholm/amatl/pkg/markdown/math"
 	"github.com/Bornholm/amatl/pkg/resolver"
+	"github.com/pkg/errors"
 	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
 	"github.com/yuin/goldmark/extension"
 	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/text"
 	"github.com/yuin/goldmark/util"
 	"go.abhg.dev/goldmark/frontmatter"
 	"go.abhg.dev/goldmark/mermaid"
 )
 
-var (
-	cache = include.NewSourceCache()
-)
-
 type ParserOptions struct {
 	EmbedLinkedResources bool
 	LinkReplacements     map[string]string
 	IgnoredDirectives    []directive.Type
+	MaxIncludeDepth      int
+	SourceCache          *include.SourceCache
+	// DiagramCompilers are the compilers of the diagram code blocks
+	// rendered as inline SVG, indexed by language
+	DiagramCompilers map[string]diagram.Compiler
+	// Directives are the custom directives, the
+	// default registry being used if nil
+	Directives *directive.Registry
 }
 
-func newParser(sourcePath resolver.Path, opts ParserOptions) parser.Parser {
+// NewParser returns the parser of the Markdown documents, handling the
+// built-in directives and the custom directives of the given registry
+func NewParser(sourcePath resolver.Path, opts ParserOptions) parser.Parser {
 	markdown := goldmark.New(
 		goldmark.WithExtensions(
 			extension.GFM,
+			extension.Footnote,
+			extension.DefinitionList,
 			&mermaid.Extender{
 				RenderMode: mermaid.RenderModeClient,
 			},
 			&frontmatter.Extender{
 				Mode: frontmatter.SetMetadata,
 			},
+			&diagram.Extender{
+				Compilers: opts.DiagramCompilers,
+			},
+			&math.Extender{},
+			&alert.Extender{},
 		),
 	)
 
 	parse := markdown.Parser()
 
-	directiveTransformers := []directive.TransformerOptionFunc{}
+	directiveTransformers := []directive.TransformerOptionFunc{
+		directive.WithKnownTypes(toc.Type, attrs.Type, include.Type, kbd.Type, abbr.Type),
+	}
 
 	if !isDirectiveIgnored(toc.Type, opts.IgnoredDirectives) {
 		directiveTransformers = append(directiveTransformers,
@@ -69,15 +92,21 @@ func newParser(sourcePath resolver.Path, opts ParserOptions) parser.Parser {
 				include.Type,
 				&include.NodeTransformer{
 					SourcePath: sourcePath,
-					Cache:      cache,
+					Cache:      opts.SourceCache,
 					Parser:     parse,
+					MaxDepth:   opts.MaxIncludeDepth,
 				},
 			),
 		)
 	}
 
+	directiveTransformers = append(directiveTransformers, getDirectives(opts.Directives).TransformerOptions()...)
+
 	parse.AddOptions(
 		parser.WithAutoHeadingID(),
+		parser.WithBlockParsers(
+			util.Prioritized(&directive.BlockParser{}, 100),
+		),
 		parser.WithInlineParsers(
 			util.Prioritized(&directive.InlineParser{}, 0),
 		),
@@ -108,8 +137,38 @@ func newParser(sourcePath resolver.Path, opts ParserOptions) parser.Parser {
 	return parse
 }
 
+// parseDocument parses the given source, converting the panics raised
+// by the AST transformers into errors
+func parseDocument(parse parser.Parser, reader text.Reader, pc parser.Context) (document ast.Node, err error) {
+	defer func() {
+		recovered := recover()
+		if recovered == nil {
+			return
+		}
+
+		if recoveredErr, ok := recovered.(error); ok {
+			err = errors.WithStack(recoveredErr)
+			return
+		}
+
+		panic(recovered)
+	}()
+
+	document = parse.Parse(reader, parser.WithContext(pc))
+
+	return document, nil
+}
+
 func isDirectiveIgnored(dt directive.Type, ignored []directive.Type) bool {
 	return slices.ContainsFunc(ignored, func(curr directive.Type) bool {
 		return dt == curr
 	})
 }
+
+func getDirectives(registry *directive.Registry) *directive.Registry {
+	if registry == nil {
+		return directive.DefaultRegistry
+	}
+
+	return registry
+}
@@ -4,6 +4,7 @@ import (
 	"io"
 	"log/slog"
 
+	"github.com/Bornholm/amatl/pkg/diagram"
 	"github.com/Bornholm/amatl/pkg/log"
 	"github.com/Bornholm/amatl/pkg/markdown/directive/attrs"
 	"github.com/Bornholm/amatl/pkg/markdown/directive/toc"
@@ -11,7 +12,6 @@ import (
 	"github.com/Bornholm/amatl/pkg/resolver"
 	"github.com/pkg/errors"
 	"github.com/urfave/cli/v2"
-	"github.com/urfave/cli/v2/altsrc"
 )
 
 func PDF() *cli.Command {
@@ -19,26 +19,40 @@ func PDF() *cli.Command {
 	return &cli.Command{
 		Name:   "pdf",
 		Flags:  flags,
-		Before: altsrc.InitInputSourceWithContext(flags, NewResolverSourceFromFlagFunc("config")),
+		Before: newBeforeFunc(flags),
 		Action: func(ctx *cli.Context) error {
-			sourcePath, source, err := getMarkdownSource(ctx)
+			tracker := resolver.NewTracker()
+			ctx.Context = resolver.WithTracker(ctx.Context, tracker)
+
+			sourcePath, source, err := getMarkdownSource(ctx, ctx.Context)
 			if err != nil {
 				return errors.WithStack(err)
 			}
 
-			vars, err := getVars(ctx, paramTemplateVars)
+			vars, err := getVars(ctx, ctx.Context, paramTemplateVars)
 			if err != nil {
 				return errors.WithStack(err)
 			}
 
 			leftDelimiter, rightDelimiter := getTemplateDelimiters(ctx)
+			maxIncludeDepth := getMaxIncludeDepth(ctx)
 
 			linkReplacements, err := getLinkReplacements(ctx)
 			if err != nil {
 				return errors.WithStack(err)
 			}
 
-			layoutVars, err := getVars(ctx, paramHTMLLayoutVars)
+			assetURLs, err := getHTMLAssetURLs(ctx)
+			if err != nil {
+				return errors.WithStack(err)
+			}
+
+			directives, err := getDirectiveRegistry(ctx)
+			if err != nil {
+				return errors.WithStack(err)
+			}
+
+			layoutVars, err := getVars(ctx, ctx.Context, paramHTMLLayoutVars)
 			if err != nil {
 				return errors.WithStack(err)
 			}
@@ -50,6 +64,18 @@ func PDF() *cli.Command {
 			execPath := getPDFExecPath(ctx)
 			displayHeaderFooter, headerTemplate, footerTemplate := getPDFHeaderFooter(ctx)
 			noSandbox := getPDFNoSandbox(ctx)
+			sectionDepth := getPDFSectionDepth(ctx)
+			outline := getPDFOutline(ctx)
+
+			mermaidCompiler, err := getMermaidCompiler(ctx,
+				diagram.WithExecPath(execPath),
+				diagram.WithNoSandbox(noSandbox),
+			)
+			if err != nil {
+				return errors.WithStack(err)
+			}
+
+			defer closeMermaidCompiler(ctx, mermaidCompiler)
 
 			baseDir, err := sourcePath.Dir().Abs()
 			if err != nil {
@@ -71,6 +97,9 @@ func PDF() *cli.Command {
 				MarkdownMiddleware(
 					WithSourcePath(sourcePath),
 					WithLinkReplacements(linkReplacements),
+					WithDirectives(directives),
+					WithMaxIncludeDepth(maxIncludeDepth),
+					WithStrict(getStrict(ctx)),
 					WithIgnoredDirectives(toc.Type, attrs.Type),
 				),
 				TemplateMiddleware(
@@ -83,9 +112,15 @@ func PDF() *cli.Command {
 					WithMarkdownTransformerOptions(
 						WithSourcePath(sourcePath),
 						WithLinkReplacements(linkReplacements),
+						WithDirectives(directives),
+						WithMaxIncludeDepth(maxIncludeDepth),
 					),
 					WithLayoutURL(htmlLayoutPath.String()),
 					WithLayoutVars(layoutVars),
+					WithMermaidURL(getHTMLMermaidURL(ctx)),
+					WithAssetURLs(assetURLs),
+					WithMermaidCompiler(mermaidCompiler),
+					WithDiagramCompilers(getDiagramCompilers(ctx)),
 				),
 				// Render generated HTML to PDF with Chromium
 				PDFMiddleware(
@@ -101,6 +136,10 @@ func PDF() *cli.Command {
 					WithHeaderTemplate(headerTemplate),
 					WithFooterTemplate(footerTemplate),
 					WithNoSandbox(noSandbox),
+					WithHeaderFooterVars(vars),
+					WithVersion(ctx.App.Version),
+					WithSectionDepth(sectionDepth),
+					WithOutline(outline),
 				),
 			)
 
@@ -125,6 +164,10 @@ func PDF() *cli.Command {
 				return errors.WithStack(err)
 			}
 
+			if err := writeDepsFile(ctx, tracker); err != nil {
+				return errors.Wrap(err, "could not write dependency file")
+			}
+
 			return nil
 		},
 	}
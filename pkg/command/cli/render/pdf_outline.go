@@ -0,0 +1,83 @@
+package render
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/Bornholm/amatl/pkg/markdown/directive/toc"
+	"github.com/Bornholm/amatl/pkg/pdf"
+)
+
+// buildPDFOutline returns the outline of the PDF document, built from the
+// headings tree of the document and located with the outline generated by Chrome
+func buildPDFOutline(ctx context.Context, headings []toc.Heading, located []pdf.Bookmark) []pdf.Bookmark {
+	if len(headings) == 0 {
+		return nil
+	}
+
+	if len(located) == 0 {
+		slog.WarnContext(ctx, "could not locate headings in pdf document, outline will not be generated")
+		return nil
+	}
+
+	return pdf.Locate(headingsToBookmarks(headings), located)
+}
+
+func headingsToBookmarks(headings []toc.Heading) []pdf.Bookmark {
+	if len(headings) == 0 {
+		return nil
+	}
+
+	bookmarks := make([]pdf.Bookmark, 0, len(headings))
+	for _, h := range headings {
+		bookmarks = append(bookmarks, pdf.Bookmark{
+			Title:    h.Title,
+			Children: headingsToBookmarks(h.Children),
+		})
+	}
+
+	return bookmarks
+}
+
+// getPDFInfo returns the PDF document information from the front matter
+func getPDFInfo(meta map[string]any, opts *PDFTransformerOptions) pdf.Info {
+	info := pdf.Info{
+		Title:    metaString(meta, "title"),
+		Author:   metaString(meta, "author", "authors"),
+		Subject:  metaString(meta, "subject", "description"),
+		Keywords: metaString(meta, "keywords", "tags"),
+		Creator:  "amatl",
+	}
+
+	if opts.Version != "" {
+		info.Creator += " " + opts.Version
+	}
+
+	return info
+}
+
+// metaString returns the value of the first existing key of the front matter
+// as a string, lists being joined with commas
+func metaString(meta map[string]any, keys ...string) string {
+	for _, key := range keys {
+		value, exists := meta[key]
+		if !exists || value == nil {
+			continue
+		}
+
+		switch v := value.(type) {
+		case []any:
+			values := make([]string, 0, len(v))
+			for _, item := range v {
+				values = append(values, fmt.Sprint(item))
+			}
+			return strings.Join(values, ", ")
+		default:
+			return fmt.Sprint(v)
+		}
+	}
+
+	return ""
+}
@@ -0,0 +1,91 @@
+package render
+
+import (
+	"bytes"
+	"text/template"
+	"time"
+
+	"github.com/Masterminds/sprig/v3"
+	"github.com/pkg/errors"
+)
+
+// PDFTemplateData is the data exposed to the PDF header and footer templates
+type PDFTemplateData struct {
+	*PDFTransformerOptions
+	// Meta is the front matter of the document
+	Meta map[string]any
+	// Date is the rendering date
+	Date time.Time
+	// Headings are the titles of the current heading of the page and
+	// of its ancestors, outermost first
+	Headings []string
+	// Chapter is the title of the current top level heading of the page
+	Chapter string
+	// Section is the title of the current heading of the page
+	Section string
+}
+
+func (d PDFTemplateData) withHeadings(headings []string) *PDFTemplateData {
+	d.Headings = headings
+	d.Chapter = ""
+	d.Section = ""
+
+	if len(headings) > 0 {
+		d.Chapter = headings[0]
+		d.Section = headings[len(headings)-1]
+	}
+
+	return &d
+}
+
+type pdfTemplates struct {
+	header *template.Template
+	footer *template.Template
+}
+
+func (t *pdfTemplates) Execute(data *PDFTemplateData) (string, string, error) {
+	var header bytes.Buffer
+	if err := t.header.Execute(&header, data); err != nil {
+		return "", "", errors.Wrapf(err, "could not execute header template")
+	}
+
+	var footer bytes.Buffer
+	if err := t.footer.Execute(&footer, data); err != nil {
+		return "", "", errors.Wrapf(err, "could not execute footer template")
+	}
+
+	return header.String(), footer.String(), nil
+}
+
+// UsesHeadings returns true if the header or the footer
+// depend on the current headings of the page
+func (t *pdfTemplates) UsesHeadings(data *PDFTemplateData) (bool, error) {
+	header, footer, err := t.Execute(data.withHeadings(nil))
+	if err != nil {
+		return false, errors.WithStack(err)
+	}
+
+	probedHeader, probedFooter, err := t.Execute(data.withHeadings([]string{"chapter", "section"}))
+	if err != nil {
+		return false, errors.WithStack(err)
+	}
+
+	return header != probedHeader || footer != probedFooter, nil
+}
+
+func newPDFTemplates(opts *PDFTransformerOptions) (*pdfTemplates, error) {
+	header, err := template.New("").Funcs(sprig.FuncMap()).Parse(opts.HeaderTemplate)
+	if err != nil {
+		return nil, errors.Wrapf(err, "could not parse header template")
+	}
+
+	footer, err := template.New("").Funcs(sprig.FuncMap()).Parse(opts.FooterTemplate)
+	if err != nil {
+		return nil, errors.Wrapf(err, "could not parse footer template")
+	}
+
+	return &pdfTemplates{
+		header: header,
+		footer: footer,
+	}, nil
+}
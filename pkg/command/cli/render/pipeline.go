@@ -3,13 +3,21 @@ package render
 import (
 	"bytes"
 	"context"
+	"fmt"
+	"html"
 	"log/slog"
+	"math"
+	"strings"
 	"sync"
 	"text/template"
 	"time"
 
+	"github.com/Bornholm/amatl/pkg/diagram"
 	"github.com/Bornholm/amatl/pkg/html/layout"
 	"github.com/Bornholm/amatl/pkg/markdown/directive"
+	"github.com/Bornholm/amatl/pkg/markdown/directive/include"
+	"github.com/Bornholm/amatl/pkg/markdown/directive/toc"
+	"github.com/Bornholm/amatl/pkg/pdf"
 	"github.com/Bornholm/amatl/pkg/pipeline"
 	"github.com/Bornholm/amatl/pkg/resolver"
 	"github.com/Masterminds/sprig/v3"
@@ -21,7 +29,8 @@ import (
 )
 
 const (
-	attrMeta = "meta"
+	attrMeta     = "meta"
+	attrHeadings = "headings"
 )
 
 type TemplateTransformerOptions struct {
@@ -114,6 +123,15 @@ type MarkdownTransformerOptions struct {
 	SourcePath        resolver.Path
 	LinkReplacements  map[string]string
 	IgnoredDirectives []directive.Type
+	MaxIncludeDepth   int
+	// SourceCache stores the included sources between renderings,
+	// a new cache is used for each rendering if nil
+	SourceCache *include.SourceCache
+	// Strict turns the directive diagnostics into errors
+	Strict bool
+	// Directives are the custom directives, the
+	// default registry being used if nil
+	Directives *directive.Registry
 }
 
 type MarkdownTransformerOptionFunc func(opts *MarkdownTransformerOptions)
@@ -121,6 +139,7 @@ type MarkdownTransformerOptionFunc func(opts *MarkdownTransformerOptions)
 func NewMarkdownTransformerOptions(funcs ...MarkdownTransformerOptionFunc) *MarkdownTransformerOptions {
 	opts := &MarkdownTransformerOptions{
 		LinkReplacements: make(map[string]string),
+		MaxIncludeDepth:  include.DefaultMaxDepth,
 	}
 	for _, fn := range funcs {
 		fn(opts)
@@ -146,6 +165,30 @@ func WithIgnoredDirectives(directiveTypes ...directive.Type) MarkdownTransformer
 	}
 }
 
+func WithMaxIncludeDepth(maxDepth int) MarkdownTransformerOptionFunc {
+	return func(opts *MarkdownTransformerOptions) {
+		opts.MaxIncludeDepth = maxDepth
+	}
+}
+
+func WithSourceCache(cache *include.SourceCache) MarkdownTransformerOptionFunc {
+	return func(opts *MarkdownTransformerOptions) {
+		opts.SourceCache = cache
+	}
+}
+
+func WithDirectives(directives *directive.Registry) MarkdownTransformerOptionFunc {
+	return func(opts *MarkdownTransformerOptions) {
+		opts.Directives = directives
+	}
+}
+
+func WithStrict(strict bool) MarkdownTransformerOptionFunc {
+	return func(opts *MarkdownTransformerOptions) {
+		opts.Strict = strict
+	}
+}
+
 func MarkdownMiddleware(funcs ...MarkdownTransformerOptionFunc) pipeline.Middleware {
 	opts := NewMarkdownTransformerOptions(funcs...)
 	return func(next pipeline.Transformer) pipeline.Transformer {
@@ -155,19 +198,34 @@ func MarkdownMiddleware(funcs ...MarkdownTransformerOptionFunc) pipeline.Middlew
 			data := payload.GetData()
 			reader := text.NewReader(data)
 
-			parse := newParser(opts.SourcePath, ParserOptions{
+			parse := NewParser(opts.SourcePath, ParserOptions{
 				EmbedLinkedResources: false,
 				LinkReplacements:     opts.LinkReplacements,
 				IgnoredDirectives:    opts.IgnoredDirectives,
+				MaxIncludeDepth:      opts.MaxIncludeDepth,
+				SourceCache:          getSourceCache(opts.SourceCache),
+				Directives:           opts.Directives,
 			})
-			render := newMarkdownRenderer()
+			render := NewMarkdownRenderer(opts.Directives)
 
 			slog.DebugContext(ctx, "parsing markdown file")
 
+			diagnostics := directive.NewDiagnostics()
+
 			pc := parser.NewContext()
 			pc = pipeline.WithContext(ctx, pc)
+			pc = directive.WithDiagnostics(pc, diagnostics)
+			pc = directive.WithSourcePath(pc, opts.SourcePath.String())
+			pc = directive.WithParser(pc, parse)
+
+			document, err := parseDocument(parse, reader, pc)
+			if err != nil {
+				return errors.Wrap(err, "could not parse markdown document")
+			}
 
-			document := parse.Parse(reader, parser.WithContext(pc))
+			if err := checkDiagnostics(ctx, diagnostics.All(), opts.Strict); err != nil {
+				return errors.WithStack(err)
+			}
 
 			var doc bytes.Buffer
 
@@ -191,10 +249,53 @@ func MarkdownMiddleware(funcs ...MarkdownTransformerOptionFunc) pipeline.Middlew
 	}
 }
 
+// checkDiagnostics logs the given directive diagnostics as warnings,
+// or returns them as an error in strict mode
+func checkDiagnostics(ctx context.Context, diagnostics []directive.Diagnostic, strict bool) error {
+	if len(diagnostics) == 0 {
+		return nil
+	}
+
+	if strict {
+		lines := make([]string, 0, len(diagnostics))
+		for _, d := range diagnostics {
+			lines = append(lines, d.String())
+		}
+
+		return errors.Errorf("found %d directive diagnostic(s) in strict mode:\n%s", len(diagnostics), strings.Join(lines, "\n"))
+	}
+
+	for _, d := range diagnostics {
+		slog.WarnContext(ctx, d.String())
+	}
+
+	return nil
+}
+
+func getSourceCache(cache *include.SourceCache) *include.SourceCache {
+	if cache == nil {
+		return include.NewSourceCache()
+	}
+
+	return cache
+}
+
 type HTMLTransformerOptions struct {
 	*MarkdownTransformerOptions
 	LayoutURL  string
 	LayoutVars map[string]any
+	// MermaidURL is the url of the mermaid script loaded by
+	// the document, the bundled script is embedded if empty
+	MermaidURL string
+	// AssetURLs are the remote urls of the bundled assets loaded
+	// by the document instead of the embedded ones
+	AssetURLs map[string]string
+	// MermaidCompiler renders the mermaid diagrams as inline SVG,
+	// the diagrams are rendered by the browser if nil
+	MermaidCompiler *diagram.MermaidCompiler
+	// DiagramCompilers render the code blocks of the associated
+	// languages as inline SVG, i.e. "dot" or "d2"
+	DiagramCompilers map[string]diagram.Compiler
 }
 
 type HTMLTransformerOptionFunc func(opts *HTMLTransformerOptions)
@@ -229,6 +330,30 @@ func WithLayoutVars(vars map[string]any) HTMLTransformerOptionFunc {
 	}
 }
 
+func WithMermaidURL(mermaidURL string) HTMLTransformerOptionFunc {
+	return func(opts *HTMLTransformerOptions) {
+		opts.MermaidURL = mermaidURL
+	}
+}
+
+func WithAssetURLs(assetURLs map[string]string) HTMLTransformerOptionFunc {
+	return func(opts *HTMLTransformerOptions) {
+		opts.AssetURLs = assetURLs
+	}
+}
+
+func WithMermaidCompiler(compiler *diagram.MermaidCompiler) HTMLTransformerOptionFunc {
+	return func(opts *HTMLTransformerOptions) {
+		opts.MermaidCompiler = compiler
+	}
+}
+
+func WithDiagramCompilers(compilers map[string]diagram.Compiler) HTMLTransformerOptionFunc {
+	return func(opts *HTMLTransformerOptions) {
+		opts.DiagramCompilers = compilers
+	}
+}
+
 func HTMLMiddleware(funcs ...HTMLTransformerOptionFunc) pipeline.Middleware {
 	opts := NewHTMLTransformerOptions(funcs...)
 	return func(next pipeline.Transformer) pipeline.Transformer {
@@ -239,23 +364,48 @@ func HTMLMiddleware(funcs ...HTMLTransformerOptionFunc) pipeline.Middleware {
 
 			reader := text.NewReader(data)
 
-			parse := newParser(opts.SourcePath, ParserOptions{
+			parse := NewParser(opts.SourcePath, ParserOptions{
 				EmbedLinkedResources: true,
 				LinkReplacements:     opts.LinkReplacements,
+				MaxIncludeDepth:      opts.MaxIncludeDepth,
+				SourceCache:          getSourceCache(opts.SourceCache),
+				DiagramCompilers:     opts.DiagramCompilers,
+				Directives:           opts.Directives,
 			})
 			pc := parser.NewContext()
 			pc = pipeline.WithContext(ctx, pc)
+			pc = directive.WithParser(pc, parse)
 
 			slog.DebugContext(ctx, "parsing markdown file")
 
-			document := parse.Parse(reader, parser.WithContext(pc))
+			document, err := parseDocument(parse, reader, pc)
+			if err != nil {
+				return errors.Wrap(err, "could not parse markdown document")
+			}
+
+			headings, err := toc.Headings(document, data, 1, math.MaxInt)
+			if err != nil {
+				return errors.Wrap(err, "could not retrieve document headings")
+			}
+
+			payload.SetAttribute(attrHeadings, headings)
 
 			meta, ok := pipeline.GetAttribute[map[string]any](payload, attrMeta)
 			if !ok {
 				meta = make(map[string]any)
 			}
 
-			render := newHTMLRenderer()
+			mermaidURL := opts.MermaidURL
+			if mermaidURL == "" {
+				mermaidURL = opts.AssetURLs[mermaidAsset]
+			}
+
+			mermaidExtender, err := newMermaidExtender(mermaidURL, opts.MermaidCompiler, document)
+			if err != nil {
+				return errors.Wrap(err, "could not configure mermaid rendering")
+			}
+
+			render := NewHTMLRenderer(mermaidExtender, opts.DiagramCompilers, opts.Directives)
 
 			var body bytes.Buffer
 
@@ -267,11 +417,12 @@ func HTMLMiddleware(funcs ...HTMLTransformerOptionFunc) pipeline.Middleware {
 
 			slog.DebugContext(ctx, "rendering html layout", slog.String("layout", opts.LayoutURL))
 
-			err := layout.Render(
+			err = layout.Render(
 				ctx, &doc, body.Bytes(),
 				layout.WithURL(opts.LayoutURL),
 				layout.WithVars(opts.LayoutVars),
 				layout.WithMeta(meta),
+				layout.WithAssetURLs(opts.AssetURLs),
 			)
 			if err != nil {
 				return errors.WithStack(err)
@@ -303,6 +454,16 @@ type PDFTransformerOptions struct {
 	NoSandbox           bool
 	HeaderTemplate      string
 	FooterTemplate      string
+	// Vars are the variables exposed to the header and footer templates
+	Vars map[string]any
+	// Version is the version of amatl exposed to the header and footer templates
+	Version string
+	// SectionDepth is the maximum depth of the headings
+	// used as running headers
+	SectionDepth int
+	// Outline enables the generation of the PDF outline
+	// from the headings of the document
+	Outline bool
 }
 
 const (
@@ -317,7 +478,9 @@ const (
 		<div style="font-size:10px;width:100%;padding-left:{{ .MarginLeft }}cm;padding-right:{{ .MarginRight }}cm">
 			<span style="float:right"><span class="pageNumber"></span> / <span class="totalPages"></span></span>
 		</div>`
-	DefaultPDFNoSandbox bool = false
+	DefaultPDFNoSandbox    bool = false
+	DefaultPDFSectionDepth int  = 2
+	DefaultPDFOutline      bool = true
 )
 
 type PDFTransformerOptionFunc func(opts *PDFTransformerOptions)
@@ -336,6 +499,9 @@ func NewPDFTransformerOptions(funcs ...PDFTransformerOptionFunc) *PDFTransformer
 		HeaderTemplate:      DefaultPDFHeaderTemplate,
 		FooterTemplate:      DefaultPDFFooterTemplate,
 		NoSandbox:           DefaultPDFNoSandbox,
+		Vars:                map[string]any{},
+		SectionDepth:        DefaultPDFSectionDepth,
+		Outline:             DefaultPDFOutline,
 	}
 	for _, fn := range funcs {
 		fn(opts)
@@ -415,6 +581,32 @@ func WithNoSandbox(noSandbox bool) PDFTransformerOptionFunc {
 	}
 }
 
+func WithHeaderFooterVars(vars map[string]any) PDFTransformerOptionFunc {
+	return func(opts *PDFTransformerOptions) {
+		for key, value := range vars {
+			opts.Vars[key] = value
+		}
+	}
+}
+
+func WithVersion(version string) PDFTransformerOptionFunc {
+	return func(opts *PDFTransformerOptions) {
+		opts.Version = version
+	}
+}
+
+func WithSectionDepth(depth int) PDFTransformerOptionFunc {
+	return func(opts *PDFTransformerOptions) {
+		opts.SectionDepth = depth
+	}
+}
+
+func WithOutline(outline bool) PDFTransformerOptionFunc {
+	return func(opts *PDFTransformerOptions) {
+		opts.Outline = outline
+	}
+}
+
 func PDFMiddleware(funcs ...PDFTransformerOptionFunc) pipeline.Middleware {
 	opts := NewPDFTransformerOptions(funcs...)
 
@@ -445,14 +637,45 @@ func PDFMiddleware(funcs ...PDFTransformerOptionFunc) pipeline.Middleware {
 			ctx, cancel := chromedp.NewContext(timeoutCtx)
 			defer cancel()
 
-			var output []byte
+			meta, ok := pipeline.GetAttribute[map[string]any](payload, attrMeta)
+			if !ok {
+				meta = make(map[string]any)
+			}
+
+			templates, err := newPDFTemplates(opts)
+			if err != nil {
+				return errors.WithStack(err)
+			}
+
+			templateData := &PDFTemplateData{
+				PDFTransformerOptions: opts,
+				Meta:                  meta,
+				Date:                  time.Now(),
+			}
 
 			slog.DebugContext(ctx, "rendering pdf with chrome", slog.Duration("timeout", opts.Timeout))
 
-			if err := chromedp.Run(ctx, printToPDF(data, &output, opts)); err != nil {
+			if err := chromedp.Run(ctx, loadContent(data)); err != nil {
 				return errors.Wrap(err, "could not execute chrome")
 			}
 
+			output, located, err := printPDF(ctx, opts, templates, templateData)
+			if err != nil {
+				return errors.Wrap(err, "could not print pdf")
+			}
+
+			var outline []pdf.Bookmark
+
+			if opts.Outline {
+				headings, _ := pipeline.GetAttribute[[]toc.Heading](payload, attrHeadings)
+				outline = buildPDFOutline(ctx, headings, located)
+			}
+
+			output, err = pdf.Update(output, outline, getPDFInfo(meta, opts))
+			if err != nil {
+				return errors.Wrap(err, "could not update pdf outline and document information")
+			}
+
 			payload.SetData(output)
 
 			if err := next.Transform(ctx, payload); err != nil {
@@ -466,7 +689,7 @@ func PDFMiddleware(funcs ...PDFTransformerOptionFunc) pipeline.Middleware {
 	}
 }
 
-func printToPDF(html []byte, res *[]byte, opts *PDFTransformerOptions) chromedp.Tasks {
+func loadContent(html []byte) chromedp.Tasks {
 	return chromedp.Tasks{
 		enableLifeCycleEvents(),
 		chromedp.Navigate("about:blank"),
@@ -495,47 +718,195 @@ func printToPDF(html []byte, res *[]byte, opts *PDFTransformerOptions) chromedp.
 			wg.Wait()
 			return nil
 		}),
-		chromedp.ActionFunc(func(ctx context.Context) error {
-			headerTemplate, err := template.New("").Parse(opts.HeaderTemplate)
-			if err != nil {
-				return errors.Wrapf(err, "could not parse header template")
-			}
+	}
+}
 
-			var header bytes.Buffer
-			if err := headerTemplate.Execute(&header, opts); err != nil {
-				return errors.Wrapf(err, "could not execute header template")
-			}
+// printPDF prints the loaded document and returns it with the outline
+// generated by Chrome, if required. If the header or the footer use the
+// current headings, the document is printed once with its outline to locate
+// the headings and its header and footer are stamped afterwards, the
+// content of the document being printed only once to keep its links.
+func printPDF(ctx context.Context, opts *PDFTransformerOptions, templates *pdfTemplates, data *PDFTemplateData) ([]byte, []pdf.Bookmark, error) {
+	runningHeadings := false
 
-			footerTemplate, err := template.New("").Parse(opts.FooterTemplate)
-			if err != nil {
-				return errors.Wrapf(err, "could not parse footer template")
-			}
+	if opts.DisplayHeaderFooter {
+		usesHeadings, err := templates.UsesHeadings(data)
+		if err != nil {
+			return nil, nil, errors.WithStack(err)
+		}
 
-			var footer bytes.Buffer
-			if err := footerTemplate.Execute(&footer, opts); err != nil {
-				return errors.Wrapf(err, "could not execute footer template")
-			}
+		runningHeadings = usesHeadings
+	}
 
-			buf, _, err := page.PrintToPDF().
-				WithDisplayHeaderFooter(opts.DisplayHeaderFooter).
-				WithFooterTemplate(footer.String()).
-				WithHeaderTemplate(header.String()).
-				WithPreferCSSPageSize(true).
-				WithMarginRight(centimetersToInches(opts.MarginRight)).
-				WithMarginTop(centimetersToInches(opts.MarginTop)).
-				WithMarginBottom(centimetersToInches(opts.MarginBottom)).
-				WithMarginLeft(centimetersToInches(opts.MarginLeft)).
-				WithPrintBackground(opts.Background).
-				WithScale(opts.Scale).
-				Do(ctx)
-			if err != nil {
-				return err
-			}
+	withOutline := runningHeadings || opts.Outline
 
-			*res = buf
-			return nil
-		}),
+	params := newPrintParams(opts).
+		WithGenerateTaggedPDF(withOutline).
+		WithGenerateDocumentOutline(withOutline)
+
+	if opts.DisplayHeaderFooter && !runningHeadings {
+		var err error
+
+		params, err = withHeaderFooter(params, templates, data.withHeadings(nil))
+		if err != nil {
+			return nil, nil, errors.WithStack(err)
+		}
+	}
+
+	var title string
+
+	if err := chromedp.Run(ctx, chromedp.Title(&title)); err != nil {
+		return nil, nil, errors.Wrap(err, "could not execute chrome")
+	}
+
+	document, err := printPages(ctx, params)
+	if err != nil {
+		return nil, nil, errors.WithStack(err)
+	}
+
+	if !withOutline {
+		return document, nil, nil
 	}
+
+	outline, err := pdf.Outline(document)
+	if err != nil {
+		return nil, nil, errors.WithStack(err)
+	}
+
+	if !runningHeadings {
+		return document, outline, nil
+	}
+
+	document, err = stampHeaderFooter(ctx, opts, templates, data, document, outline, title)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "could not stamp header and footer")
+	}
+
+	return document, outline, nil
+}
+
+// stampHeaderFooter prints the header and the footer of each range of pages
+// sharing the same headings on blank pages of the size of the pages of the
+// given document, then stamps them on the document
+func stampHeaderFooter(ctx context.Context, opts *PDFTransformerOptions, templates *pdfTemplates, data *PDFTemplateData, document []byte, outline []pdf.Bookmark, title string) ([]byte, error) {
+	pageCount, err := pdf.PageCount(document)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	width, height, err := pdf.PageSize(document)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	sections := pdf.Sections(outline, pageCount, opts.SectionDepth)
+
+	slog.DebugContext(ctx, "printing pdf header and footer with running headings", slog.Int("sections", len(sections)))
+
+	if err := chromedp.Run(ctx, loadContent(blankPages(pageCount, title))); err != nil {
+		return nil, errors.Wrap(err, "could not execute chrome")
+	}
+
+	overlays := make([][]byte, 0, len(sections))
+
+	for _, s := range sections {
+		pageRanges := fmt.Sprintf("%d-%d", s.FromPage, s.ToPage)
+
+		params := newPrintParams(opts).
+			WithPreferCSSPageSize(false).
+			WithPaperWidth(pointsToInches(width)).
+			WithPaperHeight(pointsToInches(height)).
+			WithPrintBackground(false).
+			WithPageRanges(pageRanges)
+
+		params, err := withHeaderFooter(params, templates, data.withHeadings(s.Headings))
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+
+		overlay, err := printPages(ctx, params)
+		if err != nil {
+			return nil, errors.Wrapf(err, "could not print pages '%s'", pageRanges)
+		}
+
+		overlays = append(overlays, overlay)
+	}
+
+	// The overlays only hold the headers and the footers,
+	// without links to keep
+	overlay, err := pdf.Merge(overlays...)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	stamped, err := pdf.Stamp(document, overlay)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	return stamped, nil
+}
+
+// blankPages returns an HTML document with the given
+// title and the given number of blank pages
+func blankPages(count int, title string) []byte {
+	var buff bytes.Buffer
+
+	buff.WriteString("<html><head><title>")
+	buff.WriteString(html.EscapeString(title))
+	buff.WriteString("</title></head><body><div></div>")
+
+	for i := 1; i < count; i++ {
+		buff.WriteString(`<div style="break-before:page"></div>`)
+	}
+
+	buff.WriteString("</body></html>")
+
+	return buff.Bytes()
+}
+
+func newPrintParams(opts *PDFTransformerOptions) *page.PrintToPDFParams {
+	return page.PrintToPDF().
+		WithPreferCSSPageSize(true).
+		WithMarginRight(centimetersToInches(opts.MarginRight)).
+		WithMarginTop(centimetersToInches(opts.MarginTop)).
+		WithMarginBottom(centimetersToInches(opts.MarginBottom)).
+		WithMarginLeft(centimetersToInches(opts.MarginLeft)).
+		WithPrintBackground(opts.Background).
+		WithScale(opts.Scale)
+}
+
+// withHeaderFooter displays the header and the footer
+// executed with the given data on the printed pages
+func withHeaderFooter(params *page.PrintToPDFParams, templates *pdfTemplates, data *PDFTemplateData) (*page.PrintToPDFParams, error) {
+	header, footer, err := templates.Execute(data)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	return params.
+		WithDisplayHeaderFooter(true).
+		WithHeaderTemplate(header).
+		WithFooterTemplate(footer), nil
+}
+
+func printPages(ctx context.Context, params *page.PrintToPDFParams) ([]byte, error) {
+	var res []byte
+
+	err := chromedp.Run(ctx, chromedp.ActionFunc(func(ctx context.Context) error {
+		buf, _, err := params.Do(ctx)
+		if err != nil {
+			return err
+		}
+
+		res = buf
+		return nil
+	}))
+	if err != nil {
+		return nil, errors.Wrap(err, "could not execute chrome")
+	}
+
+	return res, nil
 }
 
 func enableLifeCycleEvents() chromedp.ActionFunc {
@@ -552,6 +923,10 @@ func enableLifeCycleEvents() chromedp.ActionFunc {
 	}
 }
 
+func pointsToInches(points float64) float64 {
+	return points / 72
+}
+
 func centimetersToInches(cm float64) float64 {
 	return cm / 2.54
 }
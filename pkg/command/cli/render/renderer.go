@@ -1,10 +1,21 @@
 package render
 
 import (
+	"sync"
+
+	"github.com/Bornholm/amatl/pkg/diagram"
+	"github.com/Bornholm/amatl/pkg/html/layout/resolver/amatl"
+	"github.com/Bornholm/amatl/pkg/markdown/alert"
 	"github.com/Bornholm/amatl/pkg/markdown/directive"
+	"github.com/Bornholm/amatl/pkg/markdown/directive/abbr"
 	"github.com/Bornholm/amatl/pkg/markdown/directive/include"
+	"github.com/Bornholm/amatl/pkg/markdown/directive/kbd"
+	"github.com/Bornholm/amatl/pkg/markdown/math"
 	"github.com/Bornholm/amatl/pkg/markdown/renderer/markdown"
+	"github.com/pkg/errors"
+	"github.com/vincent-petithory/dataurl"
 	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
 	"github.com/yuin/goldmark/extension"
 	"github.com/yuin/goldmark/renderer"
 	"github.com/yuin/goldmark/renderer/html"
@@ -19,9 +30,69 @@ import (
 	chromahtml "github.com/alecthomas/chroma/v2/formatters/html"
 )
 
-func newMarkdownRenderer() renderer.Renderer {
+// mermaidAsset is the name of the bundled mermaid script
+const mermaidAsset = "mermaid.min.js"
+
+var bundledMermaidURL = sync.OnceValues(func() (string, error) {
+	script, err := amatl.Asset(mermaidAsset)
+	if err != nil {
+		return "", errors.WithStack(err)
+	}
+
+	return dataurl.New(script, "text/javascript").String(), nil
+})
+
+// newMermaidExtender returns an extender rendering the diagrams with the
+// given compiler or, if nil, in the browser with the given mermaid script url,
+// the bundled script being embedded if empty and if the given document has
+// mermaid diagrams
+func newMermaidExtender(mermaidURL string, compiler *diagram.MermaidCompiler, document ast.Node) (*mermaid.Extender, error) {
+	if compiler != nil {
+		return &mermaid.Extender{
+			RenderMode: mermaid.RenderModeServer,
+			Compiler:   compiler,
+		}, nil
+	}
+
+	if mermaidURL == "" && hasMermaidBlock(document) {
+		bundled, err := bundledMermaidURL()
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+
+		mermaidURL = bundled
+	}
+
+	return &mermaid.Extender{
+		RenderMode: mermaid.RenderModeClient,
+		MermaidURL: mermaidURL,
+	}, nil
+}
+
+// hasMermaidBlock returns true if the given document has mermaid diagrams
+func hasMermaidBlock(document ast.Node) bool {
+	found := false
+
+	_ = ast.Walk(document, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if entering && n.Kind() == mermaid.Kind {
+			found = true
+			return ast.WalkStop, nil
+		}
+
+		return ast.WalkContinue, nil
+	})
+
+	return found
+}
+
+// NewMarkdownRenderer returns the renderer of the consolidated Markdown
+// documents, rendering the custom directives of the given registry,
+// the default registry being used if nil
+func NewMarkdownRenderer(directives *directive.Registry) renderer.Renderer {
 	render := markdown.NewRenderer()
 
+	directives = getDirectives(directives)
+
 	render.AddOptions(
 		renderer.WithNodeRenderers(
 			util.Prioritized(
@@ -29,7 +100,6 @@ func newMarkdownRenderer() renderer.Renderer {
 					directive.WithRenderer(
 						include.Type,
 						&include.NodeRenderer{
-							Cache:    cache,
 							Renderer: render,
 						},
 					),
@@ -37,15 +107,19 @@ func newMarkdownRenderer() renderer.Renderer {
 			),
 		),
 		markdown.WithNodeRenderers(node.Renderers()),
+		markdown.WithNodeRenderers(math.MarkdownRenderers()),
 		markdown.WithNodeRenderer(
 			directive.KindDirective,
 			directive.NewMarkdownNodeRenderer(
-				directive.WithMarkdownDirectiveRenderer(
-					include.Type,
-					&include.MarkdownRenderer{
-						Cache: cache,
+				append(
+					[]directive.MarkdownNodeRendererOptionFunc{
+						directive.WithMarkdownDirectiveRenderer(
+							include.Type,
+							&include.MarkdownRenderer{},
+						),
 					},
-				),
+					directives.MarkdownNodeRendererOptions()...,
+				)...,
 			),
 		),
 		markdown.WithNodeRenderer(
@@ -61,22 +135,42 @@ func newMarkdownRenderer() renderer.Renderer {
 	return render
 }
 
-func newHTMLRenderer() renderer.Renderer {
+// NewHTMLRenderer returns the renderer of the HTML documents, rendering the
+// custom directives of the given registry, the default registry being used if nil
+func NewHTMLRenderer(mermaidExtender *mermaid.Extender, diagramCompilers map[string]diagram.Compiler, directives *directive.Registry) renderer.Renderer {
 	markdown := goldmark.New(
 		goldmark.WithExtensions(
 			extension.GFM,
+			extension.Footnote,
+			extension.DefinitionList,
 			highlighting.NewHighlighting(
 				highlighting.WithStyle("nord"),
 				highlighting.WithFormatOptions(
 					chromahtml.WithLineNumbers(false),
 				),
 			),
-			&mermaid.Extender{
-				RenderMode: mermaid.RenderModeClient,
+			mermaidExtender,
+			&diagram.Extender{
+				Compilers: diagramCompilers,
 			},
+			&math.Extender{},
+			&alert.Extender{},
 		),
 		goldmark.WithRendererOptions(
 			html.WithUnsafe(),
+			renderer.WithNodeRenderers(
+				util.Prioritized(
+					directive.NewRenderer(
+						append(
+							[]directive.RendererOptionFunc{
+								directive.WithRenderer(kbd.Type, &kbd.NodeRenderer{}),
+								directive.WithRenderer(abbr.Type, &abbr.NodeRenderer{}),
+							},
+							getDirectives(directives).RendererOptions()...,
+						)...,
+					), 0,
+				),
+			),
 		),
 	)
 
@@ -0,0 +1,135 @@
+package render
+
+import (
+	"os"
+
+	"github.com/Bornholm/amatl/pkg/resolver"
+	"github.com/Bornholm/amatl/pkg/resolver/git"
+	httpResolver "github.com/Bornholm/amatl/pkg/resolver/http"
+	"github.com/pkg/errors"
+	"github.com/urfave/cli/v2"
+	"github.com/urfave/cli/v2/altsrc"
+	"gopkg.in/yaml.v3"
+)
+
+// newBeforeFunc loads the configuration file values into the given flags
+// then configures the resolvers and their policy accordingly
+func newBeforeFunc(flags []cli.Flag) cli.BeforeFunc {
+	initInputSource := altsrc.InitInputSourceWithContext(flags, NewResolverSourceFromFlagFunc(paramConfig))
+
+	return func(ctx *cli.Context) error {
+		if err := initInputSource(ctx); err != nil {
+			return errors.WithStack(err)
+		}
+
+		if err := configureResolvers(ctx); err != nil {
+			return errors.Wrap(err, "could not configure resolvers")
+		}
+
+		if policy := getResolverPolicy(ctx); policy != nil {
+			ctx.Context = resolver.WithPolicy(ctx.Context, policy)
+		}
+
+		return nil
+	}
+}
+
+// configureResolvers replaces the default remote resolvers
+// by resolvers configured with the command flags
+func configureResolvers(ctx *cli.Context) error {
+	offline := getOffline(ctx)
+
+	credentials, err := getHTTPCredentials(ctx)
+	if err != nil {
+		return errors.Wrap(err, "could not load http credentials")
+	}
+
+	httpRes := httpResolver.NewResolver(
+		httpResolver.WithCacheDir(getHTTPCacheDir(ctx)),
+		httpResolver.WithOffline(offline),
+		httpResolver.WithTimeout(getHTTPTimeout(ctx)),
+		httpResolver.WithRetries(getHTTPRetries(ctx), httpResolver.DefaultRetryBackoff),
+		httpResolver.WithUserAgent(getHTTPUserAgent(ctx)),
+		httpResolver.WithCredentials(credentials...),
+	)
+
+	resolver.Register(httpResolver.Scheme, httpRes)
+	resolver.Register(httpResolver.SchemeAlt, httpRes)
+
+	gitRes := git.NewResolver(
+		git.WithOffline(offline),
+	)
+
+	for _, scheme := range git.Schemes {
+		resolver.Register(scheme, gitRes)
+	}
+
+	return nil
+}
+
+const configKeyHTTPHosts = "http-hosts"
+
+// httpHostConfig is an entry of the 'http-hosts' section of the configuration file
+type httpHostConfig struct {
+	Host     string            `yaml:"host"`
+	Username string            `yaml:"username"`
+	Password string            `yaml:"password"`
+	Token    string            `yaml:"token"`
+	Headers  map[string]string `yaml:"headers"`
+}
+
+// getHTTPCredentials loads the credentials of the 'http-hosts' section of the
+// configuration file, then the ones of the netrc file. Environment variables
+// references (i.e. ${TOKEN}) are expanded in the configuration file values.
+func getHTTPCredentials(ctx *cli.Context) ([]httpResolver.Credentials, error) {
+	credentials := make([]httpResolver.Credentials, 0)
+
+	if configURL := ctx.String(paramConfig); configURL != "" {
+		values, err := readConfig(ctx.Context, configURL)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+
+		if rawHosts, exists := values[configKeyHTTPHosts]; exists {
+			data, err := yaml.Marshal(rawHosts)
+			if err != nil {
+				return nil, errors.WithStack(err)
+			}
+
+			var hosts []httpHostConfig
+			if err := yaml.Unmarshal(data, &hosts); err != nil {
+				return nil, errors.Wrapf(err, "could not decode '%s' configuration", configKeyHTTPHosts)
+			}
+
+			for i, h := range hosts {
+				if h.Host == "" {
+					return nil, errors.Errorf("missing host in '%s' configuration entry #%d", configKeyHTTPHosts, i)
+				}
+
+				headers := make(map[string]string, len(h.Headers))
+				for name, value := range h.Headers {
+					headers[name] = os.ExpandEnv(value)
+				}
+
+				credentials = append(credentials, httpResolver.Credentials{
+					Host:     h.Host,
+					Username: os.ExpandEnv(h.Username),
+					Password: os.ExpandEnv(h.Password),
+					Token:    os.ExpandEnv(h.Token),
+					Headers:  headers,
+				})
+			}
+		}
+	}
+
+	if netrc := getHTTPNetrc(ctx); netrc != "" {
+		netrcCredentials, err := httpResolver.ReadNetrc(netrc)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+
+		credentials = append(credentials, netrcCredentials...)
+	}
+
+	return credentials, nil
+}
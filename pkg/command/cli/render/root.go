@@ -6,11 +6,7 @@ func Root() *cli.Command {
 	return &cli.Command{
 		Name: "render",
 		Flags: []cli.Flag{
-			&cli.StringFlag{
-				Name:    "config",
-				Aliases: []string{"c"},
-				Usage:   "configuration file to use",
-			},
+			flagConfig,
 		},
 		Subcommands: []*cli.Command{
 			HTML(),
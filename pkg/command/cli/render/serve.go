@@ -0,0 +1,289 @@
+package render
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"html"
+	"log/slog"
+	"net/http"
+	"os"
+	"os/signal"
+	"sync"
+	"time"
+
+	"github.com/Bornholm/amatl/pkg/resolver"
+	"github.com/pkg/errors"
+	"github.com/urfave/cli/v2"
+)
+
+const (
+	previewEventsPath = "/_amatl/events"
+	previewScript     = `<script>(function(){var es=new EventSource("` + previewEventsPath + `");es.addEventListener("reload",function(){window.location.reload();});})();</script>`
+)
+
+func Serve() *cli.Command {
+	flags := withServeFlags()
+	return &cli.Command{
+		Name:   "serve",
+		Usage:  "render a markdown file as html, serve it and reload connected browsers when any of its resources changes",
+		Flags:  flags,
+		Before: newBeforeFunc(flags),
+		Action: func(ctx *cli.Context) error {
+			address := getServeAddress(ctx)
+			interval := getServeWatchInterval(ctx)
+
+			baseCtx, stop := signal.NotifyContext(ctx.Context, os.Interrupt)
+			defer stop()
+
+			watcher := newPollWatcher(interval)
+			go watcher.Run(baseCtx)
+
+			mermaidCompiler, err := getMermaidCompiler(ctx)
+			if err != nil {
+				return errors.WithStack(err)
+			}
+
+			defer closeMermaidCompiler(ctx, mermaidCompiler)
+
+			preview := newPreviewServer()
+			caches := newRenderCaches()
+			caches.Mermaid = mermaidCompiler
+			caches.Diagrams = getDiagramCompilers(ctx)
+
+			var watched []string
+
+			rebuild := func() {
+				tracker := resolver.NewTracker()
+				renderCtx := resolver.WithTracker(baseCtx, tracker)
+
+				// The states of the watched files are taken before rendering,
+				// so that the changes made during the rendering are not missed
+				before := statFiles(watched)
+				start := time.Now()
+
+				data, err := renderPreview(ctx, renderCtx, caches)
+				if err != nil {
+					slog.ErrorContext(baseCtx, "could not render document", slog.Any("error", err))
+				} else {
+					slog.InfoContext(baseCtx, "document rendered", slog.Duration("duration", time.Since(start)))
+				}
+
+				preview.Update(data, err)
+
+				// The missing resources are watched too, to render again the
+				// document once created, as well as the directories of the
+				// glob patterns, to detect the added and removed files
+				watched = append(
+					watchableFiles(append(tracker.Paths(), tracker.FailedPaths()...)),
+					watchableDirs(tracker.Patterns())...,
+				)
+
+				slog.DebugContext(baseCtx, "watching files", slog.Any("files", watched))
+
+				watcher.Watch(watchedStates(watched, before, start, tracker.Dependencies()))
+			}
+
+			rebuild()
+
+			server := &http.Server{
+				Addr:    address,
+				Handler: preview,
+			}
+
+			go func() {
+				for {
+					select {
+					case <-baseCtx.Done():
+						return
+					case <-watcher.Changes():
+						rebuild()
+					}
+				}
+			}()
+
+			go func() {
+				<-baseCtx.Done()
+
+				shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+				defer cancel()
+
+				if err := server.Shutdown(shutdownCtx); err != nil {
+					slog.ErrorContext(shutdownCtx, "could not shutdown preview server", slog.Any("error", errors.WithStack(err)))
+				}
+			}()
+
+			slog.InfoContext(baseCtx, "serving document", slog.String("address", fmt.Sprintf("http://%s", address)))
+
+			if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				return errors.WithStack(err)
+			}
+
+			return nil
+		},
+	}
+}
+
+// renderPreview renders the document as html and writes it to the
+// configured output if any. Parsing errors are raised as panics by the
+// directive transformers, so they are recovered here to keep the server alive.
+func renderPreview(ctx *cli.Context, renderCtx context.Context, caches *renderCaches) (data []byte, err error) {
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			if recoveredErr, ok := recovered.(error); ok {
+				err = errors.WithStack(recoveredErr)
+			} else {
+				err = errors.Errorf("%v", recovered)
+			}
+		}
+	}()
+
+	payload, err := renderHTML(ctx, renderCtx, caches)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	if ctx.String(paramOutput) != "-" {
+		output, err := getOutput(ctx)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+
+		defer func() {
+			if err := output.Close(); err != nil {
+				panic(errors.WithStack(err))
+			}
+		}()
+
+		if _, err := output.Write(payload.GetData()); err != nil {
+			return nil, errors.WithStack(err)
+		}
+	}
+
+	return payload.GetData(), nil
+}
+
+type previewServer struct {
+	mutex   sync.RWMutex
+	content []byte
+	clients map[chan struct{}]struct{}
+}
+
+// ServeHTTP implements http.Handler.
+func (s *previewServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch r.URL.Path {
+	case previewEventsPath:
+		s.handleEvents(w, r)
+	case "/":
+		s.handleContent(w, r)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func (s *previewServer) handleContent(w http.ResponseWriter, r *http.Request) {
+	s.mutex.RLock()
+	content := s.content
+	s.mutex.RUnlock()
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+
+	if _, err := w.Write(content); err != nil {
+		slog.ErrorContext(r.Context(), "could not write response", slog.Any("error", errors.WithStack(err)))
+	}
+}
+
+func (s *previewServer) handleEvents(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming not supported", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+
+	reload := s.subscribe()
+	defer s.unsubscribe(reload)
+
+	flusher.Flush()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-reload:
+			if _, err := fmt.Fprint(w, "event: reload\ndata: {}\n\n"); err != nil {
+				return
+			}
+
+			flusher.Flush()
+		}
+	}
+}
+
+// Update replaces the served content and notifies connected browsers
+func (s *previewServer) Update(content []byte, err error) {
+	if err != nil {
+		content = []byte(fmt.Sprintf(
+			"<html><head><title>amatl - error</title></head><body><h1>Could not render document</h1><pre>%s</pre></body></html>",
+			html.EscapeString(err.Error()),
+		))
+	}
+
+	content = injectPreviewScript(content)
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.content = content
+
+	for client := range s.clients {
+		select {
+		case client <- struct{}{}:
+		default:
+		}
+	}
+}
+
+func (s *previewServer) subscribe() chan struct{} {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	client := make(chan struct{}, 1)
+	s.clients[client] = struct{}{}
+
+	return client
+}
+
+func (s *previewServer) unsubscribe(client chan struct{}) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	delete(s.clients, client)
+}
+
+func newPreviewServer() *previewServer {
+	return &previewServer{
+		content: []byte{},
+		clients: make(map[chan struct{}]struct{}),
+	}
+}
+
+func injectPreviewScript(content []byte) []byte {
+	idx := bytes.LastIndex(bytes.ToLower(content), []byte("</body>"))
+	if idx < 0 {
+		return append(content, []byte(previewScript)...)
+	}
+
+	injected := make([]byte, 0, len(content)+len(previewScript))
+	injected = append(injected, content[:idx]...)
+	injected = append(injected, []byte(previewScript)...)
+	injected = append(injected, content[idx:]...)
+
+	return injected
+}
+
+var _ http.Handler = &previewServer{}
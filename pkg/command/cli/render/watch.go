@@ -0,0 +1,236 @@
+package render
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/Bornholm/amatl/pkg/resolver"
+)
+
+type fileState struct {
+	ModTime time.Time
+	Size    int64
+	Exists  bool
+}
+
+// pollWatcher periodically checks the modification time and size of
+// a set of local files and directories and notifies when any of them
+// changed since its last known state.
+type pollWatcher struct {
+	interval time.Duration
+	states   chan map[string]fileState
+	changes  chan struct{}
+}
+
+// Watch replaces the watched files by the given ones,
+// compared to the given states on the next check
+func (w *pollWatcher) Watch(states map[string]fileState) {
+	w.states <- states
+}
+
+func (w *pollWatcher) Changes() <-chan struct{} {
+	return w.changes
+}
+
+func (w *pollWatcher) Run(ctx context.Context) {
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	states := map[string]fileState{}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+
+		case states = <-w.states:
+
+		case <-ticker.C:
+			changed := false
+			for f, previous := range states {
+				current := statFile(f)
+				if current == previous {
+					continue
+				}
+
+				states[f] = current
+				changed = true
+			}
+
+			if !changed {
+				continue
+			}
+
+			select {
+			case w.changes <- struct{}{}:
+			default:
+			}
+		}
+	}
+}
+
+func newPollWatcher(interval time.Duration) *pollWatcher {
+	return &pollWatcher{
+		interval: interval,
+		states:   make(chan map[string]fileState),
+		changes:  make(chan struct{}, 1),
+	}
+}
+
+func statFile(filename string) fileState {
+	stat, err := os.Stat(filename)
+	if err != nil {
+		return fileState{}
+	}
+
+	return fileState{
+		ModTime: stat.ModTime(),
+		Size:    stat.Size(),
+		Exists:  true,
+	}
+}
+
+// statFiles returns the current states of the given files
+func statFiles(files []string) map[string]fileState {
+	states := make(map[string]fileState, len(files))
+	for _, f := range files {
+		states[f] = statFile(f)
+	}
+
+	return states
+}
+
+// watchedStates returns the states to compare the given files to, the states
+// taken before the rendering started being used when known. The other files
+// changed since the given start time, or whose content differs from the
+// resolved one, are given an empty state to render the document again.
+func watchedStates(files []string, before map[string]fileState, start time.Time, dependencies []resolver.Dependency) map[string]fileState {
+	states := make(map[string]fileState, len(files))
+
+	for _, f := range files {
+		if state, exists := before[f]; exists {
+			states[f] = state
+			continue
+		}
+
+		state := statFile(f)
+		if state.ModTime.Before(start) {
+			states[f] = state
+		} else {
+			states[f] = fileState{}
+		}
+	}
+
+	for _, d := range dependencies {
+		filename, ok := d.Path.LocalPath()
+		if !ok {
+			continue
+		}
+
+		abs, err := filepath.Abs(filename)
+		if err != nil {
+			continue
+		}
+
+		if _, watched := states[abs]; !watched || hashFile(abs) == d.Hash {
+			continue
+		}
+
+		states[abs] = fileState{}
+	}
+
+	return states
+}
+
+// hashFile returns the hex encoded SHA-256 of the given file
+// content, or an empty string if it can not be read
+func hashFile(filename string) string {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return ""
+	}
+
+	sum := sha256.Sum256(data)
+
+	return hex.EncodeToString(sum[:])
+}
+
+// watchableDirs returns the local directories of the given glob patterns,
+// to detect the creation and the removal of the matching files
+func watchableDirs(patterns []resolver.Path) []string {
+	dirs := make([]string, 0, len(patterns))
+
+	for _, p := range patterns {
+		pattern, ok := p.LocalPath()
+		if !ok {
+			continue
+		}
+
+		pattern, err := filepath.Abs(pattern)
+		if err != nil {
+			continue
+		}
+
+		// Every directory level with glob special characters is
+		// expanded, up to the base directory of the pattern
+		dir := filepath.Dir(pattern)
+		for {
+			if !hasGlobMeta(dir) {
+				if !slices.Contains(dirs, dir) {
+					dirs = append(dirs, dir)
+				}
+
+				break
+			}
+
+			matches, err := filepath.Glob(dir)
+			if err == nil {
+				for _, m := range matches {
+					if !slices.Contains(dirs, m) {
+						dirs = append(dirs, m)
+					}
+				}
+			}
+
+			dir = filepath.Dir(dir)
+		}
+	}
+
+	return dirs
+}
+
+func hasGlobMeta(path string) bool {
+	return strings.ContainsAny(path, "*?[")
+}
+
+// watchableFiles returns the local filesystem paths of the given resolved
+// paths, ignoring resources that can not be watched (remote, embedded, etc)
+func watchableFiles(paths []resolver.Path) []string {
+	files := make([]string, 0, len(paths))
+
+	for _, p := range paths {
+		filename, ok := p.LocalPath()
+		if !ok {
+			continue
+		}
+
+		abs, err := filepath.Abs(filename)
+		if err != nil {
+			continue
+		}
+
+		if slices.Contains(files, abs) {
+			continue
+		}
+
+		files = append(files, abs)
+	}
+
+	return files
+}
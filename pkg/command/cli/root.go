@@ -11,6 +11,7 @@ func Root() *cli.Command {
 		Name: "cli",
 		Subcommands: []*cli.Command{
 			render.Root(),
+			render.Serve(),
 			mcp.Root(),
 		},
 	}
@@ -0,0 +1,31 @@
+package diagram
+
+import "github.com/yuin/goldmark/ast"
+
+// Kind is the node kind of a diagram [Block] node
+var Kind = ast.NewNodeKind("DiagramBlock")
+
+// Block is a fenced code block rendered as a diagram. Its raw
+// contents are the source of the diagram.
+type Block struct {
+	ast.BaseBlock
+	// Language is the info string of the original code block, i.e. "dot" or "d2"
+	Language string
+	// SVG is the rendered diagram
+	SVG string
+}
+
+// IsRaw implements ast.Node.
+func (*Block) IsRaw() bool { return true }
+
+// Kind implements ast.Node.
+func (*Block) Kind() ast.NodeKind { return Kind }
+
+// Dump implements ast.Node.
+func (b *Block) Dump(source []byte, level int) {
+	ast.DumpHelper(b, source, level, map[string]string{
+		"Language": b.Language,
+	}, nil)
+}
+
+var _ ast.Node = &Block{}
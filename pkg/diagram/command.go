@@ -0,0 +1,136 @@
+package diagram
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Compiler renders the source of a diagram to SVG
+type Compiler interface {
+	Compile(ctx context.Context, source string) (string, error)
+}
+
+// CommandCompiler renders diagrams to SVG with an executable reading
+// the source of the diagram on its standard input and writing the SVG
+// document on its standard output. The rendered diagrams are cached by source.
+type CommandCompiler struct {
+	opts *CommandOptions
+
+	mutex sync.Mutex
+	cache map[string]string
+}
+
+// Compile implements Compiler.
+func (c *CommandCompiler) Compile(ctx context.Context, source string) (string, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	hash := sha256.Sum256([]byte(source))
+	key := hex.EncodeToString(hash[:])
+
+	if svg, exists := c.cache[key]; exists {
+		return svg, nil
+	}
+
+	timeoutCtx, cancel := context.WithTimeout(ctx, c.opts.Timeout)
+	defer cancel()
+
+	var stdout, stderr bytes.Buffer
+
+	cmd := exec.CommandContext(timeoutCtx, c.opts.ExecPath, c.opts.Args...)
+	cmd.Stdin = strings.NewReader(source)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		if message := strings.TrimSpace(stderr.String()); message != "" {
+			return "", errors.Wrapf(err, "could not execute '%s': %s", c.opts.ExecPath, message)
+		}
+
+		return "", errors.Wrapf(err, "could not execute '%s'", c.opts.ExecPath)
+	}
+
+	svg := stdout.String()
+
+	// Strip the xml declaration and the doctype
+	// for the document to be inlined in html
+	start := strings.Index(svg, "<svg")
+	if start == -1 {
+		return "", errors.Errorf("could not find svg element in '%s' output", c.opts.ExecPath)
+	}
+
+	svg = svg[start:]
+
+	c.cache[key] = svg
+
+	return svg, nil
+}
+
+func NewCommandCompiler(execPath string, args []string, funcs ...CommandOptionFunc) *CommandCompiler {
+	opts := NewCommandOptions(funcs...)
+	opts.ExecPath = execPath
+	opts.Args = args
+
+	return &CommandCompiler{
+		opts:  opts,
+		cache: make(map[string]string),
+	}
+}
+
+// NewGraphvizCompiler returns a compiler rendering Graphviz
+// diagrams with the given dot executable
+func NewGraphvizCompiler(execPath string, funcs ...CommandOptionFunc) *CommandCompiler {
+	return NewCommandCompiler(execPath, []string{"-Tsvg"}, funcs...)
+}
+
+// NewD2Compiler returns a compiler rendering D2 diagrams with the D2 Go
+// library when built with the 'd2' build tag, or with the given d2
+// executable otherwise. It returns nil if none of them is available.
+func NewD2Compiler(execPath string, funcs ...CommandOptionFunc) Compiler {
+	if compiler := newD2LibraryCompiler(); compiler != nil {
+		return compiler
+	}
+
+	if execPath == "" {
+		return nil
+	}
+
+	return NewCommandCompiler(execPath, []string{"-", "-"}, funcs...)
+}
+
+var _ Compiler = &CommandCompiler{}
+
+type CommandOptions struct {
+	ExecPath string
+	Args     []string
+	// Timeout is the maximum duration of the rendering of a diagram
+	Timeout time.Duration
+}
+
+type CommandOptionFunc func(opts *CommandOptions)
+
+const DefaultCommandTimeout = 30 * time.Second
+
+func NewCommandOptions(funcs ...CommandOptionFunc) *CommandOptions {
+	opts := &CommandOptions{
+		Timeout: DefaultCommandTimeout,
+	}
+	for _, fn := range funcs {
+		fn(opts)
+	}
+	return opts
+}
+
+func WithCommandTimeout(timeout time.Duration) CommandOptionFunc {
+	return func(opts *CommandOptions) {
+		opts.Timeout = timeout
+	}
+}
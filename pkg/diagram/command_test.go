@@ -0,0 +1,77 @@
+package diagram
+
+import (
+	"context"
+	"os/exec"
+	"strings"
+	"testing"
+
+	"github.com/pkg/errors"
+)
+
+func TestCommandCompiler(t *testing.T) {
+	execPath, err := exec.LookPath("cat")
+	if err != nil {
+		t.Skip("cat is not available")
+	}
+
+	compiler := NewCommandCompiler(execPath, nil)
+
+	source := `<?xml version="1.0" encoding="UTF-8" standalone="no"?>
+<!DOCTYPE svg PUBLIC "-//W3C//DTD SVG 1.1//EN" "http://www.w3.org/Graphics/SVG/1.1/DTD/svg11.dtd">
+<svg width="10" height="10"></svg>
+`
+
+	svg, err := compiler.Compile(context.Background(), source)
+	if err != nil {
+		t.Fatalf("%+v", errors.WithStack(err))
+	}
+
+	if e, g := "<svg width=\"10\" height=\"10\"></svg>\n", svg; e != g {
+		t.Errorf("svg: expected '%s', got '%s'", e, g)
+	}
+
+	if _, err := compiler.Compile(context.Background(), "not a diagram"); err == nil {
+		t.Errorf("expected error on output without svg element")
+	}
+}
+
+func TestGraphvizCompiler(t *testing.T) {
+	execPath, err := exec.LookPath("dot")
+	if err != nil {
+		t.Skip("dot is not available")
+	}
+
+	compiler := NewGraphvizCompiler(execPath)
+
+	svg, err := compiler.Compile(context.Background(), "digraph { A -> B }")
+	if err != nil {
+		t.Fatalf("%+v", errors.WithStack(err))
+	}
+
+	if !strings.HasPrefix(svg, "<svg") {
+		t.Errorf("expected svg, got '%s'", svg)
+	}
+
+	if _, err := compiler.Compile(context.Background(), "digraph {"); err == nil {
+		t.Errorf("expected error on invalid diagram")
+	}
+}
+
+func TestD2Compiler(t *testing.T) {
+	execPath, err := exec.LookPath("d2")
+	if err != nil {
+		t.Skip("d2 is not available")
+	}
+
+	compiler := NewD2Compiler(execPath)
+
+	svg, err := compiler.Compile(context.Background(), "a -> b")
+	if err != nil {
+		t.Fatalf("%+v", errors.WithStack(err))
+	}
+
+	if !strings.HasPrefix(svg, "<svg") {
+		t.Errorf("expected svg, got '%s'", svg)
+	}
+}
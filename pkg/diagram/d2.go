@@ -0,0 +1,79 @@
+//go:build d2
+
+package diagram
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"sync"
+
+	"github.com/pkg/errors"
+	"oss.terrastruct.com/d2/d2graph"
+	"oss.terrastruct.com/d2/d2layouts/d2dagrelayout"
+	"oss.terrastruct.com/d2/d2lib"
+	"oss.terrastruct.com/d2/d2renderers/d2svg"
+	"oss.terrastruct.com/d2/lib/log"
+	"oss.terrastruct.com/d2/lib/textmeasure"
+)
+
+// D2Compiler renders D2 diagrams to SVG with the D2 Go library,
+// using the dagre layout. The rendered diagrams are cached by source.
+type D2Compiler struct {
+	mutex sync.Mutex
+	ruler *textmeasure.Ruler
+	cache map[string]string
+}
+
+// Compile implements Compiler.
+func (c *D2Compiler) Compile(ctx context.Context, source string) (string, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	hash := sha256.Sum256([]byte(source))
+	key := hex.EncodeToString(hash[:])
+
+	if svg, exists := c.cache[key]; exists {
+		return svg, nil
+	}
+
+	if c.ruler == nil {
+		ruler, err := textmeasure.NewRuler()
+		if err != nil {
+			return "", errors.WithStack(err)
+		}
+
+		c.ruler = ruler
+	}
+
+	renderOpts := &d2svg.RenderOpts{}
+
+	compileOpts := &d2lib.CompileOptions{
+		Ruler: c.ruler,
+		LayoutResolver: func(engine string) (d2graph.LayoutGraph, error) {
+			return d2dagrelayout.DefaultLayout, nil
+		},
+	}
+
+	diagram, _, err := d2lib.Compile(log.WithDefault(ctx), source, compileOpts, renderOpts)
+	if err != nil {
+		return "", errors.Wrap(err, "could not compile d2 diagram")
+	}
+
+	svg, err := d2svg.Render(diagram, renderOpts)
+	if err != nil {
+		return "", errors.Wrap(err, "could not render d2 diagram")
+	}
+
+	c.cache[key] = string(svg)
+
+	return string(svg), nil
+}
+
+func newD2LibraryCompiler() Compiler {
+	return &D2Compiler{
+		cache: make(map[string]string),
+	}
+}
+
+var _ Compiler = &D2Compiler{}
@@ -0,0 +1,9 @@
+//go:build !d2
+
+package diagram
+
+// newD2LibraryCompiler returns nil, the D2 Go library
+// being only available with the 'd2' build tag
+func newD2LibraryCompiler() Compiler {
+	return nil
+}
@@ -0,0 +1,28 @@
+//go:build d2
+
+package diagram
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/pkg/errors"
+)
+
+func TestD2Compiler(t *testing.T) {
+	compiler := NewD2Compiler("")
+
+	if _, ok := compiler.(*D2Compiler); !ok {
+		t.Fatalf("expected compiler to be a *D2Compiler, got %T", compiler)
+	}
+
+	svg, err := compiler.Compile(context.Background(), "a -> b")
+	if err != nil {
+		t.Fatalf("%+v", errors.WithStack(err))
+	}
+
+	if !strings.Contains(svg, "<svg") {
+		t.Errorf("expected output to contain an svg element, got '%s'", svg)
+	}
+}
@@ -0,0 +1,138 @@
+package diagram
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+
+	"github.com/Bornholm/amatl/pkg/pipeline"
+	"github.com/pkg/errors"
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/renderer"
+	"github.com/yuin/goldmark/text"
+	"github.com/yuin/goldmark/util"
+)
+
+// Extender renders the fenced code blocks of the languages
+// associated with a compiler as inline SVG diagrams
+type Extender struct {
+	// Compilers are the diagram compilers indexed by code block language
+	Compilers map[string]Compiler
+}
+
+// Extend implements goldmark.Extender.
+func (e *Extender) Extend(m goldmark.Markdown) {
+	if len(e.Compilers) == 0 {
+		return
+	}
+
+	m.Parser().AddOptions(
+		parser.WithASTTransformers(
+			util.Prioritized(&Transformer{Compilers: e.Compilers}, 100),
+		),
+	)
+
+	m.Renderer().AddOptions(
+		renderer.WithNodeRenderers(
+			util.Prioritized(&HTMLRenderer{}, 100),
+		),
+	)
+}
+
+var _ goldmark.Extender = &Extender{}
+
+// Transformer replaces the fenced code blocks of the languages
+// associated with a compiler by diagram blocks, compiled with
+// the context of the pipeline
+type Transformer struct {
+	Compilers map[string]Compiler
+}
+
+// Transform implements parser.ASTTransformer.
+func (t *Transformer) Transform(doc *ast.Document, reader text.Reader, pc parser.Context) {
+	ctx, err := pipeline.FromParserContext(pc)
+	if err != nil {
+		ctx = context.Background()
+	}
+
+	blocks := make([]*ast.FencedCodeBlock, 0)
+
+	// Collect the blocks to be replaced without modifying the tree
+	_ = ast.Walk(doc, func(node ast.Node, entering bool) (ast.WalkStatus, error) {
+		if !entering {
+			return ast.WalkContinue, nil
+		}
+
+		codeBlock, ok := node.(*ast.FencedCodeBlock)
+		if !ok {
+			return ast.WalkContinue, nil
+		}
+
+		if _, exists := t.Compilers[string(codeBlock.Language(reader.Source()))]; !exists {
+			return ast.WalkContinue, nil
+		}
+
+		blocks = append(blocks, codeBlock)
+
+		return ast.WalkSkipChildren, nil
+	})
+
+	for _, codeBlock := range blocks {
+		language := string(codeBlock.Language(reader.Source()))
+
+		var diagram bytes.Buffer
+
+		lines := codeBlock.Lines()
+		for i := 0; i < lines.Len(); i++ {
+			line := lines.At(i)
+			diagram.Write(line.Value(reader.Source()))
+		}
+
+		svg, err := t.Compilers[language].Compile(ctx, diagram.String())
+		if err != nil {
+			panic(errors.Wrapf(err, "could not render %s diagram", language))
+		}
+
+		block := &Block{
+			Language: language,
+			SVG:      svg,
+		}
+		block.SetLines(codeBlock.Lines())
+
+		parent := codeBlock.Parent()
+		if parent != nil {
+			parent.ReplaceChild(parent, codeBlock, block)
+		}
+	}
+}
+
+var _ parser.ASTTransformer = &Transformer{}
+
+// HTMLRenderer renders the diagram blocks as inline SVG
+type HTMLRenderer struct{}
+
+// RegisterFuncs implements renderer.NodeRenderer.
+func (r *HTMLRenderer) RegisterFuncs(reg renderer.NodeRendererFuncRegisterer) {
+	reg.Register(Kind, r.render)
+}
+
+func (r *HTMLRenderer) render(w util.BufWriter, source []byte, node ast.Node, entering bool) (ast.WalkStatus, error) {
+	if !entering {
+		return ast.WalkSkipChildren, nil
+	}
+
+	block, ok := node.(*Block)
+	if !ok {
+		return ast.WalkStop, errors.Errorf("unexpected node type '%T'", node)
+	}
+
+	_, _ = fmt.Fprintf(w, "<div class=\"amatl-diagram amatl-diagram-%s\">", util.EscapeHTML([]byte(block.Language)))
+	_, _ = w.WriteString(block.SVG)
+	_, _ = w.WriteString("</div>\n")
+
+	return ast.WalkSkipChildren, nil
+}
+
+var _ renderer.NodeRenderer = &HTMLRenderer{}
@@ -0,0 +1,58 @@
+package diagram
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/yuin/goldmark"
+)
+
+type fakeCompiler struct {
+	sources []string
+}
+
+func (c *fakeCompiler) Compile(ctx context.Context, source string) (string, error) {
+	c.sources = append(c.sources, source)
+	return "<svg></svg>", nil
+}
+
+func TestExtender(t *testing.T) {
+	compiler := &fakeCompiler{}
+
+	markdown := goldmark.New(
+		goldmark.WithExtensions(
+			&Extender{
+				Compilers: map[string]Compiler{
+					"dot": compiler,
+				},
+			},
+		),
+	)
+
+	source := "```dot\ndigraph { A -> B }\n```\n\n```go\nfunc main() {}\n```\n"
+
+	var html bytes.Buffer
+
+	if err := markdown.Convert([]byte(source), &html); err != nil {
+		t.Fatalf("%+v", errors.WithStack(err))
+	}
+
+	if e, g := 1, len(compiler.sources); e != g {
+		t.Fatalf("len(compiler.sources): expected '%d', got '%d'", e, g)
+	}
+
+	if e, g := "digraph { A -> B }\n", compiler.sources[0]; e != g {
+		t.Errorf("compiler.sources[0]: expected '%s', got '%s'", e, g)
+	}
+
+	if e, g := `<div class="amatl-diagram amatl-diagram-dot"><svg></svg></div>`, html.String(); !strings.Contains(g, e) {
+		t.Errorf("expected html to contain '%s', got '%s'", e, g)
+	}
+
+	if e, g := `<code class="language-go">`, html.String(); !strings.Contains(g, e) {
+		t.Errorf("expected html to contain '%s', got '%s'", e, g)
+	}
+}
@@ -0,0 +1,227 @@
+package diagram
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	cdruntime "github.com/chromedp/cdproto/runtime"
+	"github.com/chromedp/chromedp"
+	"github.com/pkg/errors"
+	"go.abhg.dev/goldmark/mermaid"
+)
+
+const mermaidRenderFunc = `
+async function amatlRenderMermaid(id, source) {
+	const { svg } = await mermaid.render(id, source);
+	return svg;
+}
+`
+
+// MermaidCompiler renders Mermaid diagrams to SVG with a headless Chrome.
+// The browser is started on the first compilation and the rendered
+// diagrams are cached by source.
+type MermaidCompiler struct {
+	opts *MermaidOptions
+
+	mutex   sync.Mutex
+	ctx     context.Context
+	cancels []context.CancelFunc
+	cache   map[string]string
+}
+
+// Compile implements mermaid.Compiler.
+func (c *MermaidCompiler) Compile(ctx context.Context, req *mermaid.CompileRequest) (*mermaid.CompileResponse, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	hash := sha256.Sum256([]byte(req.Source))
+	key := hex.EncodeToString(hash[:])
+
+	if svg, exists := c.cache[key]; exists {
+		return &mermaid.CompileResponse{SVG: svg}, nil
+	}
+
+	if c.ctx == nil {
+		if err := c.start(); err != nil {
+			return nil, errors.Wrap(err, "could not start mermaid renderer")
+		}
+	}
+
+	// Diagrams ids must be unique in the document
+	// as they are used to scope their styles
+	id := "amatl-mermaid-" + key[:12]
+
+	encodedID, err := json.Marshal(id)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	encodedSource, err := json.Marshal(req.Source)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	timeoutCtx, cancel := context.WithTimeout(c.ctx, c.opts.Timeout)
+	defer cancel()
+
+	var svg string
+
+	err = chromedp.Run(timeoutCtx, chromedp.Evaluate(
+		fmt.Sprintf("amatlRenderMermaid(%s, %s)", encodedID, encodedSource),
+		&svg,
+		func(p *cdruntime.EvaluateParams) *cdruntime.EvaluateParams {
+			return p.WithAwaitPromise(true)
+		},
+	))
+	if err != nil {
+		return nil, errors.Wrap(err, "could not render mermaid diagram")
+	}
+
+	c.cache[key] = svg
+
+	return &mermaid.CompileResponse{SVG: svg}, nil
+}
+
+func (c *MermaidCompiler) start() error {
+	if c.opts.Script == "" {
+		return errors.New("mermaid script is missing")
+	}
+
+	allocatorOptions := chromedp.DefaultExecAllocatorOptions[:]
+
+	if c.opts.NoSandbox {
+		allocatorOptions = append(allocatorOptions, chromedp.NoSandbox)
+	}
+
+	if c.opts.ExecPath != "" {
+		allocatorOptions = append(allocatorOptions, chromedp.ExecPath(c.opts.ExecPath))
+	}
+
+	// The browser lives as long as the compiler, its context
+	// is not bound to the one of a compilation
+	allocatorCtx, allocatorCancel := chromedp.NewExecAllocator(context.Background(), allocatorOptions...)
+	ctx, cancel := chromedp.NewContext(allocatorCtx)
+
+	initialize, err := json.Marshal(map[string]any{
+		"startOnLoad": false,
+		"theme":       c.opts.Theme,
+	})
+	if err != nil {
+		cancel()
+		allocatorCancel()
+		return errors.WithStack(err)
+	}
+
+	var ignored *cdruntime.RemoteObject
+
+	err = chromedp.Run(ctx,
+		chromedp.Evaluate(c.opts.Script, &ignored),
+		chromedp.Evaluate(mermaidRenderFunc, &ignored),
+		chromedp.Evaluate(fmt.Sprintf("mermaid.initialize(%s)", initialize), &ignored),
+	)
+	if err != nil {
+		cancel()
+		allocatorCancel()
+		return errors.Wrap(err, "could not execute chrome")
+	}
+
+	c.ctx = ctx
+	c.cancels = []context.CancelFunc{cancel, allocatorCancel}
+
+	return nil
+}
+
+// Close stops the browser, if started
+func (c *MermaidCompiler) Close() error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if c.ctx == nil {
+		return nil
+	}
+
+	err := chromedp.Cancel(c.ctx)
+
+	for _, cancel := range c.cancels {
+		cancel()
+	}
+
+	c.ctx = nil
+	c.cancels = nil
+
+	if err != nil && !errors.Is(err, context.Canceled) {
+		return errors.WithStack(err)
+	}
+
+	return nil
+}
+
+func NewMermaidCompiler(funcs ...MermaidOptionFunc) *MermaidCompiler {
+	return &MermaidCompiler{
+		opts:  NewMermaidOptions(funcs...),
+		cache: make(map[string]string),
+	}
+}
+
+var _ mermaid.Compiler = &MermaidCompiler{}
+
+type MermaidOptions struct {
+	// Script is the source of the Mermaid library
+	Script string
+	// Theme is the Mermaid theme, i.e. "default", "neutral", "dark" or "forest"
+	Theme     string
+	ExecPath  string
+	NoSandbox bool
+	// Timeout is the maximum duration of the rendering of a diagram
+	Timeout time.Duration
+}
+
+type MermaidOptionFunc func(opts *MermaidOptions)
+
+const DefaultMermaidTimeout = 30 * time.Second
+
+func NewMermaidOptions(funcs ...MermaidOptionFunc) *MermaidOptions {
+	opts := &MermaidOptions{
+		Theme:   "default",
+		Timeout: DefaultMermaidTimeout,
+	}
+	for _, fn := range funcs {
+		fn(opts)
+	}
+	return opts
+}
+
+func WithMermaidScript(script string) MermaidOptionFunc {
+	return func(opts *MermaidOptions) {
+		opts.Script = script
+	}
+}
+
+func WithMermaidTheme(theme string) MermaidOptionFunc {
+	return func(opts *MermaidOptions) {
+		opts.Theme = theme
+	}
+}
+
+func WithExecPath(execPath string) MermaidOptionFunc {
+	return func(opts *MermaidOptions) {
+		opts.ExecPath = execPath
+	}
+}
+
+func WithNoSandbox(noSandbox bool) MermaidOptionFunc {
+	return func(opts *MermaidOptions) {
+		opts.NoSandbox = noSandbox
+	}
+}
+
+func WithTimeout(timeout time.Duration) MermaidOptionFunc {
+	return func(opts *MermaidOptions) {
+		opts.Timeout = timeout
+	}
+}
@@ -0,0 +1,59 @@
+package diagram
+
+import (
+	"context"
+	"os/exec"
+	"strings"
+	"testing"
+
+	"github.com/Bornholm/amatl/pkg/html/layout/resolver/amatl"
+	"github.com/pkg/errors"
+	"go.abhg.dev/goldmark/mermaid"
+)
+
+func TestMermaidCompiler(t *testing.T) {
+	execPath := findChrome()
+	if execPath == "" {
+		t.Skip("chrome is not available")
+	}
+
+	script, err := amatl.Asset("mermaid.min.js")
+	if err != nil {
+		t.Fatalf("%+v", errors.WithStack(err))
+	}
+
+	compiler := NewMermaidCompiler(
+		WithMermaidScript(string(script)),
+		WithExecPath(execPath),
+		WithNoSandbox(true),
+	)
+
+	defer func() {
+		if err := compiler.Close(); err != nil {
+			t.Errorf("%+v", errors.WithStack(err))
+		}
+	}()
+
+	res, err := compiler.Compile(context.Background(), &mermaid.CompileRequest{Source: "graph TD;\n  A-->B;"})
+	if err != nil {
+		t.Fatalf("%+v", errors.WithStack(err))
+	}
+
+	if !strings.HasPrefix(res.SVG, "<svg") {
+		t.Errorf("expected svg, got '%s'", res.SVG)
+	}
+
+	if _, err := compiler.Compile(context.Background(), &mermaid.CompileRequest{Source: "not a diagram"}); err == nil {
+		t.Errorf("expected error on invalid diagram")
+	}
+}
+
+func findChrome() string {
+	for _, name := range []string{"google-chrome", "chromium", "chromium-browser", "headless-shell"} {
+		if path, err := exec.LookPath(name); err == nil {
+			return path
+		}
+	}
+
+	return ""
+}
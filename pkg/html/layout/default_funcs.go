@@ -8,6 +8,7 @@ import (
 	"net/http"
 	"strings"
 
+	"github.com/Bornholm/amatl/pkg/html/layout/resolver/amatl"
 	"github.com/Bornholm/amatl/pkg/resolver"
 	"github.com/Masterminds/sprig/v3"
 	"github.com/andybalholm/cascadia"
@@ -24,7 +25,7 @@ func DefaultFuncs(resolver resolver.Resolver) template.FuncMap {
 	funcs["htmlRemove"] = htmlRemove
 	funcs["htmlAddAttr"] = htmlAddAttr
 	funcs["htmlTextContent"] = htmlTextContent
-	funcs["resolve"] = getResolveFunc(resolver)
+	funcs["resolve"] = getResolveFunc(resolver, nil)
 	return funcs
 }
 
@@ -263,10 +264,19 @@ func htmlSplit(rawHTML template.HTML, query string) ([]template.HTML, error) {
 	return elements, nil
 }
 
-func getResolveFunc(res resolver.Resolver) func(ctx context.Context, rawURL string, mimeTypes ...string) (template.URL, error) {
+// getResolveFunc returns a template function embedding the resolved resources
+// as data urls, the bundled assets with a remote url in the given map
+// being linked instead
+func getResolveFunc(res resolver.Resolver, assetURLs map[string]string) func(ctx context.Context, rawURL string, mimeTypes ...string) (template.URL, error) {
 	return func(ctx context.Context, rawURL string, mimeTypes ...string) (template.URL, error) {
 		path := resolver.Path(rawURL)
 
+		if path.Scheme() == amatl.Scheme && path.Host() == amatl.AssetsHost {
+			if assetURL, exists := assetURLs[strings.TrimPrefix(path.URLPath(), "/")]; exists {
+				return template.URL(assetURL), nil
+			}
+		}
+
 		reader, err := res.Resolve(ctx, path)
 		if err != nil {
 			return "", errors.WithStack(err)
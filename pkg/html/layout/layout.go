@@ -49,7 +49,17 @@ func Render(ctx context.Context, w io.Writer, body []byte, funcs ...OptionFunc)
 	}
 	ctx = resolver.WithWorkDir(ctx, workDir)
 
-	layout, err := template.New("").Funcs(opts.Funcs).Parse(string(rawTmpl))
+	tmplFuncs := opts.Funcs
+	if len(opts.AssetURLs) > 0 {
+		tmplFuncs = make(template.FuncMap, len(opts.Funcs))
+		for name, fn := range opts.Funcs {
+			tmplFuncs[name] = fn
+		}
+
+		tmplFuncs["resolve"] = getResolveFunc(opts.Resolver, opts.AssetURLs)
+	}
+
+	layout, err := template.New("").Funcs(tmplFuncs).Parse(string(rawTmpl))
 	if err != nil {
 		return errors.WithStack(err)
 	}
@@ -74,6 +84,9 @@ type LayoutOptions struct {
 	Meta     map[string]any
 	Resolver resolver.Resolver
 	Funcs    template.FuncMap
+	// AssetURLs are the remote urls used instead of the
+	// bundled assets with the same name, i.e. "bulma.min.css"
+	AssetURLs map[string]string
 }
 
 type OptionFunc func(opts *LayoutOptions)
@@ -134,3 +147,9 @@ func WithResolver(resolver resolver.Resolver) OptionFunc {
 		opts.Resolver = resolver
 	}
 }
+
+func WithAssetURLs(assetURLs map[string]string) OptionFunc {
+	return func(opts *LayoutOptions) {
+		opts.AssetURLs = assetURLs
+	}
+}
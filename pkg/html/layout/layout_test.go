@@ -0,0 +1,37 @@
+package layout
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/pkg/errors"
+)
+
+func TestRenderAssetURLs(t *testing.T) {
+	const assetURL = "https://cdn.example.test/github-markdown-light.min.css"
+
+	var doc bytes.Buffer
+
+	err := Render(
+		context.Background(), &doc, []byte("<p>Hello</p>"),
+		WithURL("amatl://document.html"),
+		WithAssetURLs(map[string]string{
+			"github-markdown-light.min.css": assetURL,
+		}),
+	)
+	if err != nil {
+		t.Fatalf("%+v", errors.WithStack(err))
+	}
+
+	html := doc.String()
+
+	if !strings.Contains(html, assetURL) {
+		t.Errorf("expected document to link the remote asset '%s'", assetURL)
+	}
+
+	if strings.Contains(html, "data:text/css") {
+		t.Errorf("expected bundled stylesheet not to be embedded in the document")
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"io"
 	"io/fs"
 	"path/filepath"
+	"strings"
 
 	"github.com/Bornholm/amatl/pkg/resolver"
 	"github.com/pkg/errors"
@@ -14,9 +15,16 @@ import (
 
 const Scheme = "amatl"
 
+// AssetsHost is the host of the urls of the bundled assets,
+// i.e. amatl://assets/github-markdown-light.min.css
+const AssetsHost = "assets"
+
 var (
 	//go:embed templates/*.html
 	templateFs embed.FS
+
+	//go:embed assets/*.min.css assets/*.min.js
+	assetFs embed.FS
 )
 
 const templatePattern = "templates/*.html"
@@ -36,6 +44,16 @@ func Available() []string {
 	return available
 }
 
+// Asset returns the content of the bundled asset with the given name
+func Asset(name string) ([]byte, error) {
+	data, err := fs.ReadFile(assetFs, "assets/"+name)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	return data, nil
+}
+
 type Resolver struct {
 }
 
@@ -43,6 +61,15 @@ type Resolver struct {
 func (*Resolver) Resolve(ctx context.Context, path resolver.Path) (io.ReadCloser, error) {
 	filename := path.Host()
 
+	if filename == AssetsHost {
+		file, err := assetFs.Open("assets/" + strings.TrimPrefix(path.URLPath(), "/"))
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+
+		return file, nil
+	}
+
 	file, err := templateFs.Open("templates/" + filename)
 	if err != nil {
 		return nil, errors.WithStack(err)
@@ -0,0 +1,67 @@
+package amatl
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"testing"
+
+	"github.com/Bornholm/amatl/pkg/resolver"
+	"github.com/pkg/errors"
+)
+
+func TestResolverAssets(t *testing.T) {
+	res := NewResolver()
+
+	for _, path := range []resolver.Path{
+		"amatl://assets/github-markdown-light.min.css",
+		"amatl://assets/mermaid.min.js",
+		"amatl://document.html",
+	} {
+		reader, err := res.Resolve(context.Background(), path)
+		if err != nil {
+			t.Fatalf("%s: %+v", path, errors.WithStack(err))
+		}
+
+		data, err := io.ReadAll(reader)
+		if err != nil {
+			t.Fatalf("%s: %+v", path, errors.WithStack(err))
+		}
+
+		if err := reader.Close(); err != nil {
+			t.Fatalf("%s: %+v", path, errors.WithStack(err))
+		}
+
+		if len(data) == 0 {
+			t.Errorf("%s: expected content", path)
+		}
+	}
+
+	if _, err := res.Resolve(context.Background(), "amatl://assets/missing.css"); err == nil {
+		t.Errorf("expected error for missing asset")
+	}
+}
+
+func TestLayoutsOffline(t *testing.T) {
+	res := NewResolver()
+
+	for _, layout := range Available() {
+		reader, err := res.Resolve(context.Background(), resolver.Path(layout))
+		if err != nil {
+			t.Fatalf("%s: %+v", layout, errors.WithStack(err))
+		}
+
+		data, err := io.ReadAll(reader)
+		if err != nil {
+			t.Fatalf("%s: %+v", layout, errors.WithStack(err))
+		}
+
+		if err := reader.Close(); err != nil {
+			t.Fatalf("%s: %+v", layout, errors.WithStack(err))
+		}
+
+		if bytes.Contains(data, []byte("http://")) || bytes.Contains(data, []byte("https://")) {
+			t.Errorf("%s: expected only bundled assets", layout)
+		}
+	}
+}
@@ -0,0 +1,55 @@
+package alert
+
+import (
+	"strings"
+
+	"github.com/yuin/goldmark/ast"
+)
+
+const attrAlert = "alert"
+
+// Alert is a blockquote starting with a [!TYPE] marker,
+// optionally followed by a title
+type Alert struct {
+	// Type is the type of the alert, as written in its marker
+	Type string
+	// Title is the custom title of the alert, if any
+	Title string
+	// Separated reports whether the marker is followed by a blank line
+	Separated bool
+}
+
+// Class returns the CSS class name of the alert type
+func (a *Alert) Class() string {
+	return "markdown-alert-" + strings.ToLower(a.Type)
+}
+
+// Label returns the title of the alert, defaulting to its type
+func (a *Alert) Label() string {
+	if a.Title != "" {
+		return a.Title
+	}
+
+	label := strings.ToLower(a.Type)
+
+	return strings.ToUpper(label[:1]) + label[1:]
+}
+
+func setAlert(n ast.Node, alert *Alert) {
+	n.SetAttributeString(attrAlert, alert)
+}
+
+// Get returns the alert associated with the given blockquote, if any
+func Get(n ast.Node) (*Alert, bool) {
+	raw, exists := n.AttributeString(attrAlert)
+	if !exists {
+		return nil, false
+	}
+
+	alert, ok := raw.(*Alert)
+	if !ok {
+		return nil, false
+	}
+
+	return alert, true
+}
@@ -0,0 +1,179 @@
+package alert
+
+import (
+	"html"
+	"regexp"
+
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/renderer"
+	goldmarkHTML "github.com/yuin/goldmark/renderer/html"
+	"github.com/yuin/goldmark/text"
+	"github.com/yuin/goldmark/util"
+)
+
+// Extender adds the recognition of the GitHub alerts (> [!NOTE])
+// and of the admonitions of any type (> [!TYPE] Title)
+// and their rendering as callouts
+type Extender struct {
+}
+
+// Extend implements goldmark.Extender.
+func (e *Extender) Extend(m goldmark.Markdown) {
+	m.Parser().AddOptions(
+		parser.WithASTTransformers(
+			util.Prioritized(&Transformer{}, 100),
+		),
+	)
+
+	m.Renderer().AddOptions(
+		renderer.WithNodeRenderers(
+			util.Prioritized(&HTMLRenderer{}, 100),
+		),
+	)
+}
+
+var _ goldmark.Extender = &Extender{}
+
+var markerRegExp = regexp.MustCompile(`^\[!([A-Za-z][A-Za-z0-9_-]*)\](?:[ \t]+(.*?))?[ \t]*\r?\n?$`)
+
+// Transformer associates the blockquotes starting with
+// a [!TYPE] marker with an alert and removes their marker
+type Transformer struct {
+}
+
+// Transform implements parser.ASTTransformer.
+func (t *Transformer) Transform(doc *ast.Document, reader text.Reader, pc parser.Context) {
+	source := reader.Source()
+
+	_ = ast.Walk(doc, func(node ast.Node, entering bool) (ast.WalkStatus, error) {
+		if !entering {
+			return ast.WalkContinue, nil
+		}
+
+		blockquote, ok := node.(*ast.Blockquote)
+		if !ok {
+			return ast.WalkContinue, nil
+		}
+
+		paragraph, ok := blockquote.FirstChild().(*ast.Paragraph)
+		if !ok || paragraph.Lines().Len() == 0 {
+			return ast.WalkContinue, nil
+		}
+
+		line := paragraph.Lines().At(0)
+
+		matches := markerRegExp.FindSubmatch(line.Value(source))
+		if matches == nil {
+			return ast.WalkContinue, nil
+		}
+
+		removeFirstLine(paragraph, line)
+
+		separated := !paragraph.HasChildren()
+		if separated {
+			blockquote.RemoveChild(blockquote, paragraph)
+		}
+
+		setAlert(blockquote, &Alert{
+			Type:      string(matches[1]),
+			Title:     string(matches[2]),
+			Separated: separated,
+		})
+
+		return ast.WalkContinue, nil
+	})
+}
+
+var _ parser.ASTTransformer = &Transformer{}
+
+// removeFirstLine removes the inline nodes and the
+// segment of the first line of the given paragraph
+func removeFirstLine(paragraph *ast.Paragraph, line text.Segment) {
+	for child := paragraph.FirstChild(); child != nil; {
+		start, found := inlineStart(child)
+		if !found || start >= line.Stop {
+			break
+		}
+
+		next := child.NextSibling()
+		paragraph.RemoveChild(paragraph, child)
+		child = next
+	}
+
+	lines := text.NewSegments()
+	for i := 1; i < paragraph.Lines().Len(); i++ {
+		lines.Append(paragraph.Lines().At(i))
+	}
+
+	paragraph.SetLines(lines)
+}
+
+// inlineStart returns the position in the source
+// of the first character of the given inline node
+func inlineStart(node ast.Node) (int, bool) {
+	switch n := node.(type) {
+	case *ast.Text:
+		return n.Segment.Start, true
+	case *ast.RawHTML:
+		if n.Segments.Len() > 0 {
+			return n.Segments.At(0).Start, true
+		}
+	}
+
+	for child := node.FirstChild(); child != nil; child = child.NextSibling() {
+		if start, found := inlineStart(child); found {
+			return start, true
+		}
+	}
+
+	return 0, false
+}
+
+// HTMLRenderer renders the alerts as GitHub styled callouts
+// and the other blockquotes as usual
+type HTMLRenderer struct {
+}
+
+// RegisterFuncs implements renderer.NodeRenderer.
+func (r *HTMLRenderer) RegisterFuncs(reg renderer.NodeRendererFuncRegisterer) {
+	reg.Register(ast.KindBlockquote, r.renderBlockquote)
+}
+
+func (r *HTMLRenderer) renderBlockquote(w util.BufWriter, source []byte, node ast.Node, entering bool) (ast.WalkStatus, error) {
+	alert, ok := Get(node)
+	if !ok {
+		if entering {
+			_, _ = w.WriteString("<blockquote")
+			goldmarkHTML.RenderAttributes(w, node, goldmarkHTML.BlockquoteAttributeFilter)
+			_, _ = w.WriteString(">\n")
+		} else {
+			_, _ = w.WriteString("</blockquote>\n")
+		}
+
+		return ast.WalkContinue, nil
+	}
+
+	if !entering {
+		_, _ = w.WriteString("</div>\n")
+		return ast.WalkContinue, nil
+	}
+
+	class := "markdown-alert " + alert.Class()
+	if extra, exists := node.AttributeString("class"); exists {
+		switch extra := extra.(type) {
+		case []byte:
+			class += " " + string(extra)
+		case string:
+			class += " " + extra
+		}
+	}
+
+	_, _ = w.WriteString(`<div class="` + html.EscapeString(class) + `">` + "\n")
+	_, _ = w.WriteString(`<p class="markdown-alert-title">` + html.EscapeString(alert.Label()) + "</p>\n")
+
+	return ast.WalkContinue, nil
+}
+
+var _ renderer.NodeRenderer = &HTMLRenderer{}
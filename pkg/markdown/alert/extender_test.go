@@ -0,0 +1,56 @@
+package alert
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/yuin/goldmark"
+)
+
+func TestExtender(t *testing.T) {
+	markdown := goldmark.New(
+		goldmark.WithExtensions(&Extender{}),
+	)
+
+	type testCase struct {
+		Source   string
+		Expected string
+	}
+
+	testCases := []testCase{
+		{
+			Source:   "> [!NOTE]\n> Useful *information*.\n",
+			Expected: "<div class=\"markdown-alert markdown-alert-note\">\n<p class=\"markdown-alert-title\">Note</p>\n<p>Useful <em>information</em>.</p>\n</div>\n",
+		},
+		{
+			Source:   "> [!warning]\n>\n> Urgent.\n",
+			Expected: "<div class=\"markdown-alert markdown-alert-warning\">\n<p class=\"markdown-alert-title\">Warning</p>\n<p>Urgent.</p>\n</div>\n",
+		},
+		{
+			Source:   "> [!Example] A <custom> title\n> Content.\n",
+			Expected: "<div class=\"markdown-alert markdown-alert-example\">\n<p class=\"markdown-alert-title\">A &lt;custom&gt; title</p>\n<p>Content.</p>\n</div>\n",
+		},
+		{
+			Source:   "> [!NOTE] Read the *docs*\n> [link](#)\n",
+			Expected: "<div class=\"markdown-alert markdown-alert-note\">\n<p class=\"markdown-alert-title\">Read the *docs*</p>\n<p><a href=\"#\">link</a></p>\n</div>\n",
+		},
+		{
+			Source:   "> A [!NOTE] blockquote.\n",
+			Expected: "<blockquote>\n<p>A [!NOTE] blockquote.</p>\n</blockquote>\n",
+		},
+	}
+
+	for _, tc := range testCases {
+		var html bytes.Buffer
+
+		if err := markdown.Convert([]byte(tc.Source), &html); err != nil {
+			t.Fatalf("%+v", errors.WithStack(err))
+		}
+
+		if e, g := tc.Expected, html.String(); e != g {
+			t.Errorf("%s: expected '%s', got '%s'", strings.TrimSpace(tc.Source), e, g)
+		}
+	}
+}
@@ -36,7 +36,7 @@ func (t *Transformer) Transform(node *ast.Document, reader text.Reader, pc parse
 
 			dataURL, err := t.toDataURL(ctx, destination)
 			if err != nil {
-				return ast.WalkStop, errors.WithStack(err)
+				return ast.WalkStop, errors.Wrapf(err, "could not embed image '%s'", destination)
 			}
 
 			typ.Destination = []byte(dataURL.String())
@@ -0,0 +1,34 @@
+package abbr
+
+import (
+	"fmt"
+	"html"
+
+	"github.com/Bornholm/amatl/pkg/markdown/directive"
+	"github.com/yuin/goldmark/util"
+)
+
+const attrNameTitle = "title"
+
+// NodeRenderer renders the :abbr[abbreviation]{title="<expansion>"}
+// directives as <abbr> elements
+type NodeRenderer struct {
+}
+
+// Render implements directive.NodeRenderer.
+func (r *NodeRenderer) Render(writer util.BufWriter, source []byte, node *directive.Node) {
+	title, exists := node.AttributeString(attrNameTitle)
+	if !exists {
+		_, _ = writer.WriteString("<abbr>")
+		return
+	}
+
+	_, _ = fmt.Fprintf(writer, `<abbr title="%s">`, html.EscapeString(fmt.Sprintf("%v", title)))
+}
+
+// RenderExit implements directive.ContentNodeRenderer.
+func (r *NodeRenderer) RenderExit(writer util.BufWriter, source []byte, node *directive.Node) {
+	_, _ = writer.WriteString("</abbr>")
+}
+
+var _ directive.ContentNodeRenderer = &NodeRenderer{}
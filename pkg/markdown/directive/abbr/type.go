@@ -0,0 +1,5 @@
+package abbr
+
+import "github.com/Bornholm/amatl/pkg/markdown/directive"
+
+const Type directive.Type = "abbr"
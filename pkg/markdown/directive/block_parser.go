@@ -0,0 +1,186 @@
+package directive
+
+import (
+	"bytes"
+	"regexp"
+
+	"github.com/pkg/errors"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/text"
+	"github.com/yuin/goldmark/util"
+)
+
+const minContainerFence = 3
+
+var (
+	directiveNameRegExp       = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9_-]*$`)
+	directiveNamePrefixRegExp = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9_-]*`)
+)
+
+// BlockParser parses the container directives, i.e.
+//
+//	:::name{key="value"}
+//	Markdown content
+//	:::
+//
+// Container directives can be nested, the closing fence
+// closing the innermost opened container directive.
+type BlockParser struct {
+}
+
+// Trigger implements parser.BlockParser.
+func (p *BlockParser) Trigger() []byte {
+	return []byte{':'}
+}
+
+// Open implements parser.BlockParser.
+func (p *BlockParser) Open(parent ast.Node, reader text.Reader, pc parser.Context) (ast.Node, parser.State) {
+	line, segment := reader.PeekLine()
+
+	pos := pc.BlockOffset()
+	if pos < 0 {
+		return nil, parser.NoChildren
+	}
+
+	fence := countFence(line[pos:])
+	if fence < minContainerFence {
+		return nil, parser.NoChildren
+	}
+
+	start := pos + fence
+	raw := util.TrimRightSpace(line[start:])
+
+	value := ast.NewTextSegment(text.NewSegment(segment.Start+start, segment.Start+start+len(raw)))
+
+	directive, err := parseContainerDirective(raw, value)
+	if err != nil {
+		reportAt(pc, reader.Source(), segment.Start+pos, "%s", err)
+		return nil, parser.NoChildren
+	}
+
+	if directive == nil {
+		return nil, parser.NoChildren
+	}
+
+	directive.fence = fence
+	directive.start = segment.Start + pos
+
+	reader.Advance(segment.Len() - 1)
+
+	return directive, parser.HasChildren
+}
+
+// Continue implements parser.BlockParser.
+func (p *BlockParser) Continue(node ast.Node, reader text.Reader, pc parser.Context) parser.State {
+	line, segment := reader.PeekLine()
+
+	directive := node.(*Node)
+
+	w, pos := util.IndentWidth(line, reader.LineOffset())
+	if w > 3 {
+		return parser.Continue | parser.HasChildren
+	}
+
+	fence := countFence(line[pos:])
+	if fence < directive.fence || !util.IsBlank(line[pos+fence:]) || isClosingDelegated(directive, fence, pc) {
+		return parser.Continue | parser.HasChildren
+	}
+
+	newline := 0
+	if line[len(line)-1] == '\n' {
+		newline = 1
+	}
+
+	reader.Advance(segment.Stop - segment.Start - newline + segment.Padding)
+
+	return parser.Close
+}
+
+// Close implements parser.BlockParser.
+func (p *BlockParser) Close(node ast.Node, reader text.Reader, pc parser.Context) {
+}
+
+// CanInterruptParagraph implements parser.BlockParser.
+func (p *BlockParser) CanInterruptParagraph() bool {
+	return true
+}
+
+// CanAcceptIndentedLine implements parser.BlockParser.
+func (p *BlockParser) CanAcceptIndentedLine() bool {
+	return false
+}
+
+var _ parser.BlockParser = &BlockParser{}
+
+// isClosingDelegated returns true if the closing fence of the given size
+// belongs to a block opened inside the given container directive, i.e.
+// a nested container directive or a raw block such as a fenced code block
+func isClosingDelegated(directive *Node, fence int, pc parser.Context) bool {
+	opened := pc.OpenedBlocks()
+
+	inside := false
+	for _, block := range opened {
+		if block.Node == directive {
+			inside = true
+			continue
+		}
+
+		if !inside {
+			continue
+		}
+
+		if block.Node.IsRaw() {
+			return true
+		}
+
+		if nested, ok := block.Node.(*Node); ok && nested.IsContainer() && nested.fence <= fence {
+			return true
+		}
+	}
+
+	return false
+}
+
+func countFence(line []byte) int {
+	count := 0
+	for count < len(line) && line[count] == ':' {
+		count++
+	}
+
+	return count
+}
+
+// parseContainerDirective parses the name and the optional attributes
+// following the opening fence, returning nil if the line is not an
+// opening fence and an error if the attributes are malformed
+func parseContainerDirective(raw []byte, value *ast.Text) (*Node, error) {
+	braces := bytes.IndexByte(raw, '{')
+
+	if braces < 0 {
+		if !directiveNameRegExp.Match(raw) {
+			return nil, nil
+		}
+
+		return &Node{
+			directiveType: Type(raw),
+			value:         value,
+		}, nil
+	}
+
+	name := raw[:braces]
+	if !directiveNameRegExp.Match(name) {
+		return nil, nil
+	}
+
+	if raw[len(raw)-1] != '}' {
+		return nil, errors.Errorf("malformed attributes of directive '%s'", name)
+	}
+
+	directive := parseDirective(append([]byte{':'}, raw...), value)
+	if directive == nil {
+		return nil, errors.Errorf("malformed attributes of directive '%s'", name)
+	}
+
+	return directive, nil
+}
@@ -0,0 +1,128 @@
+package directive
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/Bornholm/amatl/pkg/markdown/renderer/markdown"
+	"github.com/Bornholm/amatl/pkg/markdown/renderer/markdown/node"
+	"github.com/pkg/errors"
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/renderer"
+	"github.com/yuin/goldmark/text"
+	"github.com/yuin/goldmark/util"
+)
+
+func TestBlockParser(t *testing.T) {
+	type testCase struct {
+		Name     string
+		Source   string
+		Expected string
+	}
+
+	testCases := []testCase{
+		{
+			Name:     "container",
+			Source:   ":::note{title=\"Hello\" class=\"wide\"}\nSome **bold** text.\n:::\n",
+			Expected: "<div title=\"Hello\" class=\"amatl-directive amatl-directive-note wide\">\n<p>Some <strong>bold</strong> text.</p>\n</div>\n",
+		},
+		{
+			Name:     "without attributes",
+			Source:   ":::details\nContent\n:::\n\nAfter\n",
+			Expected: "<div class=\"amatl-directive amatl-directive-details\">\n<p>Content</p>\n</div>\n<p>After</p>\n",
+		},
+		{
+			Name:     "custom attributes",
+			Source:   ":::tabs{selected=\"2\"}\n:::\n",
+			Expected: "<div data-selected=\"2\" class=\"amatl-directive amatl-directive-tabs\">\n</div>\n",
+		},
+		{
+			Name:     "nested",
+			Source:   ":::columns\n:::column\nA\n:::\n:::column\nB\n:::\n:::\n",
+			Expected: "<div class=\"amatl-directive amatl-directive-columns\">\n<div class=\"amatl-directive amatl-directive-column\">\n<p>A</p>\n</div>\n<div class=\"amatl-directive amatl-directive-column\">\n<p>B</p>\n</div>\n</div>\n",
+		},
+		{
+			Name:     "fenced code block",
+			Source:   ":::note\n```\n:::\n```\n:::\n",
+			Expected: "<div class=\"amatl-directive amatl-directive-note\">\n<pre><code>:::\n</code></pre>\n</div>\n",
+		},
+		{
+			Name:     "not a directive",
+			Source:   "::: note\n\n::note\n",
+			Expected: "<p>::: note</p>\n<p>::note</p>\n",
+		},
+	}
+
+	markdown := goldmark.New(
+		goldmark.WithParserOptions(
+			parser.WithBlockParsers(
+				util.Prioritized(&BlockParser{}, 100),
+			),
+		),
+		goldmark.WithRendererOptions(
+			renderer.WithNodeRenderers(
+				util.Prioritized(NewRenderer(), 0),
+			),
+		),
+	)
+
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			var html bytes.Buffer
+
+			if err := markdown.Convert([]byte(tc.Source), &html); err != nil {
+				t.Fatalf("%+v", errors.WithStack(err))
+			}
+
+			if e, g := tc.Expected, html.String(); e != g {
+				t.Errorf("expected '%s', got '%s'", e, g)
+			}
+		})
+	}
+}
+
+func TestBlockParserMarkdownRoundTrip(t *testing.T) {
+	source := strings.Join([]string{
+		"# Containers",
+		"",
+		":::note{title=\"Hello\"}",
+		"Some **bold** text.",
+		"",
+		"- A",
+		"- B",
+		":::",
+		"",
+		"::::columns",
+		":::column",
+		"A",
+		":::",
+		"::::",
+	}, "\n")
+
+	parse := goldmark.New().Parser()
+	parse.AddOptions(
+		parser.WithBlockParsers(
+			util.Prioritized(&BlockParser{}, 100),
+		),
+	)
+
+	document := parse.Parse(text.NewReader([]byte(source)))
+
+	render := markdown.NewRenderer()
+	render.AddOptions(
+		markdown.WithNodeRenderers(node.Renderers()),
+		markdown.WithNodeRenderer(KindDirective, NewMarkdownNodeRenderer()),
+	)
+
+	var buff bytes.Buffer
+
+	if err := render.Render(&buff, []byte(source), document); err != nil {
+		t.Fatalf("%+v", errors.WithStack(err))
+	}
+
+	if e, g := source, strings.TrimSpace(buff.String()); e != g {
+		t.Errorf("expected '%s', got '%s'", e, g)
+	}
+}
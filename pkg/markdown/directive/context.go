@@ -0,0 +1,42 @@
+package directive
+
+import (
+	"github.com/yuin/goldmark/parser"
+)
+
+var (
+	contextKeyParser      = parser.NewContextKey()
+	contextKeyRootContext = parser.NewContextKey()
+)
+
+// WithParser associates the parser of the document with the parser
+// context, to parse the Markdown produced by the directives
+func WithParser(pc parser.Context, p parser.Parser) parser.Context {
+	pc.Set(contextKeyParser, p)
+	return pc
+}
+
+// ParserFromContext returns the parser associated
+// with the parser context, if any
+func ParserFromContext(pc parser.Context) (parser.Parser, bool) {
+	p, ok := pc.Get(contextKeyParser).(parser.Parser)
+	return p, ok && p != nil
+}
+
+// WithRootContext associates the parser context of the root document
+// with the parser context of a document it includes
+func WithRootContext(pc parser.Context, root parser.Context) parser.Context {
+	pc.Set(contextKeyRootContext, root)
+	return pc
+}
+
+// RootContext returns the parser context of the root document,
+// the given parser context if it is the one of the root document
+func RootContext(pc parser.Context) parser.Context {
+	root, ok := pc.Get(contextKeyRootContext).(parser.Context)
+	if !ok || root == nil {
+		return pc
+	}
+
+	return root
+}
@@ -0,0 +1,144 @@
+package directive
+
+import (
+	"bytes"
+	"cmp"
+	"fmt"
+	"slices"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/yuin/goldmark/parser"
+)
+
+// Diagnostic is a problem found with a directive of a document,
+// such as an unknown type or a malformed attribute
+type Diagnostic struct {
+	// Path is the path of the document containing the directive
+	Path string
+	// Line is the line of the directive, starting at 1
+	Line int
+	// Column is the column of the directive, starting at 1
+	Column int
+	// Message describes the problem
+	Message string
+}
+
+// String returns the diagnostic as "path:line:column: message"
+func (d Diagnostic) String() string {
+	path := d.Path
+	if path == "" {
+		path = "<unknown>"
+	}
+
+	return fmt.Sprintf("%s:%d:%d: %s", path, d.Line, d.Column, d.Message)
+}
+
+// Diagnostics collects the diagnostics reported while parsing
+// a document and the documents it includes
+type Diagnostics struct {
+	mutex       sync.Mutex
+	diagnostics []Diagnostic
+}
+
+// Add records the given diagnostics
+func (d *Diagnostics) Add(diagnostics ...Diagnostic) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	d.diagnostics = append(d.diagnostics, diagnostics...)
+}
+
+// All returns a copy of the recorded diagnostics,
+// sorted by path and position
+func (d *Diagnostics) All() []Diagnostic {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	diagnostics := append([]Diagnostic(nil), d.diagnostics...)
+
+	slices.SortStableFunc(diagnostics, func(a, b Diagnostic) int {
+		return cmp.Or(
+			cmp.Compare(a.Path, b.Path),
+			cmp.Compare(a.Line, b.Line),
+			cmp.Compare(a.Column, b.Column),
+		)
+	})
+
+	return diagnostics
+}
+
+func NewDiagnostics() *Diagnostics {
+	return &Diagnostics{
+		diagnostics: make([]Diagnostic, 0),
+	}
+}
+
+var (
+	contextKeyDiagnostics = parser.NewContextKey()
+	contextKeySourcePath  = parser.NewContextKey()
+)
+
+// WithDiagnostics associates the given collector with the parser context,
+// the diagnostics being ignored if none is associated
+func WithDiagnostics(pc parser.Context, diagnostics *Diagnostics) parser.Context {
+	pc.Set(contextKeyDiagnostics, diagnostics)
+	return pc
+}
+
+// DiagnosticsFromContext returns the collector associated
+// with the parser context, if any
+func DiagnosticsFromContext(pc parser.Context) (*Diagnostics, bool) {
+	diagnostics, ok := pc.Get(contextKeyDiagnostics).(*Diagnostics)
+	return diagnostics, ok && diagnostics != nil
+}
+
+// WithSourcePath associates the path of the
+// parsed document with the parser context
+func WithSourcePath(pc parser.Context, path string) parser.Context {
+	pc.Set(contextKeySourcePath, path)
+	return pc
+}
+
+// SourcePathFromContext returns the path of the
+// parsed document associated with the parser context
+func SourcePathFromContext(pc parser.Context) string {
+	path, _ := pc.Get(contextKeySourcePath).(string)
+	return path
+}
+
+// Report records a diagnostic about the given directive in
+// the collector associated with the parser context, if any
+func Report(pc parser.Context, source []byte, node *Node, format string, args ...any) {
+	reportAt(pc, source, node.start, format, args...)
+}
+
+func reportAt(pc parser.Context, source []byte, offset int, format string, args ...any) {
+	diagnostics, ok := DiagnosticsFromContext(pc)
+	if !ok {
+		return
+	}
+
+	line, column := position(source, offset)
+
+	diagnostics.Add(Diagnostic{
+		Path:    SourcePathFromContext(pc),
+		Line:    line,
+		Column:  column,
+		Message: fmt.Sprintf(format, args...),
+	})
+}
+
+// position returns the line and the column, in runes,
+// of the given offset in the source
+func position(source []byte, offset int) (int, int) {
+	offset = min(max(offset, 0), len(source))
+
+	before := source[:offset]
+	line := bytes.Count(before, []byte{'\n'}) + 1
+
+	lineStart := bytes.LastIndexByte(before, '\n') + 1
+	column := utf8.RuneCount(before[lineStart:]) + 1
+
+	return line, column
+}
@@ -0,0 +1,79 @@
+package directive
+
+import (
+	"testing"
+
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/text"
+	"github.com/yuin/goldmark/util"
+)
+
+func TestDiagnostics(t *testing.T) {
+	type testCase struct {
+		Name     string
+		Source   string
+		Expected []string
+	}
+
+	testCases := []testCase{
+		{
+			Name:     "unknown directive",
+			Source:   "# Title\n\nSome :unknown{} and :known{} directives.\n",
+			Expected: []string{"doc.md:3:6: unknown directive 'unknown'"},
+		},
+		{
+			Name:     "malformed inline attributes",
+			Source:   "Ünïcode :known{key=\"value} text.\n",
+			Expected: []string{"doc.md:1:9: malformed attributes of directive 'known'"},
+		},
+		{
+			Name:     "malformed container attributes",
+			Source:   "Text\n\n:::note{class=\"x\ncontent\n:::\n",
+			Expected: []string{"doc.md:3:1: malformed attributes of directive 'note'"},
+		},
+		{
+			Name:     "labelled and container directives",
+			Source:   ":badge[beta]\n\n:::note\ncontent\n:::\n",
+			Expected: []string{},
+		},
+	}
+
+	p := parser.NewParser(
+		parser.WithBlockParsers(append(
+			parser.DefaultBlockParsers(),
+			util.Prioritized(&BlockParser{}, 100),
+		)...),
+		parser.WithInlineParsers(append(
+			parser.DefaultInlineParsers(),
+			util.Prioritized(&InlineParser{}, 0),
+		)...),
+		parser.WithParagraphTransformers(parser.DefaultParagraphTransformers()...),
+		parser.WithASTTransformers(
+			util.Prioritized(NewTransformer(WithKnownTypes("known")), 0),
+		),
+	)
+
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			diagnostics := NewDiagnostics()
+
+			pc := parser.NewContext()
+			pc = WithDiagnostics(pc, diagnostics)
+			pc = WithSourcePath(pc, "doc.md")
+
+			p.Parse(text.NewReader([]byte(tc.Source)), parser.WithContext(pc))
+
+			all := diagnostics.All()
+
+			if e, g := len(tc.Expected), len(all); e != g {
+				t.Fatalf("len(diagnostics): expected '%v', got '%v': %v", e, g, all)
+			}
+
+			for i, d := range all {
+				if e, g := tc.Expected[i], d.String(); e != g {
+					t.Errorf("diagnostics[%d]: expected '%v', got '%v'", i, e, g)
+				}
+			}
+		})
+	}
+}
@@ -1,12 +1,15 @@
 package include
 
 import (
+	"github.com/Bornholm/amatl/pkg/resolver"
+	"github.com/pkg/errors"
 	"github.com/yuin/goldmark/ast"
 )
 
 const (
 	attrIncludedNode   = "includedNode"
 	attrIncludedSource = "includedSource"
+	attrIncludedPath   = "includedPath"
 )
 
 func setIncludedNode(n ast.Node, includedNode ast.Node) {
@@ -44,3 +47,56 @@ func IncludedSource(n ast.Node) ([]byte, bool) {
 
 	return includedSource, true
 }
+
+func getIncluded(n ast.Node) ([]byte, ast.Node, error) {
+	includedSource, exists := IncludedSource(n)
+	if !exists {
+		return nil, nil, errors.New("could not find source associated with include directive")
+	}
+
+	includedNode, exists := IncludedNode(n)
+	if !exists {
+		return nil, nil, errors.New("could not find node associated with include directive")
+	}
+
+	return includedSource, includedNode, nil
+}
+
+// getCachedIncluded returns the source and the node included by the
+// given node, from the given cache for the nodes without included node
+func getCachedIncluded(cache *SourceCache, n ast.Node) ([]byte, ast.Node, error) {
+	if _, exists := IncludedNode(n); exists || cache == nil {
+		return getIncluded(n)
+	}
+
+	rawURL, err := getNodeURLAttribute(n)
+	if err != nil {
+		return nil, nil, errors.WithStack(err)
+	}
+
+	includedSource, includedNode, exists := cache.Get(rawURL)
+	if !exists {
+		return nil, nil, errors.Errorf("could not find source associated with path '%s'", rawURL)
+	}
+
+	return includedSource, includedNode, nil
+}
+
+func setIncludedPath(n ast.Node, includedPath resolver.Path) {
+	n.SetAttributeString(attrIncludedPath, includedPath)
+}
+
+// IncludedPath returns the path of the document included by the given node
+func IncludedPath(n ast.Node) (resolver.Path, bool) {
+	raw, exists := n.AttributeString(attrIncludedPath)
+	if !exists {
+		return "", false
+	}
+
+	includedPath, ok := raw.(resolver.Path)
+	if !ok {
+		return "", false
+	}
+
+	return includedPath, true
+}
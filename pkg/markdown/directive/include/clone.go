@@ -0,0 +1,61 @@
+package include
+
+import (
+	"reflect"
+
+	"github.com/pkg/errors"
+	"github.com/yuin/goldmark/ast"
+)
+
+// cloneNode returns a deep copy of the given tree, the trees
+// included by its directives being copied too, so that the
+// cached trees are never modified by the documents including them
+func cloneNode(node ast.Node) (ast.Node, error) {
+	value := reflect.ValueOf(node)
+	if value.Kind() != reflect.Pointer || value.Elem().Kind() != reflect.Struct {
+		return nil, errors.Errorf("could not clone node of type %T", node)
+	}
+
+	clone := reflect.New(value.Elem().Type())
+	clone.Elem().Set(value.Elem())
+
+	// The copied node must not share the links to the
+	// other nodes nor the attributes of the original one
+	base := clone.Elem().FieldByName("BaseNode")
+	if !base.IsValid() || !base.CanSet() {
+		return nil, errors.Errorf("could not clone node of type %T", node)
+	}
+
+	base.Set(reflect.Zero(base.Type()))
+
+	cloned, ok := clone.Interface().(ast.Node)
+	if !ok {
+		return nil, errors.Errorf("could not clone node of type %T", node)
+	}
+
+	for _, attr := range node.Attributes() {
+		value := attr.Value
+
+		if included, ok := value.(ast.Node); ok {
+			clonedIncluded, err := cloneNode(included)
+			if err != nil {
+				return nil, errors.WithStack(err)
+			}
+
+			value = clonedIncluded
+		}
+
+		cloned.SetAttribute(attr.Name, value)
+	}
+
+	for child := node.FirstChild(); child != nil; child = child.NextSibling() {
+		clonedChild, err := cloneNode(child)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+
+		cloned.AppendChild(cloned, clonedChild)
+	}
+
+	return cloned, nil
+}
@@ -0,0 +1,9 @@
+package include
+
+import "errors"
+
+var (
+	ErrIncludeCycle     = errors.New("include cycle detected")
+	ErrMaxDepthExceeded = errors.New("maximum include depth exceeded")
+	ErrSectionNotFound  = errors.New("section not found")
+)
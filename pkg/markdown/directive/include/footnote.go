@@ -0,0 +1,154 @@
+package include
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/Bornholm/amatl/pkg/markdown/directive"
+	"github.com/Bornholm/amatl/pkg/resolver"
+	"github.com/pkg/errors"
+	"github.com/yuin/goldmark/ast"
+	extAST "github.com/yuin/goldmark/extension/ast"
+)
+
+// footnoteList returns the list of the footnotes
+// of the given document, if any
+func footnoteList(root ast.Node) *extAST.FootnoteList {
+	for child := root.LastChild(); child != nil; child = child.PreviousSibling() {
+		if list, ok := child.(*extAST.FootnoteList); ok {
+			return list
+		}
+	}
+
+	return nil
+}
+
+// attrSourceRef is the label of a prefixed footnote
+// in the document it comes from
+const attrSourceRef = "sourceRef"
+
+// prefixFootnotes prefixes the labels of the footnotes of an included document
+// with an identifier of its resource and, from its second inclusion, with the
+// number of the inclusion. The labels are then unique in the including
+// document, which numbers all the footnotes again when parsed.
+func prefixFootnotes(list *extAST.FootnoteList, rootPath, resourcePath resolver.Path, inclusion int) {
+	if list == nil {
+		return
+	}
+
+	sum := sha256.Sum256([]byte(footnoteResourceID(rootPath, resourcePath)))
+	name := strings.TrimSuffix(filepath.Base(resourcePath.String()), filepath.Ext(resourcePath.String()))
+	prefix := name + "-" + hex.EncodeToString(sum[:])[:6] + "-"
+
+	if inclusion > 0 {
+		prefix += strconv.Itoa(inclusion) + "-"
+	}
+
+	for child := list.FirstChild(); child != nil; child = child.NextSibling() {
+		footnote, ok := child.(*extAST.Footnote)
+		if !ok {
+			continue
+		}
+
+		ref := footnoteSourceRef(footnote)
+		footnote.SetAttributeString(attrSourceRef, ref)
+		footnote.Ref = append([]byte(prefix), ref...)
+	}
+}
+
+// footnoteResourceID returns the identifier of the given resource in the
+// footnote labels, its path relative to the directory of the root document
+// if both are local files, so that the labels do not depend on the location
+// of the documents, and its path as is otherwise
+func footnoteResourceID(rootPath, resourcePath resolver.Path) string {
+	rootFile, rootIsLocal := rootPath.LocalPath()
+	resourceFile, resourceIsLocal := resourcePath.LocalPath()
+	if !rootIsLocal || !resourceIsLocal {
+		return resourcePath.String()
+	}
+
+	absRootFile, err := filepath.Abs(rootFile)
+	if err != nil {
+		return resourcePath.String()
+	}
+
+	absResourceFile, err := filepath.Abs(resourceFile)
+	if err != nil {
+		return resourcePath.String()
+	}
+
+	rel, err := filepath.Rel(filepath.Dir(absRootFile), absResourceFile)
+	if err != nil {
+		return resourcePath.String()
+	}
+
+	return filepath.ToSlash(rel)
+}
+
+// uniqueFootnoteLabels prefixes the labels of the footnotes of the documents
+// included by the given document again, numbering the inclusions of each
+// resource, the included documents being cached and included again. The
+// resources are identified relatively to the given root document.
+func uniqueFootnoteLabels(root ast.Node, rootPath resolver.Path, inclusions map[resolver.Path]int) error {
+	err := ast.Walk(root, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if !entering {
+			return ast.WalkContinue, nil
+		}
+
+		node, ok := n.(*directive.Node)
+		if !ok {
+			return ast.WalkContinue, nil
+		}
+
+		includedNode, exists := IncludedNode(node)
+		if !exists {
+			return ast.WalkContinue, nil
+		}
+
+		// The glob patterns include their matches as directives
+		if includedPath, exists := IncludedPath(node); exists {
+			prefixFootnotes(footnoteList(includedNode), rootPath, includedPath, inclusions[includedPath])
+			inclusions[includedPath]++
+		}
+
+		if err := uniqueFootnoteLabels(includedNode, rootPath, inclusions); err != nil {
+			return ast.WalkStop, errors.WithStack(err)
+		}
+
+		return ast.WalkContinue, nil
+	})
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	return nil
+}
+
+// footnoteSourceRef returns the label of the
+// footnote in the document it comes from
+func footnoteSourceRef(footnote *extAST.Footnote) []byte {
+	if ref, exists := footnote.AttributeString(attrSourceRef); exists {
+		if raw, ok := ref.([]byte); ok {
+			return raw
+		}
+	}
+
+	return footnote.Ref
+}
+
+// keepFootnotes appends the list of the footnotes to the
+// given document if it was removed with the excluded nodes
+func keepFootnotes(root ast.Node, list *extAST.FootnoteList) {
+	if list == nil || list.Parent() == root {
+		return
+	}
+
+	if parent := list.Parent(); parent != nil {
+		parent.RemoveChild(parent, list)
+	}
+
+	root.AppendChild(root, list)
+}
@@ -0,0 +1,303 @@
+package include
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/Bornholm/amatl/pkg/markdown/directive"
+	"github.com/Bornholm/amatl/pkg/pipeline"
+	"github.com/Bornholm/amatl/pkg/resolver"
+	"github.com/pkg/errors"
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/text"
+	"go.abhg.dev/goldmark/frontmatter"
+)
+
+const (
+	attrNameSort    = "sort"
+	attrNameReverse = "reverse"
+	attrNameWhere   = "where"
+)
+
+const (
+	sortByName = "name"
+	// metaKeyPrefix prefixes the front matter keys of the sort and where attributes
+	metaKeyPrefix = "meta."
+)
+
+// globMatch is a resource matching the glob pattern of an :include directive
+type globMatch struct {
+	path resolver.Path
+	meta map[string]any
+}
+
+// includeGlob includes the resources matching the given pattern, each of them
+// as an :include directive in the content of the given node
+func (t *NodeTransformer) includeGlob(node *directive.Node, pc parser.Context, sourcePath, pattern resolver.Path, fragment string, opts includeOptions) error {
+	ctx, err := pipeline.FromParserContext(pc)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	sortKey, err := getNodeSortAttribute(node)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	reverse, err := getNodeReverseAttribute(node)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	conditions, err := getNodeWhereAttribute(node)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	paths, err := resolver.Glob(ctx, pattern.String())
+	if err != nil {
+		return errors.Wrapf(err, "could not list resources matching '%s'", pattern)
+	}
+
+	matches := make([]globMatch, 0, len(paths))
+
+	for _, p := range paths {
+		// The including document is not included again
+		if canonicalPath(p) == canonicalPath(sourcePath) {
+			continue
+		}
+
+		source, err := readResource(ctx, p)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+
+		meta, err := readMeta(source)
+		if err != nil {
+			return errors.Wrapf(err, "could not read front matter of markdown resource '%s'", p)
+		}
+
+		if !matchConditions(meta, conditions) {
+			continue
+		}
+
+		matches = append(matches, globMatch{path: p, meta: meta})
+	}
+
+	sortMatches(matches, sortKey, reverse)
+
+	document := ast.NewDocument()
+
+	for _, m := range matches {
+		child := node.Clone()
+
+		url := m.path.String()
+		if fragment != "" {
+			url += "#" + fragment
+		}
+
+		child.SetAttributeString(attrNameUrl, url)
+		document.AppendChild(document, child)
+
+		if err := t.include(child, pc, sourcePath, m.path, fragment, opts); err != nil {
+			return errors.WithStack(err)
+		}
+	}
+
+	setIncludedSource(node, []byte{})
+	setIncludedNode(node, document)
+	directive.Hoist(node)
+
+	return nil
+}
+
+// isGlobPattern checks that the given path contains glob special characters,
+// the query of the urls being ignored
+func isGlobPattern(path resolver.Path) bool {
+	if path.IsURL() {
+		return strings.ContainsAny(path.URLPath(), "*[")
+	}
+
+	return strings.ContainsAny(path.String(), "*?[")
+}
+
+// readMeta returns the front matter of the given source, if any
+func readMeta(source []byte) (map[string]any, error) {
+	pc := parser.NewContext()
+
+	goldmark.New(
+		goldmark.WithExtensions(&frontmatter.Extender{}),
+	).Parser().Parse(text.NewReader(source), parser.WithContext(pc))
+
+	meta := map[string]any{}
+
+	data := frontmatter.Get(pc)
+	if data == nil {
+		return meta, nil
+	}
+
+	if err := data.Decode(&meta); err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	return meta, nil
+}
+
+// sortMatches sorts the given matches by name or by the value of a front matter
+// key, the matches without this value being last
+func sortMatches(matches []globMatch, sortKey string, reverse bool) {
+	direction := 1
+	if reverse {
+		direction = -1
+	}
+
+	slices.SortStableFunc(matches, func(a, b globMatch) int {
+		byName := direction * cmp.Or(
+			cmp.Compare(a.path.Base().String(), b.path.Base().String()),
+			cmp.Compare(a.path.String(), b.path.String()),
+		)
+
+		if sortKey == sortByName {
+			return byName
+		}
+
+		key := strings.TrimPrefix(sortKey, metaKeyPrefix)
+
+		aValue, aExists := lookupMeta(a.meta, key)
+		bValue, bExists := lookupMeta(b.meta, key)
+
+		switch {
+		case !aExists && !bExists:
+			return byName
+		case !aExists:
+			return 1
+		case !bExists:
+			return -1
+		}
+
+		return cmp.Or(direction*compareValues(aValue, bValue), byName)
+	})
+}
+
+// compareValues compares front matter values, numerically
+// if both are numbers and as strings otherwise
+func compareValues(a, b any) int {
+	aNumber, aErr := strconv.ParseFloat(fmt.Sprintf("%v", a), 64)
+	bNumber, bErr := strconv.ParseFloat(fmt.Sprintf("%v", b), 64)
+
+	if aErr == nil && bErr == nil {
+		return cmp.Compare(aNumber, bNumber)
+	}
+
+	return cmp.Compare(fmt.Sprintf("%v", a), fmt.Sprintf("%v", b))
+}
+
+// lookupMeta returns the value of the given front matter key,
+// the nested keys being separated by dots
+func lookupMeta(meta map[string]any, key string) (any, bool) {
+	var value any = meta
+
+	for _, k := range strings.Split(key, ".") {
+		m, ok := value.(map[string]any)
+		if !ok {
+			return nil, false
+		}
+
+		value, ok = m[k]
+		if !ok {
+			return nil, false
+		}
+	}
+
+	return value, true
+}
+
+// condition filters the matches by the value of a front matter key
+type condition struct {
+	key   string
+	value string
+}
+
+func matchConditions(meta map[string]any, conditions []condition) bool {
+	for _, c := range conditions {
+		value, exists := lookupMeta(meta, c.key)
+		if !exists || fmt.Sprintf("%v", value) != c.value {
+			return false
+		}
+	}
+
+	return true
+}
+
+func getNodeSortAttribute(node ast.Node) (string, error) {
+	value, exists := node.AttributeString(attrNameSort)
+	if !exists {
+		return sortByName, nil
+	}
+
+	raw, ok := value.(string)
+	if !ok {
+		return "", errors.Errorf("unexpected value type '%T' for '%s' attribute", value, attrNameSort)
+	}
+
+	if raw != sortByName && (!strings.HasPrefix(raw, metaKeyPrefix) || raw == metaKeyPrefix) {
+		return "", errors.Errorf("invalid value '%s' for '%s' attribute, expected '%s' or '%s<key>'", raw, attrNameSort, sortByName, metaKeyPrefix)
+	}
+
+	return raw, nil
+}
+
+func getNodeReverseAttribute(node ast.Node) (bool, error) {
+	value, exists := node.AttributeString(attrNameReverse)
+	if !exists {
+		return false, nil
+	}
+
+	raw, ok := value.(string)
+	if !ok {
+		return false, errors.Errorf("unexpected value type '%T' for '%s' attribute", value, attrNameReverse)
+	}
+
+	reverse, err := strconv.ParseBool(raw)
+	if err != nil {
+		return false, errors.Wrapf(err, "invalid value '%s' for '%s' attribute", raw, attrNameReverse)
+	}
+
+	return reverse, nil
+}
+
+// getNodeWhereAttribute returns the conditions of the 'where' attribute,
+// i.e. where="status=accepted,author=jdoe"
+func getNodeWhereAttribute(node ast.Node) ([]condition, error) {
+	value, exists := node.AttributeString(attrNameWhere)
+	if !exists {
+		return nil, nil
+	}
+
+	raw, ok := value.(string)
+	if !ok {
+		return nil, errors.Errorf("unexpected value type '%T' for '%s' attribute", value, attrNameWhere)
+	}
+
+	conditions := make([]condition, 0)
+
+	for _, rawCondition := range strings.Split(raw, ",") {
+		key, value, found := strings.Cut(rawCondition, "=")
+		key = strings.TrimPrefix(strings.TrimSpace(key), metaKeyPrefix)
+		if !found || key == "" {
+			return nil, errors.Errorf("invalid condition '%s' for '%s' attribute, expected '<key>=<value>'", rawCondition, attrNameWhere)
+		}
+
+		conditions = append(conditions, condition{
+			key:   key,
+			value: strings.TrimSpace(value),
+		})
+	}
+
+	return conditions, nil
+}
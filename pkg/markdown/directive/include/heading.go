@@ -0,0 +1,146 @@
+package include
+
+import (
+	"bytes"
+
+	"github.com/Bornholm/amatl/pkg/markdown/directive"
+	"github.com/pkg/errors"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/parser"
+)
+
+const (
+	// attrSourceID is the identifier of a renamed heading
+	// in the document it comes from
+	attrSourceID = "sourceID"
+	// attrSourceDestination is the destination of a rewritten
+	// link in the document it comes from
+	attrSourceDestination = "sourceDestination"
+)
+
+// uniqueHeadingIDs renames the automatic identifiers of the headings of the
+// given document and of its included documents as if they were generated
+// for a single document, the included documents being parsed separately,
+// and rewrites the links of each document targeting its renamed headings
+func uniqueHeadingIDs(root ast.Node, source []byte, ids parser.IDs) error {
+	// The identifiers generated when parsing the document alone
+	local := parser.NewContext().IDs()
+
+	renamed := map[string][]byte{}
+	links := []*ast.Link{}
+
+	err := ast.Walk(root, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if !entering {
+			return ast.WalkContinue, nil
+		}
+
+		switch node := n.(type) {
+		case *ast.Heading:
+			id, exists := headingSourceID(node)
+			if !exists {
+				return ast.WalkContinue, nil
+			}
+
+			line := headingLastLine(node, source)
+
+			// Identifiers set explicitly are kept as is
+			if !bytes.Equal(id, local.Generate(line, ast.KindHeading)) {
+				local.Put(id)
+				ids.Put(id)
+				return ast.WalkContinue, nil
+			}
+
+			unique := ids.Generate(line, ast.KindHeading)
+			node.SetAttributeString("id", unique)
+
+			if !bytes.Equal(id, unique) {
+				node.SetAttributeString(attrSourceID, id)
+				renamed[string(id)] = unique
+			}
+
+		case *ast.Link:
+			links = append(links, node)
+
+		case *directive.Node:
+			includedNode, exists := IncludedNode(node)
+			if !exists {
+				return ast.WalkContinue, nil
+			}
+
+			includedSource, exists := IncludedSource(node)
+			if !exists {
+				return ast.WalkContinue, nil
+			}
+
+			if err := uniqueHeadingIDs(includedNode, includedSource, ids); err != nil {
+				return ast.WalkStop, errors.WithStack(err)
+			}
+		}
+
+		return ast.WalkContinue, nil
+	})
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	for _, link := range links {
+		destination := linkSourceDestination(link)
+		if len(destination) < 2 || destination[0] != '#' {
+			continue
+		}
+
+		unique, exists := renamed[string(destination[1:])]
+		if !exists {
+			link.Destination = destination
+			continue
+		}
+
+		link.SetAttributeString(attrSourceDestination, destination)
+		link.Destination = append([]byte("#"), unique...)
+	}
+
+	return nil
+}
+
+// headingSourceID returns the identifier of the heading in the document it
+// comes from, the included documents being cached and included again
+func headingSourceID(heading *ast.Heading) ([]byte, bool) {
+	if id, exists := heading.AttributeString(attrSourceID); exists {
+		raw, ok := id.([]byte)
+		return raw, ok
+	}
+
+	id, exists := heading.AttributeString("id")
+	if !exists {
+		return nil, false
+	}
+
+	raw, ok := id.([]byte)
+
+	return raw, ok
+}
+
+// headingLastLine returns the last line of the heading,
+// from which goldmark generates its identifier
+func headingLastLine(heading *ast.Heading, source []byte) []byte {
+	lines := heading.Lines()
+	if lines.Len() == 0 {
+		return nil
+	}
+
+	segment := lines.At(lines.Len() - 1)
+
+	return segment.Value(source)
+}
+
+// linkSourceDestination returns the destination of the
+// link in the document it comes from
+func linkSourceDestination(link *ast.Link) []byte {
+	if destination, exists := link.AttributeString(attrSourceDestination); exists {
+		if raw, ok := destination.([]byte); ok {
+			return raw
+		}
+	}
+
+	return link.Destination
+}
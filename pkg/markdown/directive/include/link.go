@@ -0,0 +1,210 @@
+package include
+
+import (
+	"context"
+	"log/slog"
+	"net/url"
+	"path/filepath"
+	"strings"
+
+	"github.com/Bornholm/amatl/pkg/markdown/directive"
+	"github.com/Bornholm/amatl/pkg/resolver"
+	"github.com/pkg/errors"
+	"github.com/yuin/goldmark/ast"
+)
+
+// documentAnchors are the identifiers of the headings of a document
+type documentAnchors struct {
+	// first is the identifier of the first heading of the document, if any
+	first []byte
+	// ids are the identifiers of the headings, indexed
+	// by their identifier in the document they come from
+	ids map[string][]byte
+}
+
+// documentLink is a link to another document
+type documentLink struct {
+	link     *ast.Link
+	path     resolver.Path
+	fragment string
+}
+
+// rewriteDocumentLinks rewrites the links to the documents included in the
+// given document, or to the document itself, as links to the corresponding
+// headings. The links to Markdown documents which are not included are
+// reported if the document includes at least one other document
+func rewriteDocumentLinks(ctx context.Context, root ast.Node, rootPath resolver.Path) error {
+	documents := map[resolver.Path]*documentAnchors{
+		canonicalPath(rootPath): getDocumentAnchors(root),
+	}
+
+	links := []documentLink{}
+
+	var collect func(scope ast.Node, baseDir resolver.Path) error
+	collect = func(scope ast.Node, baseDir resolver.Path) error {
+		return ast.Walk(scope, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+			if !entering {
+				return ast.WalkContinue, nil
+			}
+
+			switch node := n.(type) {
+			case *ast.Link:
+				if link, ok := parseDocumentLink(node, baseDir); ok {
+					links = append(links, link)
+				}
+
+			case *directive.Node:
+				includedNode, exists := IncludedNode(node)
+				if !exists {
+					return ast.WalkContinue, nil
+				}
+
+				// Links target the first inclusion of a document, the
+				// glob patterns including their matches as directives
+				if includedPath, exists := IncludedPath(node); exists {
+					key := canonicalPath(includedPath)
+					if _, exists := documents[key]; !exists {
+						documents[key] = getDocumentAnchors(includedNode)
+					}
+				}
+
+				// Links of included documents are already
+				// rewritten relative to the root document
+				if err := collect(includedNode, ""); err != nil {
+					return ast.WalkStop, errors.WithStack(err)
+				}
+			}
+
+			return ast.WalkContinue, nil
+		})
+	}
+
+	if err := collect(root, rootPath.Dir()); err != nil {
+		return errors.WithStack(err)
+	}
+
+	for _, l := range links {
+		destination := linkSourceDestination(l.link)
+
+		// The included documents being cached, their links
+		// may have been rewritten by a previous rendering
+		l.link.Destination = destination
+
+		anchors, included := documents[canonicalPath(l.path)]
+		if !included {
+			if len(documents) > 1 {
+				slog.WarnContext(ctx, "link to a document which is not included", slog.String("destination", string(destination)))
+			}
+
+			continue
+		}
+
+		anchor := anchors.first
+		if l.fragment != "" {
+			id, exists := anchors.ids[l.fragment]
+			if !exists {
+				slog.WarnContext(ctx, "link to an unknown heading of an included document", slog.String("destination", string(destination)))
+				continue
+			}
+
+			anchor = id
+		}
+
+		if anchor == nil {
+			continue
+		}
+
+		l.link.SetAttributeString(attrSourceDestination, destination)
+		l.link.Destination = append([]byte("#"), anchor...)
+	}
+
+	return nil
+}
+
+// parseDocumentLink returns the Markdown document targeted by the given link
+// and the fragment of the link, the relative links being resolved with
+// the given base directory
+func parseDocumentLink(link *ast.Link, baseDir resolver.Path) (documentLink, bool) {
+	destination := string(linkSourceDestination(link))
+
+	if strings.HasPrefix(destination, "#") {
+		return documentLink{}, false
+	}
+
+	rawPath, fragment, _ := strings.Cut(destination, "#")
+
+	if !resolver.Path(rawPath).IsURL() {
+		if unescaped, err := url.PathUnescape(rawPath); err == nil {
+			rawPath = unescaped
+		}
+	}
+
+	switch strings.ToLower(filepath.Ext(rawPath)) {
+	case ".md", ".markdown":
+	default:
+		return documentLink{}, false
+	}
+
+	path := resolver.Path(rawPath)
+	if baseDir != "" {
+		path = baseDir.Join(path)
+	}
+
+	return documentLink{
+		link:     link,
+		path:     path,
+		fragment: fragment,
+	}, true
+}
+
+// getDocumentAnchors returns the identifiers of the headings
+// of the given document, excluding its included documents
+func getDocumentAnchors(root ast.Node) *documentAnchors {
+	anchors := &documentAnchors{
+		ids: map[string][]byte{},
+	}
+
+	_ = ast.Walk(root, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if !entering {
+			return ast.WalkContinue, nil
+		}
+
+		heading, ok := n.(*ast.Heading)
+		if !ok {
+			return ast.WalkContinue, nil
+		}
+
+		sourceID, exists := headingSourceID(heading)
+		if !exists {
+			return ast.WalkContinue, nil
+		}
+
+		rawID, _ := heading.AttributeString("id")
+
+		id, ok := rawID.([]byte)
+		if !ok {
+			return ast.WalkContinue, nil
+		}
+
+		if anchors.first == nil {
+			anchors.first = id
+		}
+
+		anchors.ids[string(sourceID)] = id
+
+		return ast.WalkContinue, nil
+	})
+
+	return anchors
+}
+
+// canonicalPath returns the absolute path of local documents,
+// used to compare the paths of the documents
+func canonicalPath(path resolver.Path) resolver.Path {
+	abs, err := path.Abs()
+	if err != nil {
+		return path
+	}
+
+	return abs
+}
@@ -10,21 +10,19 @@ import (
 )
 
 type MarkdownRenderer struct {
+	// Deprecated: the included nodes are associated with the
+	// directives, the cache is only used for the directives
+	// without included node.
 	Cache *SourceCache
 }
 
 // Render implements markdown.NodeRenderer.
 func (mr *MarkdownRenderer) Render(r *markdown.Render, directive *directive.Node, entering bool) (ast.WalkStatus, error) {
-	rawURL, err := getNodeURLAttribute(directive)
+	includedSource, includedNode, err := getCachedIncluded(mr.Cache, directive)
 	if err != nil {
 		return ast.WalkStop, errors.WithStack(err)
 	}
 
-	includedSource, includedNode, exists := mr.Cache.Get(rawURL)
-	if !exists {
-		return ast.WalkStop, errors.Errorf("could not find source associated with path '%s'", rawURL)
-	}
-
 	var buff bytes.Buffer
 
 	if err := r.Renderer().Render(&buff, includedSource, includedNode); err != nil {
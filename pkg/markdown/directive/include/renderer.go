@@ -10,22 +10,20 @@ import (
 )
 
 type NodeRenderer struct {
+	// Deprecated: the included nodes are associated with the
+	// directives, the cache is only used for the directives
+	// without included node.
 	Cache    *SourceCache
 	Renderer renderer.Renderer
 }
 
 // Render implements directive.NodeRenderer.
 func (r *NodeRenderer) Render(writer util.BufWriter, source []byte, node *directive.Node) {
-	rawURL, err := getNodeURLAttribute(node)
+	includedSource, includedNode, err := getCachedIncluded(r.Cache, node)
 	if err != nil {
 		panic(errors.WithStack(err))
 	}
 
-	includedSource, includedNode, exists := r.Cache.Get(rawURL)
-	if !exists {
-		panic(errors.Errorf("could not find source associated with path '%s'", rawURL))
-	}
-
 	var buff bytes.Buffer
 
 	if err := r.Renderer.Render(&buff, includedSource, includedNode); err != nil {
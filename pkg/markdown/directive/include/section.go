@@ -0,0 +1,78 @@
+package include
+
+import (
+	"github.com/Bornholm/amatl/pkg/markdown/selector"
+	"github.com/pkg/errors"
+	"github.com/yuin/goldmark/ast"
+)
+
+// selectSection returns a document with the section
+// of the heading identified by the given id
+func selectSection(root ast.Node, id string) (*ast.Document, error) {
+	var heading *ast.Heading
+
+	err := ast.Walk(root, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if !entering {
+			return ast.WalkContinue, nil
+		}
+
+		h, ok := n.(*ast.Heading)
+		if !ok {
+			return ast.WalkContinue, nil
+		}
+
+		if headingID, exists := headingSourceID(h); exists && string(headingID) == id {
+			heading = h
+			return ast.WalkStop, nil
+		}
+
+		return ast.WalkContinue, nil
+	})
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	if heading == nil {
+		return nil, errors.Wrapf(ErrSectionNotFound, "no heading with id '%s'", id)
+	}
+
+	return buildFilteredDocument(selector.SectionNodes(heading)), nil
+}
+
+// contextHeadingLevel returns the level of the heading preceding
+// the given node in its document, 0 if there is none
+func contextHeadingLevel(node ast.Node) int {
+	for n := node; n != nil; n = n.Parent() {
+		for sibling := n.PreviousSibling(); sibling != nil; sibling = sibling.PreviousSibling() {
+			if heading, ok := sibling.(*ast.Heading); ok {
+				return heading.Level
+			}
+		}
+	}
+
+	return 0
+}
+
+// getAutoShiftHeadings returns the shift placing the top level headings
+// of the given document right below the given context level
+func getAutoShiftHeadings(root ast.Node, contextLevel int) int {
+	minLevel := 0
+
+	_ = ast.Walk(root, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if !entering {
+			return ast.WalkContinue, nil
+		}
+
+		if heading, ok := n.(*ast.Heading); ok && (minLevel == 0 || heading.Level < minLevel) {
+			minLevel = heading.Level
+		}
+
+		return ast.WalkContinue, nil
+	})
+
+	if minLevel == 0 {
+		return 0
+	}
+
+	return contextLevel + 1 - minLevel
+}
@@ -1,31 +1,85 @@
 package include
 
-import "github.com/yuin/goldmark/ast"
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"sync"
 
+	"github.com/Bornholm/amatl/pkg/markdown/directive"
+	"github.com/Bornholm/amatl/pkg/resolver"
+	"github.com/yuin/goldmark/ast"
+)
+
+// SourceCacheEntry is a parsed included source
+type SourceCacheEntry struct {
+	Source []byte
+	Node   ast.Node
+	// Hash is the hash of the source content, see HashSource()
+	Hash string
+	// Dependencies are the hashes of the sources included,
+	// directly or not, by this source
+	Dependencies map[resolver.Path]string
+	// Diagnostics are the diagnostics reported while parsing
+	// this source and the sources it includes
+	Diagnostics []directive.Diagnostic
+}
+
+// SourceCache stores parsed included sources. It is safe for concurrent use
+// and can be shared between renderings: entries are only reused while the
+// content of the source and of its dependencies stays the same.
 type SourceCache struct {
-	sources map[string][]byte
-	nodes   map[string]ast.Node
+	mutex   sync.RWMutex
+	entries map[string]*SourceCacheEntry
 }
 
 func NewSourceCache() *SourceCache {
 	return &SourceCache{
-		sources: make(map[string][]byte),
-		nodes:   make(map[string]ast.Node),
+		entries: make(map[string]*SourceCacheEntry),
 	}
 }
 
-func (c *SourceCache) Set(path string, data []byte, node ast.Node) {
-	c.sources[path] = data
-	c.nodes[path] = node
+func (c *SourceCache) Set(key string, data []byte, node ast.Node) {
+	c.SetEntry(key, &SourceCacheEntry{
+		Source:       data,
+		Node:         node,
+		Hash:         HashSource(data),
+		Dependencies: map[resolver.Path]string{},
+	})
 }
 
-func (c *SourceCache) Get(path string) ([]byte, ast.Node, bool) {
-	data, sourceExists := c.sources[path]
-	node, nodeExists := c.nodes[path]
-
-	if !sourceExists || !nodeExists {
+func (c *SourceCache) Get(key string) ([]byte, ast.Node, bool) {
+	entry, exists := c.GetEntry(key)
+	if !exists {
 		return nil, nil, false
 	}
 
-	return data, node, true
+	return entry.Source, entry.Node, true
+}
+
+func (c *SourceCache) SetEntry(key string, entry *SourceCacheEntry) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.entries[key] = entry
+}
+
+func (c *SourceCache) GetEntry(key string) (*SourceCacheEntry, bool) {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	entry, exists := c.entries[key]
+
+	return entry, exists
+}
+
+func (c *SourceCache) Delete(key string) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	delete(c.entries, key)
+}
+
+func HashSource(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
 }
@@ -1,9 +1,14 @@
 package include
 
 import (
+	"context"
+	"fmt"
 	"io"
+	"log/slog"
+	"maps"
 	"net/url"
 	"path/filepath"
+	"slices"
 	"strconv"
 	"strings"
 
@@ -18,33 +23,76 @@ import (
 	"github.com/yuin/goldmark/text"
 )
 
+// DefaultMaxDepth is the default maximum number of nested includes
+const DefaultMaxDepth = 32
+
 type NodeTransformer struct {
 	Cache      *SourceCache
 	Parser     parser.Parser
 	SourcePath resolver.Path
+	// MaxDepth is the maximum number of nested includes,
+	// no limit is applied if lower or equal to zero
+	MaxDepth int
 }
 
 // Transform implements directive.NodeTransformer.
 func (t *NodeTransformer) Transform(node *directive.Node, reader text.Reader, pc parser.Context) error {
 	sourcePath := getSourcePath(pc, t.SourcePath)
 
-	resourcePath, rawURL, err := parseNodeURLAttribute(sourcePath, node)
+	resourcePath, fragment, err := parseNodeURLAttribute(sourcePath, node)
 	if err != nil {
 		return errors.Wrapf(err, "could not parse required attribute on directive '%s'", node.DirectiveType())
 	}
 
+	autoShiftHeadings := isAutoShiftHeadings(node)
+
 	shiftHeadings, err := getNodeShiftHeadingsAttribute(node)
-	if err != nil {
+	if err != nil && !autoShiftHeadings {
+		reportInvalidAttribute(pc, reader, node, attrNameShiftHeadings)
 		shiftHeadings = 0
 	}
 
 	fromHeadings, err := getNodeFromHeadingsAttribute(node)
 	if err != nil {
+		reportInvalidAttribute(pc, reader, node, attrNameFromHeadings)
 		fromHeadings = 0
 	}
 
-	if _, _, exists := t.Cache.Get(resourcePath.String()); exists {
-		return nil
+	opts := includeOptions{
+		shiftHeadings:     shiftHeadings,
+		autoShiftHeadings: autoShiftHeadings,
+		fromHeadings:      fromHeadings,
+		contextLevel:      contextHeadingLevel(node),
+	}
+
+	if isGlobPattern(resourcePath) {
+		return t.includeGlob(node, pc, sourcePath, resourcePath, fragment, opts)
+	}
+
+	return t.include(node, pc, sourcePath, resourcePath, fragment, opts)
+}
+
+// includeOptions are the options of an :include
+// directive applied to each included resource
+type includeOptions struct {
+	shiftHeadings     int
+	autoShiftHeadings bool
+	fromHeadings      int
+	// contextLevel is the level of the heading preceding the directive
+	contextLevel int
+}
+
+// include parses the given resource as the content of the given node
+func (t *NodeTransformer) include(node *directive.Node, pc parser.Context, sourcePath, resourcePath resolver.Path, fragment string, opts includeOptions) error {
+	stack := getIncludeStack(pc, sourcePath)
+	chain := append(stack, resourcePath)
+
+	if slices.Contains(stack, resourcePath) {
+		return errors.Wrapf(ErrIncludeCycle, "%s", formatIncludeChain(chain))
+	}
+
+	if t.MaxDepth > 0 && len(stack) > t.MaxDepth {
+		return errors.Wrapf(ErrMaxDepthExceeded, "%s (limit: %d)", formatIncludeChain(chain), t.MaxDepth)
 	}
 
 	ctx, err := pipeline.FromParserContext(pc)
@@ -52,22 +100,39 @@ func (t *NodeTransformer) Transform(node *directive.Node, reader text.Reader, pc
 		return errors.WithStack(err)
 	}
 
-	resourceReader, err := resolver.Resolve(ctx, resourcePath.String())
+	includedSource, err := readResource(ctx, resourcePath)
 	if err != nil {
-		return errors.Wrapf(err, "could not resolve resource '%s'", resourcePath)
+		return errors.WithStack(err)
 	}
 
-	defer func() {
-		if err := resourceReader.Close(); err != nil {
-			panic(errors.Wrapf(err, "could not close resource '%s'", resourcePath))
-		}
-	}()
+	setIncludedPath(node, resourcePath)
 
-	transformed := transform.NewNewlineReader(resourceReader)
+	hash := HashSource(includedSource)
+	cacheKey := getCacheKey(resourcePath, node)
 
-	includedSource, err := io.ReadAll(transformed)
-	if err != nil {
-		return errors.Wrapf(err, "could not read markdown resource '%s'", resourcePath)
+	// The automatic shift depends on the including context
+	if opts.autoShiftHeadings {
+		cacheKey += fmt.Sprintf("|contextLevel=%d", opts.contextLevel)
+	}
+
+	if t.Cache != nil {
+		entry, exists := t.Cache.GetEntry(cacheKey)
+		if exists && entry.Hash == hash && t.isUpToDate(ctx, entry) {
+			// The cached node is copied, the including
+			// document modifying its headings and links
+			includedNode, err := cloneNode(entry.Node)
+			if err == nil {
+				setIncludedSource(node, entry.Source)
+				setIncludedNode(node, includedNode)
+				recordDependencies(pc, resourcePath, hash, entry.Dependencies)
+				recordDiagnostics(pc, entry.Diagnostics)
+				directive.Hoist(node)
+
+				return nil
+			}
+
+			slog.DebugContext(ctx, "could not reuse cached node", slog.String("path", resourcePath.String()), slog.Any("error", errors.WithStack(err)))
+		}
 	}
 
 	setIncludedSource(node, includedSource)
@@ -82,10 +147,30 @@ func (t *NodeTransformer) Transform(node *directive.Node, reader text.Reader, pc
 	includeCtx := resolver.WithWorkDir(ctx, sourceDir)
 	includePC := pipeline.WithContext(includeCtx, parser.NewContext())
 
+	dependencies := map[resolver.Path]string{}
+
+	diagnostics := directive.NewDiagnostics()
+
 	setSourcePath(includePC, resourcePath)
+	setIncludeStack(includePC, chain)
+	setDependencies(includePC, dependencies)
+	directive.WithSourcePath(includePC, resourcePath.String())
+	directive.WithDiagnostics(includePC, diagnostics)
+	directive.WithRootContext(includePC, directive.RootContext(pc))
 
 	includedNode := t.Parser.Parse(includedReader, parser.WithContext(includePC))
 
+	footnotes := footnoteList(includedNode)
+	prefixFootnotes(footnotes, stack[0], resourcePath, 0)
+
+	if fragment != "" {
+		section, err := selectSection(includedNode, fragment)
+		if err != nil {
+			return errors.Wrapf(err, "could not select section of included markdown resource '%s'", resourcePath)
+		}
+		includedNode = section
+	}
+
 	selectAttr, _ := getNodeSelectAttribute(node)
 	if selectAttr != "" {
 		sel, err := selector.Parse(selectAttr)
@@ -96,32 +181,125 @@ func (t *NodeTransformer) Transform(node *directive.Node, reader text.Reader, pc
 		includedNode = buildFilteredDocument(matchedNodes)
 	}
 
-	if err := t.excludeSections(includedNode, fromHeadings); err != nil {
+	if err := t.excludeSections(includedNode, opts.fromHeadings); err != nil {
 		return errors.Wrapf(err, "could not exclude sections of included markdown resource '%s'", resourcePath)
 	}
 
+	keepFootnotes(includedNode, footnotes)
+
 	if err := t.rewriteRelativeLinks(includedNode, resourcePath); err != nil {
 		return errors.Wrapf(err, "could not rewrite links of included markdown resource '%s'", resourcePath)
 	}
 
+	shiftHeadings := opts.shiftHeadings
+	if opts.autoShiftHeadings {
+		shiftHeadings = getAutoShiftHeadings(includedNode, opts.contextLevel)
+	}
+
 	if err := t.shiftHeadings(includedNode, shiftHeadings); err != nil {
 		return errors.Wrapf(err, "could not shift headings of included markdown resource '%s'", resourcePath)
 	}
 
 	setIncludedNode(node, includedNode)
 
-	t.Cache.Set(rawURL, includedSource, includedNode)
+	if t.Cache != nil {
+		t.cacheNode(ctx, cacheKey, &SourceCacheEntry{
+			Source:       includedSource,
+			Node:         includedNode,
+			Hash:         hash,
+			Dependencies: dependencies,
+			Diagnostics:  diagnostics.All(),
+		})
+	}
+
+	recordDependencies(pc, resourcePath, hash, dependencies)
+	recordDiagnostics(pc, diagnostics.All())
+	directive.Hoist(node)
+
+	return nil
+}
 
-	parent := node.Parent()
-	if parent != nil && parent.Kind() == ast.KindParagraph {
-		grandparent := parent.Parent()
-		parent.RemoveChild(parent, node)
-		grandparent.ReplaceChild(grandparent, parent, node)
+// PostTransform implements directive.PostTranformer.
+// It makes the identifiers of the headings and the labels of the
+// footnotes unique across the document and its included documents,
+// then rewrites the links to the included documents as links to
+// their headings.
+func (t *NodeTransformer) PostTransform(doc *ast.Document, reader text.Reader, pc parser.Context) error {
+	if err := uniqueHeadingIDs(doc, reader.Source(), parser.NewContext().IDs()); err != nil {
+		return errors.Wrap(err, "could not make heading identifiers unique")
+	}
+
+	rootPath := getIncludeStack(pc, getSourcePath(pc, t.SourcePath))[0]
+
+	if err := uniqueFootnoteLabels(doc, rootPath, map[resolver.Path]int{}); err != nil {
+		return errors.Wrap(err, "could not make footnote labels unique")
+	}
+
+	ctx, err := pipeline.FromParserContext(pc)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	if err := rewriteDocumentLinks(ctx, doc, getSourcePath(pc, t.SourcePath)); err != nil {
+		return errors.Wrap(err, "could not rewrite links to included documents")
 	}
 
 	return nil
 }
 
+// cacheNode stores a copy of the node of the given entry, the node
+// being modified afterwards by the document including it
+func (t *NodeTransformer) cacheNode(ctx context.Context, key string, entry *SourceCacheEntry) {
+	node, err := cloneNode(entry.Node)
+	if err != nil {
+		slog.DebugContext(ctx, "could not cache node", slog.String("key", key), slog.Any("error", errors.WithStack(err)))
+		return
+	}
+
+	entry.Node = node
+
+	t.Cache.SetEntry(key, entry)
+}
+
+// isUpToDate checks that the sources included by the given cache entry
+// did not change since it was stored
+func (t *NodeTransformer) isUpToDate(ctx context.Context, entry *SourceCacheEntry) bool {
+	for path, hash := range entry.Dependencies {
+		source, err := readResource(ctx, path)
+		if err != nil {
+			return false
+		}
+
+		if HashSource(source) != hash {
+			return false
+		}
+	}
+
+	return true
+}
+
+func readResource(ctx context.Context, resourcePath resolver.Path) ([]byte, error) {
+	resourceReader, err := resolver.Resolve(ctx, resourcePath.String())
+	if err != nil {
+		return nil, errors.Wrapf(err, "could not resolve resource '%s'", resourcePath)
+	}
+
+	defer func() {
+		if err := resourceReader.Close(); err != nil {
+			panic(errors.Wrapf(err, "could not close resource '%s'", resourcePath))
+		}
+	}()
+
+	transformed := transform.NewNewlineReader(resourceReader)
+
+	data, err := io.ReadAll(transformed)
+	if err != nil {
+		return nil, errors.Wrapf(err, "could not read markdown resource '%s'", resourcePath)
+	}
+
+	return data, nil
+}
+
 func (t *NodeTransformer) excludeSections(root ast.Node, minLevel int) error {
 	currentLevel := 0
 	err := ast.Walk(root, func(node ast.Node, entering bool) (ast.WalkStatus, error) {
@@ -222,7 +400,10 @@ func (t *NodeTransformer) rewriteRelativeLinks(root ast.Node, basePath resolver.
 	return nil
 }
 
-var _ directive.NodeTransformer = &NodeTransformer{}
+var (
+	_ directive.NodeTransformer = &NodeTransformer{}
+	_ directive.PostTranformer  = &NodeTransformer{}
+)
 
 func isURL(str string) bool {
 	_, err := url.ParseRequestURI(str)
@@ -266,6 +447,21 @@ func getNodeShiftHeadingsAttribute(node ast.Node) (int, error) {
 	return int(shiftHeadings), nil
 }
 
+const shiftHeadingsAuto = "auto"
+
+// isAutoShiftHeadings checks that the included headings are shifted
+// relatively to the heading preceding the directive
+func isAutoShiftHeadings(node ast.Node) bool {
+	value, exists := node.AttributeString(attrNameShiftHeadings)
+	if !exists {
+		return false
+	}
+
+	raw, ok := value.(string)
+
+	return ok && raw == shiftHeadingsAuto
+}
+
 const attrNameFromHeadings = "fromHeadings"
 
 func getNodeFromHeadingsAttribute(node ast.Node) (int, error) {
@@ -287,15 +483,19 @@ func getNodeFromHeadingsAttribute(node ast.Node) (int, error) {
 	return int(fromHeadings), nil
 }
 
+// parseNodeURLAttribute returns the path of the included resource
+// and the fragment of its url, if any
 func parseNodeURLAttribute(basePath resolver.Path, node ast.Node) (resolver.Path, string, error) {
 	rawURL, err := getNodeURLAttribute(node)
 	if err != nil {
 		return "", "", errors.WithStack(err)
 	}
 
+	rawPath, fragment, _ := strings.Cut(rawURL, "#")
+
 	baseDir := basePath.Dir()
 
-	return baseDir.Join(resolver.Path(rawURL)), rawURL, nil
+	return baseDir.Join(resolver.Path(rawPath)), fragment, nil
 }
 
 var contextKeySourcePath = parser.NewContextKey()
@@ -313,6 +513,105 @@ func setSourcePath(ctx parser.Context, path resolver.Path) {
 	ctx.Set(contextKeySourcePath, path)
 }
 
+var contextKeyIncludeStack = parser.NewContextKey()
+
+// getIncludeStack returns the chain of documents currently being included,
+// starting with the root document
+func getIncludeStack(ctx parser.Context, sourcePath resolver.Path) []resolver.Path {
+	stack, ok := ctx.Get(contextKeyIncludeStack).([]resolver.Path)
+	if !ok || len(stack) == 0 {
+		return []resolver.Path{sourcePath}
+	}
+
+	return slices.Clone(stack)
+}
+
+func setIncludeStack(ctx parser.Context, stack []resolver.Path) {
+	ctx.Set(contextKeyIncludeStack, stack)
+}
+
+func formatIncludeChain(chain []resolver.Path) string {
+	var sb strings.Builder
+	for idx, p := range chain {
+		if idx != 0 {
+			sb.WriteString(" -> ")
+		}
+		sb.WriteString(p.String())
+	}
+	return sb.String()
+}
+
+var contextKeyDependencies = parser.NewContextKey()
+
+func setDependencies(ctx parser.Context, dependencies map[resolver.Path]string) {
+	ctx.Set(contextKeyDependencies, dependencies)
+}
+
+// recordDependencies adds the given source and its own dependencies
+// to the dependencies of the source being parsed, if any
+func recordDependencies(ctx parser.Context, path resolver.Path, hash string, nested map[resolver.Path]string) {
+	dependencies, ok := ctx.Get(contextKeyDependencies).(map[resolver.Path]string)
+	if !ok {
+		return
+	}
+
+	dependencies[path] = hash
+
+	maps.Copy(dependencies, nested)
+}
+
+// recordDiagnostics forwards the diagnostics of an included
+// source to the collector of the including document, if any
+func recordDiagnostics(pc parser.Context, included []directive.Diagnostic) {
+	diagnostics, ok := directive.DiagnosticsFromContext(pc)
+	if !ok {
+		return
+	}
+
+	diagnostics.Add(included...)
+}
+
+// reportInvalidAttribute reports the given attribute of the
+// directive as invalid if it is present, i.e. not an integer
+func reportInvalidAttribute(pc parser.Context, reader text.Reader, node *directive.Node, name string) {
+	value, exists := node.AttributeString(name)
+	if !exists {
+		return
+	}
+
+	directive.Report(pc, reader.Source(), node, "invalid value '%v' for attribute '%s' of directive '%s', expected an integer", value, name, node.DirectiveType())
+}
+
+// getCacheKey returns the key identifying the included source in the cache,
+// attributes altering the included nodes being part of it
+func getCacheKey(resourcePath resolver.Path, node ast.Node) string {
+	var sb strings.Builder
+
+	sb.WriteString(resourcePath.String())
+
+	attributes := slices.Clone(node.Attributes())
+	slices.SortFunc(attributes, func(a, b ast.Attribute) int {
+		return strings.Compare(string(a.Name), string(b.Name))
+	})
+
+	for _, attr := range attributes {
+		switch string(attr.Name) {
+		case attrNameUrl:
+			// The selected section alters the included nodes
+			if _, fragment, found := strings.Cut(fmt.Sprintf("%v", attr.Value), "#"); found {
+				sb.WriteString("#" + fragment)
+			}
+			continue
+		case attrIncludedNode, attrIncludedSource, attrIncludedPath:
+			continue
+		}
+
+		sb.WriteString(fmt.Sprintf("|%s=%v", attr.Name, attr.Value))
+	}
+
+	return sb.String()
+}
+
 const attrNameSelect = "select"
 
 func getNodeSelectAttribute(node ast.Node) (string, bool) {
@@ -0,0 +1,559 @@
+package include
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Bornholm/amatl/pkg/markdown/directive"
+	"github.com/Bornholm/amatl/pkg/pipeline"
+	"github.com/Bornholm/amatl/pkg/resolver"
+	"github.com/pkg/errors"
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/extension"
+	extAST "github.com/yuin/goldmark/extension/ast"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/text"
+	"github.com/yuin/goldmark/util"
+	"go.abhg.dev/goldmark/frontmatter"
+
+	_ "github.com/Bornholm/amatl/pkg/resolver/file"
+)
+
+func TestNodeTransformerCycle(t *testing.T) {
+	dir := t.TempDir()
+
+	writeFiles(t, dir, map[string]string{
+		"a.md": "# A\n\n:include{url=\"./b.md\"}\n",
+		"b.md": "# B\n\n:include{url=\"./a.md\"}\n",
+	})
+
+	_, err := parseFile(t, filepath.Join(dir, "a.md"), 0, NewSourceCache())
+	if !errors.Is(err, ErrIncludeCycle) {
+		t.Fatalf("expected error '%v', got '%v'", ErrIncludeCycle, err)
+	}
+
+	expectedChain := strings.Join([]string{
+		filepath.Join(dir, "a.md"),
+		filepath.Join(dir, "b.md"),
+		filepath.Join(dir, "a.md"),
+	}, " -> ")
+
+	if !strings.Contains(err.Error(), expectedChain) {
+		t.Errorf("expected error to contain include chain '%s', got '%s'", expectedChain, err.Error())
+	}
+}
+
+func TestNodeTransformerMaxDepth(t *testing.T) {
+	dir := t.TempDir()
+
+	writeFiles(t, dir, map[string]string{
+		"a.md": "# A\n\n:include{url=\"./b.md\"}\n",
+		"b.md": "# B\n\n:include{url=\"./c.md\"}\n",
+		"c.md": "# C\n",
+	})
+
+	if _, err := parseFile(t, filepath.Join(dir, "a.md"), 2, NewSourceCache()); err != nil {
+		t.Fatalf("%+v", errors.WithStack(err))
+	}
+
+	_, err := parseFile(t, filepath.Join(dir, "a.md"), 1, NewSourceCache())
+	if !errors.Is(err, ErrMaxDepthExceeded) {
+		t.Fatalf("expected error '%v', got '%v'", ErrMaxDepthExceeded, err)
+	}
+}
+
+func TestNodeTransformerRepeatedInclude(t *testing.T) {
+	dir := t.TempDir()
+
+	writeFiles(t, dir, map[string]string{
+		"a.md": "# A\n\n:include{url=\"./c.md\"}\n\n:include{url=\"./b.md\"}\n",
+		"b.md": "# B\n\n:include{url=\"./c.md\"}\n",
+		"c.md": "# C\n",
+	})
+
+	if _, err := parseFile(t, filepath.Join(dir, "a.md"), 0, NewSourceCache()); err != nil {
+		t.Fatalf("%+v", errors.WithStack(err))
+	}
+}
+
+func TestNodeTransformerCacheInvalidation(t *testing.T) {
+	dir := t.TempDir()
+
+	writeFiles(t, dir, map[string]string{
+		"a.md": "# A\n\n:include{url=\"./b.md\"}\n",
+		"b.md": "# B\n\n:include{url=\"./c.md\"}\n",
+		"c.md": "# C\n",
+	})
+
+	cache := NewSourceCache()
+
+	document, err := parseFile(t, filepath.Join(dir, "a.md"), 0, cache)
+	if err != nil {
+		t.Fatalf("%+v", errors.WithStack(err))
+	}
+
+	first := includedNodes(t, document)
+
+	cacheKey := getCacheKey(resolver.Path(filepath.Join(dir, "b.md")), ast.NewParagraph())
+
+	cached, exists := cache.GetEntry(cacheKey)
+	if !exists {
+		t.Fatalf("expected cache entry for '%s'", filepath.Join(dir, "b.md"))
+	}
+
+	// Unchanged sources are reused from the cache
+	document, err = parseFile(t, filepath.Join(dir, "a.md"), 0, cache)
+	if err != nil {
+		t.Fatalf("%+v", errors.WithStack(err))
+	}
+
+	if entry, _ := cache.GetEntry(cacheKey); entry != cached {
+		t.Errorf("expected included node to be reused from cache")
+	}
+
+	if second := includedNodes(t, document); second[0] == first[0] || second[0] == cached.Node {
+		t.Errorf("expected included node to be copied from cache")
+	}
+
+	// A change in a nested include invalidates its ancestors
+	writeFiles(t, dir, map[string]string{
+		"c.md": "# Changed\n",
+	})
+
+	document, err = parseFile(t, filepath.Join(dir, "a.md"), 0, cache)
+	if err != nil {
+		t.Fatalf("%+v", errors.WithStack(err))
+	}
+
+	if entry, _ := cache.GetEntry(cacheKey); entry == cached {
+		t.Errorf("expected included node to be parsed again")
+	}
+
+	entry, exists := cache.GetEntry(getCacheKey(resolver.Path(filepath.Join(dir, "c.md")), ast.NewParagraph()))
+	if !exists {
+		t.Fatalf("expected cache entry for '%s'", filepath.Join(dir, "c.md"))
+	}
+
+	if e, g := "# Changed\n", string(entry.Source); e != g {
+		t.Errorf("entry.Source: expected '%s', got '%s'", e, g)
+	}
+}
+
+func TestNodeTransformerCachedNodeUnchanged(t *testing.T) {
+	dir := t.TempDir()
+
+	writeFiles(t, dir, map[string]string{
+		"a.md": "# Intro\n\n:include{url=\"./b.md\"}\n\n:include{url=\"./c.md\"}\n",
+		"b.md": "# Intro\n",
+		"c.md": "See [intro](./b.md#intro).\n",
+	})
+
+	cache := NewSourceCache()
+
+	if _, err := parseFile(t, filepath.Join(dir, "a.md"), 0, cache); err != nil {
+		t.Fatalf("%+v", errors.WithStack(err))
+	}
+
+	// The headings and the links of the cached documents are
+	// rewritten according to the including document only
+	writeFiles(t, dir, map[string]string{
+		"a.md": "# Other\n\n:include{url=\"./b.md\"}\n\n:include{url=\"./c.md\"}\n",
+	})
+
+	document, err := parseFile(t, filepath.Join(dir, "a.md"), 0, cache)
+	if err != nil {
+		t.Fatalf("%+v", errors.WithStack(err))
+	}
+
+	ids, destinations := headingIDsAndLinks(t, document)
+
+	if e, g := "other,intro", strings.Join(ids, ","); e != g {
+		t.Errorf("ids: expected '%s', got '%s'", e, g)
+	}
+
+	if e, g := "#intro", strings.Join(destinations, ","); e != g {
+		t.Errorf("destinations: expected '%s', got '%s'", e, g)
+	}
+}
+
+func TestNodeTransformerFootnotes(t *testing.T) {
+	dir := t.TempDir()
+
+	writeFiles(t, dir, map[string]string{
+		"a.md": "# A\n\nA[^1]\n\n:include{url=\"./b.md\"}\n\n:include{url=\"./c.md\" select=\"p\"}\n\n[^1]: Note of A\n",
+		"b.md": "# B\n\nB[^1]\n\n[^1]: Note of B\n",
+		"c.md": "# C\n\nC[^1]\n\n[^1]: Note of C\n",
+	})
+
+	document, err := parseFile(t, filepath.Join(dir, "a.md"), 0, NewSourceCache())
+	if err != nil {
+		t.Fatalf("%+v", errors.WithStack(err))
+	}
+
+	labels := map[string]struct{}{}
+
+	for _, root := range append([]ast.Node{document}, includedNodes(t, document)...) {
+		list := footnoteList(root)
+		if list == nil {
+			t.Fatalf("expected footnote list in included document")
+		}
+
+		for footnote := list.FirstChild(); footnote != nil; footnote = footnote.NextSibling() {
+			labels[string(footnote.(*extAST.Footnote).Ref)] = struct{}{}
+		}
+	}
+
+	if e, g := 3, len(labels); e != g {
+		t.Errorf("len(labels): expected '%d', got '%d' (%v)", e, g, labels)
+	}
+
+	if _, exists := labels["1"]; !exists {
+		t.Errorf("expected label of the including document to be kept, got %v", labels)
+	}
+}
+
+func TestNodeTransformerFootnotesIncludedTwice(t *testing.T) {
+	dir := t.TempDir()
+
+	writeFiles(t, dir, map[string]string{
+		"a.md": "# A\n\n:include{url=\"./b.md\"}\n\n:include{url=\"./b.md\"}\n",
+		"b.md": "B[^1]\n\n[^1]: Note of B\n",
+	})
+
+	cache := NewSourceCache()
+
+	// The cached included documents are labeled again on each parsing
+	for i := 0; i < 2; i++ {
+		document, err := parseFile(t, filepath.Join(dir, "a.md"), 0, cache)
+		if err != nil {
+			t.Fatalf("%+v", errors.WithStack(err))
+		}
+
+		labels := []string{}
+
+		for _, root := range includedNodes(t, document) {
+			list := footnoteList(root)
+			if list == nil {
+				t.Fatalf("expected footnote list in included document")
+			}
+
+			for footnote := list.FirstChild(); footnote != nil; footnote = footnote.NextSibling() {
+				labels = append(labels, string(footnote.(*extAST.Footnote).Ref))
+			}
+		}
+
+		if e, g := 2, len(labels); e != g {
+			t.Fatalf("len(labels): expected '%d', got '%d' (%v)", e, g, labels)
+		}
+
+		if labels[0] == labels[1] {
+			t.Errorf("expected unique labels, got %v", labels)
+		}
+
+		if e, g := strings.TrimSuffix(labels[0], "1")+"1-1", labels[1]; e != g {
+			t.Errorf("second label: expected '%s', got '%s'", e, g)
+		}
+	}
+}
+
+func TestNodeTransformerFootnotesLocation(t *testing.T) {
+	files := map[string]string{
+		"a.md":      "# A\n\n:include{url=\"./docs/b.md\"}\n\n:include{url=\"./docs/b.md\"}\n\n:include{url=\"./docs/c.md\"}\n",
+		"docs/b.md": "B[^1]\n\n[^1]: Note of B\n",
+		"docs/c.md": "C[^note]\n\n[^note]: Note of C\n",
+	}
+
+	// The same documents rendered from two locations get the same labels
+	var expected []string
+
+	for i := 0; i < 2; i++ {
+		dir := t.TempDir()
+
+		if err := os.Mkdir(filepath.Join(dir, "docs"), 0755); err != nil {
+			t.Fatalf("%+v", errors.WithStack(err))
+		}
+
+		writeFiles(t, dir, files)
+
+		document, err := parseFile(t, filepath.Join(dir, "a.md"), 0, NewSourceCache())
+		if err != nil {
+			t.Fatalf("%+v", errors.WithStack(err))
+		}
+
+		labels := []string{}
+
+		for _, root := range includedNodes(t, document) {
+			list := footnoteList(root)
+			if list == nil {
+				t.Fatalf("expected footnote list in included document")
+			}
+
+			for footnote := list.FirstChild(); footnote != nil; footnote = footnote.NextSibling() {
+				labels = append(labels, string(footnote.(*extAST.Footnote).Ref))
+			}
+		}
+
+		if e, g := 3, len(labels); e != g {
+			t.Fatalf("len(labels): expected '%d', got '%d' (%v)", e, g, labels)
+		}
+
+		if i == 0 {
+			expected = labels
+			continue
+		}
+
+		if e, g := strings.Join(expected, ","), strings.Join(labels, ","); e != g {
+			t.Errorf("labels: expected '%s', got '%s'", e, g)
+		}
+	}
+}
+
+func TestNodeTransformerUniqueHeadingIDs(t *testing.T) {
+	dir := t.TempDir()
+
+	writeFiles(t, dir, map[string]string{
+		"a.md": "## Overview\n\n[A](#overview)\n\n:include{url=\"./b.md\"}\n\n:include{url=\"./b.md\"}\n",
+		"b.md": "## Overview\n\n[B](#overview)\n\n## Usage {#how-to}\n",
+	})
+
+	cache := NewSourceCache()
+
+	// The cached included documents are renamed again on each parsing
+	for i := 0; i < 2; i++ {
+		document, err := parseFile(t, filepath.Join(dir, "a.md"), 0, cache)
+		if err != nil {
+			t.Fatalf("%+v", errors.WithStack(err))
+		}
+
+		ids, destinations := headingIDsAndLinks(t, document)
+
+		if e, g := "overview,overview-1,how-to,overview-2,how-to", strings.Join(ids, ","); e != g {
+			t.Errorf("ids: expected '%s', got '%s'", e, g)
+		}
+
+		if e, g := "#overview,#overview-1,#overview-2", strings.Join(destinations, ","); e != g {
+			t.Errorf("destinations: expected '%s', got '%s'", e, g)
+		}
+	}
+}
+
+func TestNodeTransformerDocumentLinks(t *testing.T) {
+	dir := t.TempDir()
+
+	writeFiles(t, dir, map[string]string{
+		"a.md":   "# A\n\n[Auth](./api.md#auth), [API](api.md), [Other](other.md)\n\n:include{url=\"./api.md\"}\n",
+		"api.md": "# API\n\n## Auth\n\n[Back](a.md#a)\n",
+	})
+
+	cache := NewSourceCache()
+
+	for i := 0; i < 2; i++ {
+		document, err := parseFile(t, filepath.Join(dir, "a.md"), 0, cache)
+		if err != nil {
+			t.Fatalf("%+v", errors.WithStack(err))
+		}
+
+		_, destinations := headingIDsAndLinks(t, document)
+
+		if e, g := "#auth,#api,other.md,#a", strings.Join(destinations, ","); e != g {
+			t.Errorf("destinations: expected '%s', got '%s'", e, g)
+		}
+	}
+}
+
+func TestNodeTransformerSection(t *testing.T) {
+	dir := t.TempDir()
+
+	writeFiles(t, dir, map[string]string{
+		"a.md":   "# A\n\n### Deep\n\n:include{url=\"./ref.md#authentication\" shiftHeadings=\"auto\"}\n\n# B\n\n:include{url=\"./ref.md#authentication\"}\n",
+		"b.md":   "# B\n\n:include{url=\"./ref.md#unknown\"}\n",
+		"ref.md": "# Reference\n\n## Intro\n\n## Authentication\n\nUse tokens.\n\n### Tokens\n\n## Other\n",
+	})
+
+	document, err := parseFile(t, filepath.Join(dir, "a.md"), 0, NewSourceCache())
+	if err != nil {
+		t.Fatalf("%+v", errors.WithStack(err))
+	}
+
+	expectedLevels := [][]int{{4, 5}, {2, 3}}
+
+	for idx, included := range includedNodes(t, document) {
+		levels := make([]int, 0)
+
+		for child := included.FirstChild(); child != nil; child = child.NextSibling() {
+			if heading, ok := child.(*ast.Heading); ok {
+				levels = append(levels, heading.Level)
+			}
+		}
+
+		if e, g := fmt.Sprint(expectedLevels[idx]), fmt.Sprint(levels); e != g {
+			t.Errorf("levels[%d]: expected '%s', got '%s'", idx, e, g)
+		}
+	}
+
+	_, err = parseFile(t, filepath.Join(dir, "b.md"), 0, NewSourceCache())
+	if !errors.Is(err, ErrSectionNotFound) {
+		t.Fatalf("expected error '%v', got '%v'", ErrSectionNotFound, err)
+	}
+}
+
+func TestNodeTransformerGlob(t *testing.T) {
+	dir := t.TempDir()
+
+	writeFiles(t, dir, map[string]string{
+		"a.md":     "# A\n\n:include{url=\"./adr-*.md\" sort=\"meta.order\" reverse=\"true\" where=\"status=accepted\" shiftHeadings=\"1\"}\n",
+		"adr-1.md": "---\norder: 2\nstatus: accepted\n---\n\n# ADR 1\n",
+		"adr-2.md": "---\norder: 10\nstatus: accepted\n---\n\n# ADR 2\n",
+		"adr-3.md": "---\norder: 3\nstatus: rejected\n---\n\n# ADR 3\n",
+	})
+
+	document, err := parseFile(t, filepath.Join(dir, "a.md"), 0, NewSourceCache())
+	if err != nil {
+		t.Fatalf("%+v", errors.WithStack(err))
+	}
+
+	ids, _ := headingIDsAndLinks(t, document)
+
+	if e, g := "a,adr-2,adr-1", strings.Join(ids, ","); e != g {
+		t.Errorf("ids: expected '%s', got '%s'", e, g)
+	}
+
+	matches := includedNodes(t, includedNodes(t, document)[0])
+
+	heading, ok := matches[0].FirstChild().(*ast.Heading)
+	if !ok {
+		t.Fatalf("expected heading, got '%T'", matches[0].FirstChild())
+	}
+
+	if e, g := 2, heading.Level; e != g {
+		t.Errorf("heading.Level: expected '%d', got '%d'", e, g)
+	}
+}
+
+// headingIDsAndLinks returns the identifiers of the headings and the
+// destinations of the links of the given document, in document order
+func headingIDsAndLinks(t *testing.T, document ast.Node) ([]string, []string) {
+	ids := make([]string, 0)
+	destinations := make([]string, 0)
+
+	var walk func(root ast.Node)
+	walk = func(root ast.Node) {
+		err := ast.Walk(root, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+			if !entering {
+				return ast.WalkContinue, nil
+			}
+
+			switch node := n.(type) {
+			case *ast.Heading:
+				if id, exists := node.AttributeString("id"); exists {
+					ids = append(ids, string(id.([]byte)))
+				}
+			case *ast.Link:
+				destinations = append(destinations, string(node.Destination))
+			}
+
+			if included, exists := IncludedNode(n); exists {
+				walk(included)
+			}
+
+			return ast.WalkContinue, nil
+		})
+		if err != nil {
+			t.Fatalf("%+v", errors.WithStack(err))
+		}
+	}
+
+	walk(document)
+
+	return ids, destinations
+}
+
+// includedNodes returns the nodes included by the :include directives
+// of the given document
+func includedNodes(t *testing.T, document ast.Node) []ast.Node {
+	nodes := make([]ast.Node, 0)
+
+	err := ast.Walk(document, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if !entering {
+			return ast.WalkContinue, nil
+		}
+
+		if included, exists := IncludedNode(n); exists {
+			nodes = append(nodes, included)
+		}
+
+		return ast.WalkContinue, nil
+	})
+	if err != nil {
+		t.Fatalf("%+v", errors.WithStack(err))
+	}
+
+	if len(nodes) == 0 {
+		t.Fatal("expected at least one included node")
+	}
+
+	return nodes
+}
+
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatalf("%+v", errors.WithStack(err))
+		}
+	}
+}
+
+func parseFile(t *testing.T, filename string, maxDepth int, cache *SourceCache) (document ast.Node, err error) {
+	source, err := os.ReadFile(filename)
+	if err != nil {
+		t.Fatalf("%+v", errors.WithStack(err))
+	}
+
+	parse := goldmark.New(
+		goldmark.WithExtensions(extension.Footnote, &frontmatter.Extender{}),
+		goldmark.WithParserOptions(
+			parser.WithAutoHeadingID(),
+			parser.WithAttribute(),
+		),
+	).Parser()
+
+	parse.AddOptions(
+		parser.WithInlineParsers(
+			util.Prioritized(&directive.InlineParser{}, 0),
+		),
+		parser.WithASTTransformers(
+			util.Prioritized(
+				directive.NewTransformer(
+					directive.WithTransformer(Type, &NodeTransformer{
+						SourcePath: resolver.Path(filename),
+						Cache:      cache,
+						Parser:     parse,
+						MaxDepth:   maxDepth,
+					}),
+				),
+				0,
+			),
+		),
+	)
+
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			recoveredErr, ok := recovered.(error)
+			if !ok {
+				recoveredErr = fmt.Errorf("%v", recovered)
+			}
+			err = recoveredErr
+		}
+	}()
+
+	pc := pipeline.WithContext(context.Background(), parser.NewContext())
+
+	document = parse.Parse(text.NewReader(source), parser.WithContext(pc))
+
+	return document, nil
+}
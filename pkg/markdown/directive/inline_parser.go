@@ -1,53 +1,216 @@
 package directive
 
 import (
+	"unicode"
+
 	"github.com/yuin/goldmark/ast"
 	"github.com/yuin/goldmark/parser"
 	"github.com/yuin/goldmark/text"
 )
 
+var contextKeyLabels = parser.NewContextKey()
+
+// InlineParser parses the directives written in the flow of the text,
+// i.e. :name{key="value"}, :name[content] or :name[content]{key="value"},
+// the content being parsed as inline Markdown
 type InlineParser struct {
 }
 
+// labelState is a directive whose content is being parsed
+type labelState struct {
+	node *Node
+	// bottom is the last delimiter before the content
+	bottom ast.Node
+	// end is the offset of the bracket closing the content
+	end int
+	// stop is the offset of the end of the directive
+	stop int
+}
+
 // Parse implements parser.InlineParser.
-func (*InlineParser) Parse(parent ast.Node, block text.Reader, pc parser.Context) ast.Node {
+func (p *InlineParser) Parse(parent ast.Node, block text.Reader, pc parser.Context) ast.Node {
+	line, _ := block.PeekLine()
+	if line[0] == ']' {
+		return p.closeLabel(block, pc)
+	}
+
+	// Prevent false positives such as "10:30" or "std::map"
+	if preceding := block.PrecendingCharacter(); preceding == ':' || unicode.IsLetter(preceding) || unicode.IsDigit(preceding) {
+		return nil
+	}
+
 	line, segment := block.PeekLine()
-	stop := findDirectiveEnd(line)
-	if stop < 0 {
+
+	name := directiveNamePrefixRegExp.Find(line[1:])
+	if name == nil {
 		return nil
 	}
 
-	if stop >= len(line) {
+	pos := 1 + len(name)
+
+	var label *text.Segment
+
+	if pos < len(line) && line[pos] == '[' {
+		end := findLabelEnd(line[pos:])
+		if end < 0 {
+			return nil
+		}
+
+		s := text.NewSegment(segment.Start+pos+1, segment.Start+pos+end)
+		label = &s
+		pos += end + 1
+	}
+
+	hasAttributes := pos < len(line) && line[pos] == '{'
+	if label == nil && !hasAttributes {
 		return nil
 	}
 
-	value := ast.NewTextSegment(text.NewSegment(segment.Start+1, segment.Start+stop))
+	savedLine, savedPosition := block.Position()
+
+	block.Advance(pos)
+
+	var attributes parser.Attributes
+
+	if hasAttributes {
+		parsed, ok := parser.ParseAttributes(block)
+		if !ok {
+			block.SetPosition(savedLine, savedPosition)
+			reportAt(pc, block.Source(), segment.Start, "malformed attributes of directive '%s'", name)
+			return nil
+		}
+
+		attributes = parsed
+	}
+
+	_, current := block.Position()
+
+	directive := &Node{
+		directiveType: Type(name),
+		value:         ast.NewTextSegment(text.NewSegment(segment.Start+1, current.Start)),
+		start:         segment.Start,
+	}
+
+	for _, attr := range attributes {
+		directive.SetAttribute(attr.Name, attributeString(attr.Value))
+	}
+
+	if label == nil {
+		return directive
+	}
+
+	directive.hasLabel = true
+	directive.label = *label
+
+	// The content is parsed with the rest of the line,
+	// the directive taking it when reaching its closing bracket
+	block.SetPosition(savedLine, savedPosition)
+	block.Advance(label.Start - segment.Start)
+
+	pushLabel(pc, &labelState{
+		node:   directive,
+		bottom: pc.LastDelimiter(),
+		end:    label.Stop,
+		stop:   current.Start,
+	})
+
+	return directive
+}
+
+// closeLabel ends the content of the directive closed
+// by the bracket at the current position, if any
+func (p *InlineParser) closeLabel(block text.Reader, pc parser.Context) ast.Node {
+	_, segment := block.PeekLine()
 
-	directive := parseDirective(line[:stop+1], value)
-	if directive == nil {
+	labels := getLabels(pc)
+
+	// The closing brackets of the contents can have been
+	// consumed by other parsers, e.g. in code spans
+	for len(labels) > 0 && labels[len(labels)-1].end < segment.Start {
+		revertLabel(labels[len(labels)-1])
+		labels = labels[:len(labels)-1]
+	}
+
+	if len(labels) == 0 || labels[len(labels)-1].end != segment.Start {
+		pc.Set(contextKeyLabels, labels)
 		return nil
 	}
 
-	directive.SetParent(parent)
+	state := labels[len(labels)-1]
+	pc.Set(contextKeyLabels, labels[:len(labels)-1])
+
+	parser.ProcessDelimiters(state.bottom, pc)
+
+	directive := state.node
+	parent := directive.Parent()
+
+	for child := directive.NextSibling(); child != nil; {
+		next := child.NextSibling()
+		parent.RemoveChild(parent, child)
+		directive.AppendChild(directive, child)
+		child = next
+	}
+
+	parent.RemoveChild(parent, directive)
+
+	block.Advance(state.stop - segment.Start)
 
-	block.Advance(stop + 1)
 	return directive
 }
 
+// CloseBlock implements parser.CloseBlocker.
+func (p *InlineParser) CloseBlock(parent ast.Node, block text.Reader, pc parser.Context) {
+	for _, state := range getLabels(pc) {
+		revertLabel(state)
+	}
+
+	pc.Set(contextKeyLabels, nil)
+}
+
 // Trigger implements parser.InlineParser.
 func (*InlineParser) Trigger() []byte {
-	return []byte(":")
+	return []byte{':', ']'}
 }
 
-var _ parser.InlineParser = &InlineParser{}
+var (
+	_ parser.InlineParser = &InlineParser{}
+	_ parser.CloseBlocker = &InlineParser{}
+)
+
+func getLabels(pc parser.Context) []*labelState {
+	labels, _ := pc.Get(contextKeyLabels).([]*labelState)
+	return labels
+}
+
+func pushLabel(pc parser.Context, state *labelState) {
+	pc.Set(contextKeyLabels, append(getLabels(pc), state))
+}
+
+// revertLabel replaces the directive whose content has not been
+// closed by the text opening it, its content being kept as is
+func revertLabel(state *labelState) {
+	directive := state.node
+	ast.MergeOrReplaceTextSegment(directive.Parent(), directive, text.NewSegment(directive.start, directive.label.Start))
+}
 
-func findDirectiveEnd(b []byte) int {
-	end := len(b) - 1
-	last := b[end]
+// findLabelEnd returns the position of the bracket closing the
+// label starting at the beginning of the given line, -1 if none
+func findLabelEnd(line []byte) int {
+	depth := 0
 
-	if last != ']' && last != '}' {
-		return -1
+	for i := 0; i < len(line); i++ {
+		switch line[i] {
+		case '\\':
+			i++
+		case '[':
+			depth++
+		case ']':
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
 	}
 
-	return end
+	return -1
 }
@@ -0,0 +1,143 @@
+package directive
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/Bornholm/amatl/pkg/markdown/renderer/markdown"
+	"github.com/Bornholm/amatl/pkg/markdown/renderer/markdown/node"
+	"github.com/pkg/errors"
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/renderer"
+	"github.com/yuin/goldmark/text"
+	"github.com/yuin/goldmark/util"
+)
+
+func TestInlineParser(t *testing.T) {
+	type testCase struct {
+		Name     string
+		Source   string
+		Expected string
+	}
+
+	testCases := []testCase{
+		{
+			Name:     "content",
+			Source:   "Press :kbd[Ctrl+C] to copy.",
+			Expected: "<p>Press <kbd>Ctrl+C</kbd> to copy.</p>\n",
+		},
+		{
+			Name:     "markdown content",
+			Source:   "Press :kbd[Ctrl+**C**] or :kbd[`Ctrl`+[C](#c)].",
+			Expected: "<p>Press <kbd>Ctrl+<strong>C</strong></kbd> or <kbd><code>Ctrl</code>+<a href=\"#c\">C</a></kbd>.</p>\n",
+		},
+		{
+			Name:     "unclosed content",
+			Source:   "Press :kbd[`Ctrl]` now.",
+			Expected: "<p>Press :kbd[<code>Ctrl]</code> now.</p>\n",
+		},
+		{
+			Name:     "content and attributes",
+			Source:   "The :badge[beta]{class=\"new\" since=\"1.2\"} feature.",
+			Expected: "<p>The <span data-since=\"1.2\" class=\"amatl-directive amatl-directive-badge new\">beta</span> feature.</p>\n",
+		},
+		{
+			Name:     "nested brackets",
+			Source:   ":badge[a [b] c]",
+			Expected: "<p><span class=\"amatl-directive amatl-directive-badge\">a [b] c</span></p>\n",
+		},
+		{
+			Name:     "attributes only",
+			Source:   "Before :unknown{key=\"value\"} after.",
+			Expected: "<p>Before  after.</p>\n",
+		},
+		{
+			Name:     "attributes only paragraph",
+			Source:   ":unknown{key=\"value\"}\n\nAfter.",
+			Expected: "<p>After.</p>\n",
+		},
+		{
+			Name:     "not a directive",
+			Source:   "At 10:30, std::map[x] and :name alone, :unclosed[label.",
+			Expected: "<p>At 10:30, std::map[x] and :name alone, :unclosed[label.</p>\n",
+		},
+	}
+
+	markdown := goldmark.New(
+		goldmark.WithParserOptions(
+			parser.WithInlineParsers(
+				util.Prioritized(&InlineParser{}, 0),
+			),
+		),
+		goldmark.WithRendererOptions(
+			renderer.WithNodeRenderers(
+				util.Prioritized(
+					NewRenderer(
+						WithRenderer("kbd", &kbdRenderer{}),
+					), 0,
+				),
+			),
+		),
+	)
+
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			var html bytes.Buffer
+
+			if err := markdown.Convert([]byte(tc.Source), &html); err != nil {
+				t.Fatalf("%+v", errors.WithStack(err))
+			}
+
+			if e, g := tc.Expected, html.String(); e != g {
+				t.Errorf("expected '%s', got '%s'", e, g)
+			}
+		})
+	}
+}
+
+func TestInlineParserMarkdownRoundTrip(t *testing.T) {
+	source := strings.Join([]string{
+		"Press :kbd[Ctrl+**C**] to copy the :abbr[API]{title=\"Application Programming Interface\"} key.",
+		"",
+		"Before :name{key=\"value\"} after.",
+	}, "\n")
+
+	parse := goldmark.New().Parser()
+	parse.AddOptions(
+		parser.WithInlineParsers(
+			util.Prioritized(&InlineParser{}, 0),
+		),
+	)
+
+	document := parse.Parse(text.NewReader([]byte(source)))
+
+	render := markdown.NewRenderer()
+	render.AddOptions(
+		markdown.WithNodeRenderers(node.Renderers()),
+		markdown.WithNodeRenderer(KindDirective, NewMarkdownNodeRenderer()),
+	)
+
+	var buff bytes.Buffer
+
+	if err := render.Render(&buff, []byte(source), document); err != nil {
+		t.Fatalf("%+v", errors.WithStack(err))
+	}
+
+	if e, g := source, strings.TrimSpace(buff.String()); e != g {
+		t.Errorf("expected '%s', got '%s'", e, g)
+	}
+}
+
+type kbdRenderer struct{}
+
+func (r *kbdRenderer) Render(writer util.BufWriter, source []byte, node *Node) {
+	_, _ = writer.WriteString("<kbd>")
+}
+
+func (r *kbdRenderer) RenderExit(writer util.BufWriter, source []byte, node *Node) {
+	_, _ = writer.WriteString("</kbd>")
+}
+
+var _ ContentNodeRenderer = &kbdRenderer{}
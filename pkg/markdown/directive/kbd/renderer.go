@@ -0,0 +1,22 @@
+package kbd
+
+import (
+	"github.com/Bornholm/amatl/pkg/markdown/directive"
+	"github.com/yuin/goldmark/util"
+)
+
+// NodeRenderer renders the :kbd[keys] directives as <kbd> elements
+type NodeRenderer struct {
+}
+
+// Render implements directive.NodeRenderer.
+func (r *NodeRenderer) Render(writer util.BufWriter, source []byte, node *directive.Node) {
+	_, _ = writer.WriteString("<kbd>")
+}
+
+// RenderExit implements directive.ContentNodeRenderer.
+func (r *NodeRenderer) RenderExit(writer util.BufWriter, source []byte, node *directive.Node) {
+	_, _ = writer.WriteString("</kbd>")
+}
+
+var _ directive.ContentNodeRenderer = &NodeRenderer{}
@@ -0,0 +1,5 @@
+package kbd
+
+import "github.com/Bornholm/amatl/pkg/markdown/directive"
+
+const Type directive.Type = "kbd"
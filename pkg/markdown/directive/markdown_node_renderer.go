@@ -1,7 +1,9 @@
 package directive
 
 import (
+	"bytes"
 	"fmt"
+	"slices"
 	"strings"
 
 	"github.com/Bornholm/amatl/pkg/markdown/renderer/markdown"
@@ -22,21 +24,28 @@ func (t *markdownDirectiveRenderer) Render(r *markdown.Render, directive *Node,
 
 type MarkdownDirectiveRendererFunc func(r *markdown.Render, directive *Node, entering bool) (ast.WalkStatus, error)
 
+// Render implements MarkdownDirectiveRenderer.
+func (fn MarkdownDirectiveRendererFunc) Render(r *markdown.Render, directive *Node, entering bool) (ast.WalkStatus, error) {
+	return fn(r, directive, entering)
+}
+
 type MarkdownNodeRenderer struct {
 	renderers map[Type]MarkdownDirectiveRenderer
 }
 
 // Render implements markdown.NodeRenderer.
 func (mr *MarkdownNodeRenderer) Render(r *markdown.Render, node ast.Node, entering bool) (ast.WalkStatus, error) {
-	if !entering {
-		return ast.WalkContinue, nil
-	}
-
 	directive, ok := node.(*Node)
 	if !ok {
 		return ast.WalkStop, fmt.Errorf("unexpected node %T, expected *directive.Node", node)
 	}
 
+	// Only the directives with content are notified of their exit,
+	// after their content
+	if !entering && !directive.IsContainer() && !directive.HasLabel() {
+		return ast.WalkContinue, nil
+	}
+
 	renderer, exists := mr.renderers[directive.DirectiveType()]
 	if !exists {
 		return mr.renderDefault(r, directive, entering)
@@ -46,6 +55,14 @@ func (mr *MarkdownNodeRenderer) Render(r *markdown.Render, node ast.Node, enteri
 }
 
 func (mr *MarkdownNodeRenderer) renderDefault(r *markdown.Render, directive *Node, entering bool) (ast.WalkStatus, error) {
+	if directive.IsContainer() {
+		return mr.renderDefaultContainer(r, directive, entering)
+	}
+
+	if directive.HasLabel() || directive.PreviousSibling() != nil || directive.NextSibling() != nil {
+		return mr.renderDefaultInline(r, directive, entering)
+	}
+
 	str := fmt.Sprintf(":%s{%s}", directive.DirectiveType(), marshalAttributes(directive.Attributes()))
 
 	_, _ = r.Writer().Write(markdown.NewLineChar)
@@ -55,6 +72,71 @@ func (mr *MarkdownNodeRenderer) renderDefault(r *markdown.Render, directive *Nod
 	return ast.WalkContinue, nil
 }
 
+func (mr *MarkdownNodeRenderer) renderDefaultContainer(r *markdown.Render, directive *Node, entering bool) (ast.WalkStatus, error) {
+	fence := bytes.Repeat([]byte{':'}, directive.fence)
+
+	if !entering {
+		_, _ = r.Writer().Write(markdown.NewLineChar)
+		_, _ = r.Writer().Write(fence)
+		return ast.WalkContinue, nil
+	}
+
+	if directive.PreviousSibling() != nil {
+		_, _ = r.Writer().Write(markdown.NewLineChar)
+		_, _ = r.Writer().Write(markdown.NewLineChar)
+	}
+
+	_, _ = r.Writer().Write(fence)
+	_, _ = r.Writer().Write([]byte(directive.DirectiveType()))
+
+	if attributes := stringAttributes(directive); len(attributes) > 0 {
+		_, _ = fmt.Fprintf(r.Writer(), "{%s}", marshalAttributes(attributes))
+	}
+
+	if directive.HasChildren() {
+		_, _ = r.Writer().Write(markdown.NewLineChar)
+	}
+
+	return ast.WalkContinue, nil
+}
+
+// renderDefaultInline renders the directives in the flow of the text,
+// i.e. :name[content]{...}
+func (mr *MarkdownNodeRenderer) renderDefaultInline(r *markdown.Render, directive *Node, entering bool) (ast.WalkStatus, error) {
+	attributes := stringAttributes(directive)
+
+	if entering {
+		_, _ = fmt.Fprintf(r.Writer(), ":%s", directive.DirectiveType())
+
+		if directive.HasLabel() {
+			_, _ = r.Writer().Write([]byte("["))
+			return ast.WalkContinue, nil
+		}
+
+		_, _ = fmt.Fprintf(r.Writer(), "{%s}", marshalAttributes(attributes))
+
+		return ast.WalkSkipChildren, nil
+	}
+
+	_, _ = r.Writer().Write([]byte("]"))
+
+	if len(attributes) > 0 {
+		_, _ = fmt.Fprintf(r.Writer(), "{%s}", marshalAttributes(attributes))
+	}
+
+	return ast.WalkContinue, nil
+}
+
+// stringAttributes returns the attributes of the directive
+// written in the document, the transformers being able to
+// associate other values to the directive
+func stringAttributes(directive *Node) []ast.Attribute {
+	return slices.DeleteFunc(slices.Clone(directive.Attributes()), func(attr ast.Attribute) bool {
+		_, ok := attr.Value.(string)
+		return !ok
+	})
+}
+
 func marshalAttributes(attributes []ast.Attribute) string {
 	var sb strings.Builder
 
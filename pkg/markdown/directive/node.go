@@ -1,6 +1,10 @@
 package directive
 
 import (
+	"fmt"
+	"strconv"
+	"strings"
+
 	"github.com/yuin/goldmark/ast"
 	"github.com/yuin/goldmark/parser"
 	"github.com/yuin/goldmark/text"
@@ -9,10 +13,24 @@ import (
 type Type string
 
 type Node struct {
-	ast.BaseInline
+	ast.BaseBlock
 	directiveType Type
 
 	value *ast.Text
+
+	// fence is the number of colons opening a container directive,
+	// 0 for the leaf directives
+	fence int
+
+	// hasLabel is true if the directive has a [content],
+	// its children being the content
+	hasLabel bool
+
+	// label is the segment of the content between brackets
+	label text.Segment
+
+	// start is the offset of the directive in the source
+	start int
 }
 
 var KindDirective = ast.NewNodeKind("Directive")
@@ -31,10 +49,91 @@ func (n *Node) Kind() ast.NodeKind {
 	return KindDirective
 }
 
+// Type implements ast.Node.
+func (n *Node) Type() ast.NodeType {
+	if n.IsContainer() {
+		return ast.TypeBlock
+	}
+
+	return ast.TypeInline
+}
+
+// IsContainer returns true if the directive is a container
+// directive (:::name{...}), its body being its children
+func (n *Node) IsContainer() bool {
+	return n.fence > 0
+}
+
+// HasLabel returns true if the directive has a content
+// between brackets (:name[content]), its children being
+// the content
+func (n *Node) HasLabel() bool {
+	return n.hasLabel
+}
+
+// Label returns the content between brackets
+// of the directive as written in the given source
+func (n *Node) Label(source []byte) []byte {
+	return n.label.Value(source)
+}
+
+// Position returns the line and the column, in runes,
+// of the directive in the given source
+func (n *Node) Position(source []byte) (int, int) {
+	return position(source, n.start)
+}
+
+// Clone returns a leaf directive with the type, the position
+// and the string attributes of the directive, without its children
+func (n *Node) Clone() *Node {
+	clone := &Node{
+		directiveType: n.directiveType,
+		value:         n.value,
+		start:         n.start,
+	}
+
+	for _, attr := range n.Attributes() {
+		if value, ok := attr.Value.(string); ok {
+			clone.SetAttribute(attr.Name, value)
+		}
+	}
+
+	return clone
+}
+
 func (n *Node) DirectiveType() Type {
 	return n.directiveType
 }
 
+// String returns the directive as written in the document,
+// with its string attributes
+func (n *Node) String() string {
+	var sb strings.Builder
+
+	sb.WriteString(":")
+	sb.WriteString(string(n.directiveType))
+	sb.WriteString("{")
+
+	first := true
+	for _, attr := range n.Attributes() {
+		value, ok := attr.Value.(string)
+		if !ok {
+			continue
+		}
+
+		if !first {
+			sb.WriteString(" ")
+		}
+		first = false
+
+		fmt.Fprintf(&sb, "%s=%s", attr.Name, strconv.Quote(value))
+	}
+
+	sb.WriteString("}")
+
+	return sb.String()
+}
+
 func parseDirective(raw []byte, value *ast.Text) *Node {
 	var (
 		pos           int
@@ -90,16 +189,54 @@ LOOP:
 
 	node := &Node{
 		directiveType: Type(rawName),
-		BaseInline:    ast.BaseInline{},
 		value:         value,
 	}
 
 	for _, attr := range attributes {
-		value := attr.Value.([]byte)
-		node.SetAttribute(attr.Name, string(value))
+		node.SetAttribute(attr.Name, attributeString(attr.Value))
 	}
 
 	return node
 }
 
+// attributeString converts the value of a parsed attribute to a string
+func attributeString(value any) string {
+	if raw, ok := value.([]byte); ok {
+		return string(raw)
+	}
+
+	return fmt.Sprintf("%v", value)
+}
+
 var _ ast.Node = &Node{}
+
+// Hoist replaces the paragraph wrapping the directive node
+// by the node itself, the text around the directive being kept
+// in paragraphs before and after it
+func Hoist(node ast.Node) {
+	parent := node.Parent()
+	if parent == nil || parent.Kind() != ast.KindParagraph {
+		return
+	}
+
+	grandparent := parent.Parent()
+
+	after := ast.NewParagraph()
+	for sibling := node.NextSibling(); sibling != nil; {
+		next := sibling.NextSibling()
+		after.AppendChild(after, sibling)
+		sibling = next
+	}
+
+	parent.RemoveChild(parent, node)
+
+	if parent.HasChildren() {
+		grandparent.InsertAfter(grandparent, parent, node)
+	} else {
+		grandparent.ReplaceChild(grandparent, parent, node)
+	}
+
+	if after.HasChildren() {
+		grandparent.InsertAfter(grandparent, node, after)
+	}
+}
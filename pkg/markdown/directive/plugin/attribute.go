@@ -0,0 +1,54 @@
+package plugin
+
+import (
+	"github.com/pkg/errors"
+	"github.com/yuin/goldmark/ast"
+)
+
+const (
+	attrOutputNode   = "pluginOutputNode"
+	attrOutputSource = "pluginOutputSource"
+	attrOutputInline = "pluginOutputInline"
+)
+
+func setOutput(n ast.Node, source []byte, node ast.Node, inline bool) {
+	n.SetAttributeString(attrOutputSource, source)
+	n.SetAttributeString(attrOutputNode, node)
+	n.SetAttributeString(attrOutputInline, inline)
+}
+
+// hasOutput returns true if the output of the plugin
+// is already associated with the directive
+func hasOutput(n ast.Node) bool {
+	_, exists := n.AttributeString(attrOutputNode)
+	return exists
+}
+
+// getOutput returns the source and the node of the output of the plugin
+// associated with the directive and whether it is in the flow of the text,
+// the node being a paragraph whose children are the output in this case
+func getOutput(n ast.Node) ([]byte, ast.Node, bool, error) {
+	rawSource, exists := n.AttributeString(attrOutputSource)
+	if !exists {
+		return nil, nil, false, errors.New("could not find source associated with plugin directive")
+	}
+
+	rawNode, exists := n.AttributeString(attrOutputNode)
+	if !exists {
+		return nil, nil, false, errors.New("could not find node associated with plugin directive")
+	}
+
+	source, ok := rawSource.([]byte)
+	if !ok {
+		return nil, nil, false, errors.Errorf("unexpected source type '%T'", rawSource)
+	}
+
+	node, ok := rawNode.(ast.Node)
+	if !ok {
+		return nil, nil, false, errors.Errorf("unexpected node type '%T'", rawNode)
+	}
+
+	inline, _ := n.AttributeString(attrOutputInline)
+
+	return source, node, inline == true, nil
+}
@@ -0,0 +1,135 @@
+package plugin
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/Bornholm/amatl/pkg/markdown/directive"
+	"github.com/pkg/errors"
+)
+
+// Request is written as JSON on the standard input of the plugin executable
+type Request struct {
+	// Type is the type of the directive
+	Type string `json:"type"`
+	// Attributes are the attributes of the directive
+	Attributes map[string]string `json:"attributes"`
+	// Label is the plain text of the [content] of the directive, if any
+	Label string `json:"label,omitempty"`
+	// Position is the position of the directive in its document
+	Position Position `json:"position"`
+	// Meta is the YAML front matter of the document of the directive
+	Meta map[string]any `json:"meta"`
+}
+
+type Position struct {
+	Path   string `json:"path"`
+	Line   int    `json:"line"`
+	Column int    `json:"column"`
+}
+
+// Response is read as JSON on the standard output of the plugin executable,
+// with either the Markdown or the HTML replacing the directive
+type Response struct {
+	Markdown string `json:"markdown,omitempty"`
+	HTML     string `json:"html,omitempty"`
+}
+
+// Plugin executes an external executable to process directives
+type Plugin struct {
+	opts *Options
+}
+
+// Call executes the plugin with the given request
+func (p *Plugin) Call(ctx context.Context, req *Request) (*Response, error) {
+	payload, err := json.Marshal(req)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	timeoutCtx, cancel := context.WithTimeout(ctx, p.opts.Timeout)
+	defer cancel()
+
+	var stdout, stderr bytes.Buffer
+
+	cmd := exec.CommandContext(timeoutCtx, p.opts.ExecPath, p.opts.Args...)
+	cmd.Stdin = bytes.NewReader(payload)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		if message := strings.TrimSpace(stderr.String()); message != "" {
+			return nil, errors.Wrapf(err, "could not execute '%s': %s", p.opts.ExecPath, message)
+		}
+
+		return nil, errors.Wrapf(err, "could not execute '%s'", p.opts.ExecPath)
+	}
+
+	var res Response
+
+	if err := json.Unmarshal(stdout.Bytes(), &res); err != nil {
+		return nil, errors.Wrapf(err, "could not decode response of '%s'", p.opts.ExecPath)
+	}
+
+	if res.Markdown != "" && res.HTML != "" {
+		return nil, errors.Errorf("unexpected response of '%s', expected either markdown or html", p.opts.ExecPath)
+	}
+
+	return &res, nil
+}
+
+// NewDefinition returns the definition of the directives
+// processed by the given plugin
+func NewDefinition(plugin *Plugin) directive.Definition {
+	return directive.Definition{
+		Transformer:      &NodeTransformer{Plugin: plugin},
+		Renderer:         &NodeRenderer{},
+		MarkdownRenderer: &MarkdownRenderer{},
+	}
+}
+
+func NewPlugin(execPath string, funcs ...OptionFunc) *Plugin {
+	opts := NewOptions(funcs...)
+	opts.ExecPath = execPath
+
+	return &Plugin{
+		opts: opts,
+	}
+}
+
+type Options struct {
+	ExecPath string
+	Args     []string
+	// Timeout is the maximum duration of the execution of the plugin
+	Timeout time.Duration
+}
+
+type OptionFunc func(opts *Options)
+
+const DefaultTimeout = 30 * time.Second
+
+func NewOptions(funcs ...OptionFunc) *Options {
+	opts := &Options{
+		Timeout: DefaultTimeout,
+	}
+	for _, fn := range funcs {
+		fn(opts)
+	}
+	return opts
+}
+
+func WithArgs(args ...string) OptionFunc {
+	return func(opts *Options) {
+		opts.Args = args
+	}
+}
+
+func WithTimeout(timeout time.Duration) OptionFunc {
+	return func(opts *Options) {
+		opts.Timeout = timeout
+	}
+}
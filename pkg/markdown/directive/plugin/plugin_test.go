@@ -0,0 +1,248 @@
+package plugin
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/Bornholm/amatl/pkg/markdown/alert"
+	"github.com/Bornholm/amatl/pkg/markdown/directive"
+	"github.com/Bornholm/amatl/pkg/markdown/renderer/markdown"
+	"github.com/Bornholm/amatl/pkg/markdown/renderer/markdown/node"
+	"github.com/Bornholm/amatl/pkg/pipeline"
+	"github.com/pkg/errors"
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/renderer"
+	"github.com/yuin/goldmark/renderer/html"
+	"github.com/yuin/goldmark/text"
+	"github.com/yuin/goldmark/util"
+	"go.abhg.dev/goldmark/frontmatter"
+)
+
+const envTestPlugin = "AMATL_TEST_PLUGIN"
+
+// TestMain runs the test binary as the plugin executable
+// when the AMATL_TEST_PLUGIN environment variable is set
+func TestMain(m *testing.M) {
+	if os.Getenv(envTestPlugin) != "" {
+		runTestPlugin()
+		return
+	}
+
+	os.Exit(m.Run())
+}
+
+func runTestPlugin() {
+	var req Request
+
+	if err := json.NewDecoder(os.Stdin).Decode(&req); err != nil {
+		fmt.Fprintf(os.Stderr, "%+v", err)
+		os.Exit(1)
+	}
+
+	var res Response
+
+	switch req.Attributes["mode"] {
+	case "block":
+		res.Markdown = fmt.Sprintf("## Ticket %s\n\nOpened in %q.\n", req.Attributes["id"], req.Meta["title"])
+	case "alert":
+		res.Markdown = fmt.Sprintf("> [!NOTE]\n> Ticket %s of %q\n", req.Attributes["id"], req.Meta["title"])
+	case "html":
+		res.HTML = fmt.Sprintf("<span class=\"ticket\">%s</span>", req.Attributes["id"])
+	case "fail":
+		fmt.Fprint(os.Stderr, "unknown ticket")
+		os.Exit(1)
+	default:
+		res.Markdown = fmt.Sprintf("**%s** (%s, %s:%d:%d)", req.Attributes["id"], req.Label, req.Position.Path, req.Position.Line, req.Position.Column)
+	}
+
+	if err := json.NewEncoder(os.Stdout).Encode(res); err != nil {
+		os.Exit(1)
+	}
+}
+
+func TestPlugin(t *testing.T) {
+	t.Setenv(envTestPlugin, "1")
+
+	execPath, err := os.Executable()
+	if err != nil {
+		t.Fatalf("%+v", errors.WithStack(err))
+	}
+
+	registry := directive.NewRegistry()
+	registry.Register("ticket", NewDefinition(NewPlugin(execPath)))
+
+	source := strings.Join([]string{
+		"---",
+		"title: Roadmap",
+		"---",
+		"",
+		"See :ticket[the bug]{id=\"AM-42\"} now.",
+		"",
+		":ticket{id=\"AM-1\" mode=\"block\"}",
+		"",
+		"Raw :ticket{id=\"AM-2\" mode=\"html\"}.",
+		"",
+	}, "\n")
+
+	markdownHTML := goldmark.New(
+		goldmark.WithExtensions(
+			&frontmatter.Extender{},
+		),
+		goldmark.WithParserOptions(
+			parser.WithInlineParsers(
+				util.Prioritized(&directive.InlineParser{}, 0),
+			),
+			parser.WithASTTransformers(
+				util.Prioritized(directive.NewTransformer(registry.TransformerOptions()...), 0),
+			),
+		),
+		goldmark.WithRendererOptions(
+			html.WithUnsafe(),
+			renderer.WithNodeRenderers(
+				util.Prioritized(directive.NewRenderer(registry.RendererOptions()...), 0),
+			),
+		),
+	)
+
+	pc := parser.NewContext()
+	pc = pipeline.WithContext(context.Background(), pc)
+	pc = directive.WithSourcePath(pc, "roadmap.md")
+
+	document := markdownHTML.Parser().Parse(text.NewReader([]byte(source)), parser.WithContext(pc))
+
+	var buff bytes.Buffer
+
+	if err := markdownHTML.Renderer().Render(&buff, []byte(source), document); err != nil {
+		t.Fatalf("%+v", errors.WithStack(err))
+	}
+
+	expectedHTML := strings.Join([]string{
+		"<p>See <strong>AM-42</strong> (the bug, roadmap.md:5:5) now.</p>",
+		"<h2>Ticket AM-1</h2>",
+		"<p>Opened in &quot;Roadmap&quot;.</p>",
+		"<p>Raw <span class=\"ticket\">AM-2</span>.</p>",
+		"",
+	}, "\n")
+
+	if e, g := expectedHTML, buff.String(); e != g {
+		t.Errorf("expected '%s', got '%s'", e, g)
+	}
+
+	render := markdown.NewRenderer()
+	render.AddOptions(
+		markdown.WithNodeRenderers(node.Renderers()),
+		markdown.WithNodeRenderer(directive.KindDirective, directive.NewMarkdownNodeRenderer(registry.MarkdownNodeRendererOptions()...)),
+	)
+
+	buff.Reset()
+
+	if err := render.Render(&buff, []byte(source), document); err != nil {
+		t.Fatalf("%+v", errors.WithStack(err))
+	}
+
+	expectedMarkdown := strings.Join([]string{
+		"See **AM-42** (the bug, roadmap.md:5:5) now.",
+		"",
+		"## Ticket AM-1",
+		"",
+		"Opened in \"Roadmap\".",
+		"",
+		"Raw <span class=\"ticket\">AM-2</span>.",
+	}, "\n")
+
+	if e, g := expectedMarkdown, strings.TrimSpace(buff.String()); e != g {
+		t.Errorf("expected '%s', got '%s'", e, g)
+	}
+}
+
+func TestPluginDocumentParser(t *testing.T) {
+	t.Setenv(envTestPlugin, "1")
+
+	execPath, err := os.Executable()
+	if err != nil {
+		t.Fatalf("%+v", errors.WithStack(err))
+	}
+
+	registry := directive.NewRegistry()
+	registry.Register("ticket", NewDefinition(NewPlugin(execPath)))
+
+	markdownHTML := goldmark.New(
+		goldmark.WithExtensions(
+			&frontmatter.Extender{},
+			&alert.Extender{},
+		),
+		goldmark.WithParserOptions(
+			parser.WithInlineParsers(
+				util.Prioritized(&directive.InlineParser{}, 0),
+			),
+			parser.WithASTTransformers(
+				util.Prioritized(directive.NewTransformer(registry.TransformerOptions()...), 0),
+			),
+		),
+	)
+
+	rootSource := []byte("---\ntitle: Roadmap\n---\n\n# Roadmap\n")
+
+	rootPC := parser.NewContext()
+	rootPC = pipeline.WithContext(context.Background(), rootPC)
+	rootPC = directive.WithParser(rootPC, markdownHTML.Parser())
+
+	markdownHTML.Parser().Parse(text.NewReader(rootSource), parser.WithContext(rootPC))
+
+	// The directive is in a document included by the root document
+	source := []byte("---\ntitle: Milestone\n---\n\n:ticket{id=\"AM-1\" mode=\"alert\"}\n")
+
+	pc := parser.NewContext()
+	pc = pipeline.WithContext(context.Background(), pc)
+	pc = directive.WithRootContext(pc, rootPC)
+
+	document := markdownHTML.Parser().Parse(text.NewReader(source), parser.WithContext(pc))
+
+	render := markdown.NewRenderer()
+	render.AddOptions(
+		markdown.WithNodeRenderers(node.Renderers()),
+		markdown.WithNodeRenderer(directive.KindDirective, directive.NewMarkdownNodeRenderer(registry.MarkdownNodeRendererOptions()...)),
+	)
+
+	var buff bytes.Buffer
+
+	if err := render.Render(&buff, source, document); err != nil {
+		t.Fatalf("%+v", errors.WithStack(err))
+	}
+
+	expectedMarkdown := strings.Join([]string{
+		"> [!NOTE]",
+		"> Ticket AM-1 of \"Roadmap\"",
+	}, "\n")
+
+	if e, g := expectedMarkdown, strings.TrimSpace(buff.String()); e != g {
+		t.Errorf("expected '%s', got '%s'", e, g)
+	}
+}
+
+func TestPluginError(t *testing.T) {
+	t.Setenv(envTestPlugin, "1")
+
+	execPath, err := os.Executable()
+	if err != nil {
+		t.Fatalf("%+v", errors.WithStack(err))
+	}
+
+	_, err = NewPlugin(execPath).Call(context.Background(), &Request{
+		Type:       "ticket",
+		Attributes: map[string]string{"mode": "fail"},
+	})
+	if err == nil {
+		t.Fatal("expected error")
+	}
+
+	if !strings.Contains(err.Error(), "unknown ticket") {
+		t.Errorf("expected error to contain the plugin message, got '%s'", err.Error())
+	}
+}
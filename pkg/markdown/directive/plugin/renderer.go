@@ -0,0 +1,113 @@
+package plugin
+
+import (
+	"bytes"
+
+	"github.com/Bornholm/amatl/pkg/markdown/directive"
+	"github.com/Bornholm/amatl/pkg/markdown/renderer/markdown"
+	"github.com/pkg/errors"
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/extension"
+	"github.com/yuin/goldmark/renderer"
+	"github.com/yuin/goldmark/renderer/html"
+	"github.com/yuin/goldmark/util"
+)
+
+// NodeRenderer renders the output of the plugins as HTML
+type NodeRenderer struct {
+	// Renderer renders the output of the plugins, a CommonMark
+	// renderer with the GFM extensions being used if nil
+	Renderer renderer.Renderer
+}
+
+// Render implements directive.NodeRenderer.
+func (r *NodeRenderer) Render(writer util.BufWriter, source []byte, node *directive.Node) {
+	output, outputNode, inline, err := getOutput(node)
+	if err != nil {
+		panic(errors.WithStack(err))
+	}
+
+	render := r.Renderer
+	if render == nil {
+		render = defaultRenderer()
+	}
+
+	var buff bytes.Buffer
+
+	for _, n := range outputNodes(outputNode, inline) {
+		if err := render.Render(&buff, output, n); err != nil {
+			panic(errors.Wrap(err, "could not render plugin output"))
+		}
+	}
+
+	if _, err := writer.Write(buff.Bytes()); err != nil {
+		panic(errors.WithStack(err))
+	}
+}
+
+var _ directive.NodeRenderer = &NodeRenderer{}
+
+func defaultRenderer() renderer.Renderer {
+	return goldmark.New(
+		goldmark.WithExtensions(
+			extension.GFM,
+			extension.Footnote,
+			extension.DefinitionList,
+		),
+		goldmark.WithRendererOptions(
+			html.WithUnsafe(),
+		),
+	).Renderer()
+}
+
+// MarkdownRenderer renders the output of the plugins as Markdown
+type MarkdownRenderer struct{}
+
+// Render implements directive.MarkdownDirectiveRenderer.
+func (mr *MarkdownRenderer) Render(r *markdown.Render, node *directive.Node, entering bool) (ast.WalkStatus, error) {
+	if !entering {
+		return ast.WalkContinue, nil
+	}
+
+	output, outputNode, inline, err := getOutput(node)
+	if err != nil {
+		return ast.WalkStop, errors.WithStack(err)
+	}
+
+	var buff bytes.Buffer
+
+	for _, n := range outputNodes(outputNode, inline) {
+		if err := r.Renderer().Render(&buff, output, n); err != nil {
+			return ast.WalkStop, errors.Wrap(err, "could not render plugin output")
+		}
+	}
+
+	if !inline {
+		_, _ = r.Writer().Write(markdown.NewLineChar)
+		_, _ = r.Writer().Write(markdown.NewLineChar)
+	}
+
+	if _, err := r.Writer().Write(bytes.TrimRight(buff.Bytes(), "\n")); err != nil {
+		return ast.WalkStop, errors.WithStack(err)
+	}
+
+	return ast.WalkContinue, nil
+}
+
+var _ directive.MarkdownDirectiveRenderer = &MarkdownRenderer{}
+
+// outputNodes returns the nodes to render, i.e. the children
+// of the paragraph of the output in the flow of the text
+func outputNodes(node ast.Node, inline bool) []ast.Node {
+	if !inline {
+		return []ast.Node{node}
+	}
+
+	nodes := make([]ast.Node, 0, node.ChildCount())
+	for child := node.FirstChild(); child != nil; child = child.NextSibling() {
+		nodes = append(nodes, child)
+	}
+
+	return nodes
+}
@@ -0,0 +1,204 @@
+package plugin
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+
+	"github.com/Bornholm/amatl/pkg/markdown/directive"
+	"github.com/Bornholm/amatl/pkg/pipeline"
+	"github.com/pkg/errors"
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/extension"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/text"
+	"go.abhg.dev/goldmark/frontmatter"
+)
+
+// NodeTransformer replaces the directives by the output of
+// a plugin, parsed and spliced into the document
+type NodeTransformer struct {
+	Plugin *Plugin
+	// Parser parses the Markdown output of the plugin if no parser is
+	// associated with the parser context of the root document, a CommonMark
+	// parser with the GFM extensions being used if nil
+	Parser parser.Parser
+}
+
+// Transform implements directive.NodeTransformer.
+func (t *NodeTransformer) Transform(node *directive.Node, reader text.Reader, pc parser.Context) error {
+	// The hoisted directives are visited again by the transformer
+	if hasOutput(node) {
+		return nil
+	}
+
+	ctx, err := pipeline.FromParserContext(pc)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	req, err := newRequest(node, reader.Source(), pc)
+	if err != nil {
+		return errors.Wrapf(err, "could not prepare request for directive '%s'", node.DirectiveType())
+	}
+
+	res, err := t.Plugin.Call(ctx, req)
+	if err != nil {
+		return errors.Wrapf(err, "could not process directive '%s' at %s:%d:%d", node.DirectiveType(), req.Position.Path, req.Position.Line, req.Position.Column)
+	}
+
+	inline := isInline(node)
+
+	var (
+		output     []byte
+		outputNode ast.Node
+	)
+
+	if res.HTML != "" {
+		output, outputNode = newHTMLNode([]byte(res.HTML), inline)
+	} else {
+		output = []byte(res.Markdown)
+		outputNode = t.parse(ctx, output, pc)
+
+		// Markdown rendered as a single paragraph is kept in the flow of the text
+		if paragraph, ok := outputNode.FirstChild().(*ast.Paragraph); !ok || outputNode.ChildCount() != 1 {
+			inline = false
+		} else if inline {
+			outputNode = paragraph
+		}
+	}
+
+	// The label is replaced by the output
+	node.RemoveChildren(node)
+
+	setOutput(node, output, outputNode, inline)
+
+	if !inline {
+		directive.Hoist(node)
+	}
+
+	return nil
+}
+
+// parse parses the Markdown output of the plugin with the parser of the
+// document, in a parser context sharing its diagnostics and its root document
+func (t *NodeTransformer) parse(ctx context.Context, output []byte, pc parser.Context) ast.Node {
+	root := directive.RootContext(pc)
+
+	outputPC := pipeline.WithContext(ctx, parser.NewContext())
+	directive.WithRootContext(outputPC, root)
+	directive.WithSourcePath(outputPC, directive.SourcePathFromContext(pc))
+
+	if diagnostics, ok := directive.DiagnosticsFromContext(pc); ok {
+		directive.WithDiagnostics(outputPC, diagnostics)
+	}
+
+	return t.getParser(root).Parse(text.NewReader(output), parser.WithContext(outputPC))
+}
+
+func (t *NodeTransformer) getParser(pc parser.Context) parser.Parser {
+	if p, ok := directive.ParserFromContext(pc); ok {
+		return p
+	}
+
+	if t.Parser != nil {
+		return t.Parser
+	}
+
+	return defaultParser()
+}
+
+var _ directive.NodeTransformer = &NodeTransformer{}
+
+func defaultParser() parser.Parser {
+	return goldmark.New(
+		goldmark.WithExtensions(
+			extension.GFM,
+			extension.Footnote,
+			extension.DefinitionList,
+		),
+	).Parser()
+}
+
+func newRequest(node *directive.Node, source []byte, pc parser.Context) (*Request, error) {
+	line, column := node.Position(source)
+
+	req := &Request{
+		Type:       string(node.DirectiveType()),
+		Attributes: make(map[string]string),
+		Position: Position{
+			Path:   directive.SourcePathFromContext(pc),
+			Line:   line,
+			Column: column,
+		},
+		Meta: make(map[string]any),
+	}
+
+	for _, attr := range node.Attributes() {
+		switch value := attr.Value.(type) {
+		case string:
+			req.Attributes[string(attr.Name)] = value
+		case []byte:
+			req.Attributes[string(attr.Name)] = string(value)
+		default:
+			req.Attributes[string(attr.Name)] = fmt.Sprintf("%v", value)
+		}
+	}
+
+	if node.HasLabel() {
+		req.Label = string(node.Label(source))
+	}
+
+	// The metadata are the ones of the root document,
+	// the directive being possibly in an included document
+	if data := frontmatter.Get(directive.RootContext(pc)); data != nil {
+		if err := data.Decode(&req.Meta); err != nil {
+			return nil, errors.Wrap(err, "could not decode front matter")
+		}
+	}
+
+	return req, nil
+}
+
+// isInline returns true if the directive is written
+// in the flow of the text of a paragraph
+func isInline(node ast.Node) bool {
+	parent := node.Parent()
+	if parent == nil || parent.Kind() != ast.KindParagraph {
+		return false
+	}
+
+	return node.PreviousSibling() != nil || node.NextSibling() != nil
+}
+
+// newHTMLNode returns the node holding the given HTML and its source,
+// as raw HTML in a paragraph if inline or as an HTML block otherwise
+func newHTMLNode(html []byte, inline bool) ([]byte, ast.Node) {
+	source := bytes.TrimRight(html, "\n")
+
+	if inline {
+		rawHTML := ast.NewRawHTML()
+		rawHTML.Segments.Append(text.NewSegment(0, len(source)))
+
+		paragraph := ast.NewParagraph()
+		paragraph.AppendChild(paragraph, rawHTML)
+
+		return source, paragraph
+	}
+
+	source = append(source, '\n')
+
+	block := ast.NewHTMLBlock(ast.HTMLBlockType7)
+
+	for start := 0; start < len(source); {
+		end := start + bytes.IndexByte(source[start:], '\n') + 1
+		block.Lines().Append(text.NewSegment(start, end))
+		start = end
+	}
+
+	document := ast.NewDocument()
+	document.AppendChild(document, block)
+
+	return source, document
+}
@@ -0,0 +1,144 @@
+package directive
+
+import (
+	"slices"
+	"sync"
+)
+
+// Definition describes the behaviors of a directive type,
+// each of them being optional
+type Definition struct {
+	// Transformer transforms the directives when parsing the documents
+	Transformer NodeTransformer
+	// Renderer renders the directives as HTML, the directives
+	// without content being ignored if nil
+	Renderer NodeRenderer
+	// MarkdownRenderer renders the directives as Markdown,
+	// the directives being written back as is if nil
+	MarkdownRenderer MarkdownDirectiveRenderer
+}
+
+// Registry stores the definitions of custom directives,
+// used in addition to the built-in directives. It is safe for concurrent use
+type Registry struct {
+	mutex       sync.RWMutex
+	definitions map[Type]Definition
+}
+
+// Register defines the behaviors of the given directive type,
+// replacing the previous definition and the built-in behaviors if any
+func (r *Registry) Register(directiveType Type, definition Definition) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.definitions[directiveType] = definition
+}
+
+// Types returns the sorted registered directive types
+func (r *Registry) Types() []Type {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	types := make([]Type, 0, len(r.definitions))
+	for directiveType := range r.definitions {
+		types = append(types, directiveType)
+	}
+
+	slices.Sort(types)
+
+	return types
+}
+
+// Definition returns the definition of the given directive type, if registered
+func (r *Registry) Definition(directiveType Type) (Definition, bool) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	definition, exists := r.definitions[directiveType]
+
+	return definition, exists
+}
+
+// TransformerOptions returns the options adding the
+// registered directives to a Transformer
+func (r *Registry) TransformerOptions() []TransformerOptionFunc {
+	types := r.Types()
+
+	funcs := []TransformerOptionFunc{
+		WithKnownTypes(types...),
+	}
+
+	for _, directiveType := range types {
+		definition, _ := r.Definition(directiveType)
+		if definition.Transformer != nil {
+			funcs = append(funcs, WithTransformer(directiveType, definition.Transformer))
+		}
+	}
+
+	return funcs
+}
+
+// RendererOptions returns the options adding the
+// registered directives to a Renderer
+func (r *Registry) RendererOptions() []RendererOptionFunc {
+	funcs := []RendererOptionFunc{}
+
+	for _, directiveType := range r.Types() {
+		definition, _ := r.Definition(directiveType)
+		if definition.Renderer != nil {
+			funcs = append(funcs, WithRenderer(directiveType, definition.Renderer))
+		}
+	}
+
+	return funcs
+}
+
+// MarkdownNodeRendererOptions returns the options adding
+// the registered directives to a MarkdownNodeRenderer
+func (r *Registry) MarkdownNodeRendererOptions() []MarkdownNodeRendererOptionFunc {
+	funcs := []MarkdownNodeRendererOptionFunc{}
+
+	for _, directiveType := range r.Types() {
+		definition, _ := r.Definition(directiveType)
+		if definition.MarkdownRenderer != nil {
+			funcs = append(funcs, WithMarkdownDirectiveRenderer(directiveType, definition.MarkdownRenderer))
+		}
+	}
+
+	return funcs
+}
+
+// Extend returns a new registry with the definitions of this
+// registry and the given ones, the given ones taking precedence
+func (r *Registry) Extend(extensions ...func() (Type, Definition)) *Registry {
+	registry := NewRegistry()
+
+	r.mutex.RLock()
+	for directiveType, definition := range r.definitions {
+		registry.Register(directiveType, definition)
+	}
+	r.mutex.RUnlock()
+
+	for _, ext := range extensions {
+		directiveType, definition := ext()
+		registry.Register(directiveType, definition)
+	}
+
+	return registry
+}
+
+func NewRegistry() *Registry {
+	return &Registry{
+		definitions: make(map[Type]Definition),
+	}
+}
+
+// DefaultRegistry is the registry used by the render
+// commands, i.e. when wrapping command.Main
+var DefaultRegistry = NewRegistry()
+
+// Register defines the behaviors of the given
+// directive type in the default registry
+func Register(directiveType Type, definition Definition) {
+	DefaultRegistry.Register(directiveType, definition)
+}
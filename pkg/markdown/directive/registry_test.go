@@ -0,0 +1,102 @@
+package directive
+
+import (
+	"bytes"
+	"fmt"
+	"html"
+	"strings"
+	"testing"
+
+	"github.com/Bornholm/amatl/pkg/markdown/renderer/markdown"
+	"github.com/Bornholm/amatl/pkg/markdown/renderer/markdown/node"
+	"github.com/pkg/errors"
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/renderer"
+	"github.com/yuin/goldmark/text"
+	"github.com/yuin/goldmark/util"
+)
+
+func TestRegistry(t *testing.T) {
+	registry := NewRegistry()
+
+	registry.Register("jira", Definition{
+		Transformer: NodeTransformerFunc(func(node *Node, reader text.Reader, pc parser.Context) error {
+			id, _ := node.AttributeString("id")
+			node.SetAttributeString("href", fmt.Sprintf("https://jira.example.com/browse/%s", id))
+			return nil
+		}),
+		Renderer: NodeRendererFunc(func(writer util.BufWriter, source []byte, node *Node) {
+			id, _ := node.AttributeString("id")
+			href, _ := node.AttributeString("href")
+			_, _ = fmt.Fprintf(writer, `<a href="%s">%s</a>`, html.EscapeString(href.(string)), html.EscapeString(fmt.Sprintf("%s", id)))
+		}),
+		MarkdownRenderer: MarkdownDirectiveRendererFunc(func(r *markdown.Render, directive *Node, entering bool) (ast.WalkStatus, error) {
+			id, _ := directive.AttributeString("id")
+			_, _ = fmt.Fprintf(r.Writer(), "[%s](https://jira.example.com/browse/%s)", id, id)
+			return ast.WalkContinue, nil
+		}),
+	})
+
+	registry.Register("owner", Definition{})
+
+	if e, g := []Type{"jira", "owner"}, registry.Types(); fmt.Sprint(e) != fmt.Sprint(g) {
+		t.Errorf("registry.Types(): expected '%v', got '%v'", e, g)
+	}
+
+	source := []byte("See :jira{id=\"AM-42\"}, owned by :owner{team=\"docs\"}.\n")
+
+	diagnostics := NewDiagnostics()
+
+	pc := parser.NewContext()
+	pc = WithDiagnostics(pc, diagnostics)
+
+	markdownHTML := goldmark.New(
+		goldmark.WithParserOptions(
+			parser.WithInlineParsers(
+				util.Prioritized(&InlineParser{}, 0),
+			),
+			parser.WithASTTransformers(
+				util.Prioritized(NewTransformer(registry.TransformerOptions()...), 0),
+			),
+		),
+		goldmark.WithRendererOptions(
+			renderer.WithNodeRenderers(
+				util.Prioritized(NewRenderer(registry.RendererOptions()...), 0),
+			),
+		),
+	)
+
+	var buff bytes.Buffer
+
+	if err := markdownHTML.Convert(source, &buff, parser.WithContext(pc)); err != nil {
+		t.Fatalf("%+v", errors.WithStack(err))
+	}
+
+	if e, g := "<p>See <a href=\"https://jira.example.com/browse/AM-42\">AM-42</a>, owned by .</p>\n", buff.String(); e != g {
+		t.Errorf("expected '%s', got '%s'", e, g)
+	}
+
+	if all := diagnostics.All(); len(all) != 0 {
+		t.Errorf("expected no diagnostics, got '%v'", all)
+	}
+
+	document := markdownHTML.Parser().Parse(text.NewReader(source))
+
+	render := markdown.NewRenderer()
+	render.AddOptions(
+		markdown.WithNodeRenderers(node.Renderers()),
+		markdown.WithNodeRenderer(KindDirective, NewMarkdownNodeRenderer(registry.MarkdownNodeRendererOptions()...)),
+	)
+
+	buff.Reset()
+
+	if err := render.Render(&buff, source, document); err != nil {
+		t.Fatalf("%+v", errors.WithStack(err))
+	}
+
+	if e, g := "See [AM-42](https://jira.example.com/browse/AM-42), owned by :owner{team=\"docs\"}.", strings.TrimSpace(buff.String()); e != g {
+		t.Errorf("expected '%s', got '%s'", e, g)
+	}
+}
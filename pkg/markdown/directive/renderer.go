@@ -1,10 +1,13 @@
 package directive
 
 import (
+	"bytes"
 	"fmt"
+	"html"
 
 	"github.com/yuin/goldmark/ast"
 	"github.com/yuin/goldmark/renderer"
+	goldmarkHTML "github.com/yuin/goldmark/renderer/html"
 	"github.com/yuin/goldmark/util"
 )
 
@@ -12,6 +15,14 @@ type NodeRenderer interface {
 	Render(writer util.BufWriter, source []byte, node *Node)
 }
 
+// ContentNodeRenderer is a NodeRenderer also rendering the end of the
+// directives with content (container directives and :name[content]),
+// after their content
+type ContentNodeRenderer interface {
+	NodeRenderer
+	RenderExit(writer util.BufWriter, source []byte, node *Node)
+}
+
 type nodeRenderer struct {
 	render NodeRendererFunc
 }
@@ -22,22 +33,43 @@ func (t *nodeRenderer) Render(writer util.BufWriter, source []byte, node *Node)
 
 type NodeRendererFunc func(writer util.BufWriter, source []byte, node *Node)
 
+// Render implements NodeRenderer.
+func (fn NodeRendererFunc) Render(writer util.BufWriter, source []byte, node *Node) {
+	fn(writer, source, node)
+}
+
 type Renderer struct {
 	renderers map[Type]NodeRenderer
 }
 
 // Render implements renderer.Renderer.
 func (r *Renderer) Render(writer util.BufWriter, source []byte, node ast.Node, entering bool) (ast.WalkStatus, error) {
-	if !entering {
-		return ast.WalkContinue, nil
-	}
-
 	directive, ok := node.(*Node)
 	if !ok {
 		return ast.WalkStop, fmt.Errorf("unexpected node %T, expected *directive.Node", node)
 	}
 
 	renderer, exists := r.renderers[directive.DirectiveType()]
+
+	if directive.IsContainer() || directive.HasLabel() {
+		if !exists {
+			r.renderDefault(writer, directive, entering)
+			return ast.WalkContinue, nil
+		}
+
+		if entering {
+			renderer.Render(writer, source, directive)
+		} else if contentRenderer, ok := renderer.(ContentNodeRenderer); ok {
+			contentRenderer.RenderExit(writer, source, directive)
+		}
+
+		return ast.WalkContinue, nil
+	}
+
+	if !entering {
+		return ast.WalkContinue, nil
+	}
+
 	if !exists {
 		return ast.WalkSkipChildren, nil
 	}
@@ -47,8 +79,85 @@ func (r *Renderer) Render(writer util.BufWriter, source []byte, node ast.Node, e
 	return ast.WalkContinue, nil
 }
 
+// renderDefault renders the directives with content without renderer
+// as a <div> (container directives) or a <span> with the
+// amatl-directive-<name> class and their global HTML attributes,
+// the other attributes being prefixed with data-
+func (r *Renderer) renderDefault(writer util.BufWriter, directive *Node, entering bool) {
+	element, newline := "span", ""
+	if directive.IsContainer() {
+		element, newline = "div", "\n"
+	}
+
+	if !entering {
+		_, _ = fmt.Fprintf(writer, "</%s>%s", element, newline)
+		return
+	}
+
+	class := "amatl-directive amatl-directive-" + string(directive.DirectiveType())
+
+	_, _ = fmt.Fprintf(writer, "<%s", element)
+
+	for _, attr := range directive.Attributes() {
+		value := fmt.Sprintf("%v", attr.Value)
+
+		switch {
+		case bytes.Equal(attr.Name, []byte("class")):
+			class += " " + value
+			continue
+		case goldmarkHTML.GlobalAttributeFilter.Contains(attr.Name), bytes.HasPrefix(attr.Name, []byte("data-")):
+			_, _ = fmt.Fprintf(writer, ` %s="%s"`, attr.Name, html.EscapeString(value))
+		default:
+			_, _ = fmt.Fprintf(writer, ` data-%s="%s"`, attr.Name, html.EscapeString(value))
+		}
+	}
+
+	_, _ = fmt.Fprintf(writer, ` class="%s">%s`, html.EscapeString(class), newline)
+}
+
+// renderParagraph renders the paragraphs as the default HTML renderer,
+// omitting the ones containing only directives rendering nothing
+func (r *Renderer) renderParagraph(writer util.BufWriter, source []byte, node ast.Node, entering bool) (ast.WalkStatus, error) {
+	if r.isEmpty(source, node) {
+		return ast.WalkSkipChildren, nil
+	}
+
+	if !entering {
+		_, _ = writer.WriteString("</p>\n")
+		return ast.WalkContinue, nil
+	}
+
+	_, _ = writer.WriteString("<p")
+	goldmarkHTML.RenderAttributes(writer, node, goldmarkHTML.ParagraphAttributeFilter)
+	_ = writer.WriteByte('>')
+
+	return ast.WalkContinue, nil
+}
+
+// isEmpty returns true if the paragraph contains only blank
+// text and directives without content nor renderer
+func (r *Renderer) isEmpty(source []byte, paragraph ast.Node) bool {
+	for child := paragraph.FirstChild(); child != nil; child = child.NextSibling() {
+		switch node := child.(type) {
+		case *Node:
+			if _, exists := r.renderers[node.DirectiveType()]; exists || node.IsContainer() || node.HasLabel() {
+				return false
+			}
+		case *ast.Text:
+			if !util.IsBlank(node.Value(source)) {
+				return false
+			}
+		default:
+			return false
+		}
+	}
+
+	return paragraph.HasChildren()
+}
+
 func (r *Renderer) RegisterFuncs(reg renderer.NodeRendererFuncRegisterer) {
 	reg.Register(KindDirective, r.Render)
+	reg.Register(ast.KindParagraph, r.renderParagraph)
 }
 
 func NewRenderer(funcs ...RendererOptionFunc) *Renderer {
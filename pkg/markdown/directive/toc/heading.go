@@ -0,0 +1,44 @@
+package toc
+
+import (
+	"github.com/pkg/errors"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/text"
+)
+
+// Heading is an entry of the headings tree of a document
+type Heading struct {
+	Level    int
+	Title    string
+	ID       string
+	Children []Heading
+}
+
+// Headings returns the tree of the headings of the given document between
+// the given levels, the same tree the table of contents is built from
+func Headings(doc ast.Node, source []byte, minLevel int, maxLevel int) ([]Heading, error) {
+	tree, err := buildTree(doc, text.NewReader(source), minLevel, maxLevel)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	return toHeadings(tree), nil
+}
+
+func toHeadings(items []*tocItem) []Heading {
+	if len(items) == 0 {
+		return nil
+	}
+
+	headings := make([]Heading, 0, len(items))
+	for _, item := range items {
+		headings = append(headings, Heading{
+			Level:    item.Level,
+			Title:    string(item.Label),
+			ID:       string(item.ID),
+			Children: toHeadings(item.Children),
+		})
+	}
+
+	return headings
+}
@@ -0,0 +1,34 @@
+package toc
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/text"
+)
+
+func TestHeadings(t *testing.T) {
+	source := []byte("# Introduction\n\n## Install\n\n### Linux\n\n## Usage\n\n# Reference\n")
+
+	md := goldmark.New(goldmark.WithParserOptions(parser.WithAutoHeadingID()))
+	doc := md.Parser().Parse(text.NewReader(source))
+
+	headings, err := Headings(doc, source, 1, 2)
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+
+	expected := []Heading{
+		{Level: 1, Title: "Introduction", ID: "introduction", Children: []Heading{
+			{Level: 2, Title: "Install", ID: "install"},
+			{Level: 2, Title: "Usage", ID: "usage"},
+		}},
+		{Level: 1, Title: "Reference", ID: "reference"},
+	}
+
+	if e, g := fmt.Sprintf("%+v", expected), fmt.Sprintf("%+v", headings); e != g {
+		t.Errorf("expected '%s', got '%s'", e, g)
+	}
+}
@@ -93,7 +93,17 @@ func (t *NodeTransformer) PostTransform(doc *ast.Document, reader text.Reader, p
 	return nil
 }
 
-var _ directive.NodeTransformer = &NodeTransformer{}
+// Priority implements directive.PrioritizedNodeTransformer.
+// The table of contents is built after the other post transformations,
+// once the identifiers of the headings are final.
+func (t *NodeTransformer) Priority() int {
+	return 100
+}
+
+var (
+	_ directive.NodeTransformer            = &NodeTransformer{}
+	_ directive.PrioritizedNodeTransformer = &NodeTransformer{}
+)
 
 const attrNameMinLevel = "minLevel"
 
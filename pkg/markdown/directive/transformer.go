@@ -1,6 +1,9 @@
 package directive
 
 import (
+	"cmp"
+	"slices"
+
 	"github.com/pkg/errors"
 	"github.com/yuin/goldmark/ast"
 	"github.com/yuin/goldmark/parser"
@@ -34,8 +37,14 @@ func (t *nodeTransformer) Transform(node *Node, reader text.Reader, pc parser.Co
 
 type NodeTransformerFunc func(node *Node, reader text.Reader, pc parser.Context) error
 
+// Transform implements NodeTransformer.
+func (fn NodeTransformerFunc) Transform(node *Node, reader text.Reader, pc parser.Context) error {
+	return fn(node, reader, pc)
+}
+
 type Transformer struct {
 	transformers map[Type]NodeTransformer
+	knownTypes   []Type
 }
 
 // Transform implements parser.ASTTransformer.
@@ -53,11 +62,22 @@ func (t *Transformer) Transform(doc *ast.Document, reader text.Reader, pc parser
 		directiveType := directive.DirectiveType()
 		transformer, exists := t.transformers[directiveType]
 		if !exists {
+			// The directives with content are rendered even without
+			// specific behavior, the other ones being ignored
+			if !directive.IsContainer() && !directive.HasLabel() && !slices.Contains(t.knownTypes, directiveType) {
+				Report(pc, reader.Source(), directive, "unknown directive '%s'", directiveType)
+			}
+
 			return ast.WalkContinue, nil
 		}
 
 		if err := transformer.Transform(directive, reader, pc); err != nil {
-			return ast.WalkStop, errors.WithStack(err)
+			return ast.WalkStop, errors.Wrapf(err, "could not transform directive '%s'", directive)
+		}
+
+		// The directives in the body of a container are transformed too
+		if directive.IsContainer() {
+			return ast.WalkContinue, nil
 		}
 
 		return ast.WalkSkipChildren, nil
@@ -66,27 +86,60 @@ func (t *Transformer) Transform(doc *ast.Document, reader text.Reader, pc parser
 		panic(errors.WithStack(err))
 	}
 
-	for _, transformer := range t.transformers {
-		postTransformer, ok := transformer.(PostTranformer)
-		if !ok {
-			continue
-		}
-
+	for _, postTransformer := range t.postTransformers() {
 		if err := postTransformer.PostTransform(doc, reader, pc); err != nil {
 			panic(errors.WithStack(err))
 		}
 	}
 }
 
+// postTransformers returns the transformers with a post transformation,
+// sorted by ascending priority then by directive type
+func (t *Transformer) postTransformers() []PostTranformer {
+	types := make([]Type, 0, len(t.transformers))
+	for directiveType, transformer := range t.transformers {
+		if _, ok := transformer.(PostTranformer); ok {
+			types = append(types, directiveType)
+		}
+	}
+
+	slices.SortFunc(types, func(a, b Type) int {
+		return cmp.Or(
+			cmp.Compare(transformerPriority(t.transformers[a]), transformerPriority(t.transformers[b])),
+			cmp.Compare(a, b),
+		)
+	})
+
+	postTransformers := make([]PostTranformer, 0, len(types))
+	for _, directiveType := range types {
+		postTransformers = append(postTransformers, t.transformers[directiveType].(PostTranformer))
+	}
+
+	return postTransformers
+}
+
+func transformerPriority(transformer NodeTransformer) int {
+	if prioritized, ok := transformer.(PrioritizedNodeTransformer); ok {
+		return prioritized.Priority()
+	}
+
+	return 0
+}
+
 func NewTransformer(funcs ...TransformerOptionFunc) *Transformer {
 	opts := NewTransformerOptions(funcs...)
 	return &Transformer{
 		transformers: opts.Transformers,
+		knownTypes:   opts.KnownTypes,
 	}
 }
 
 type TransformerOptions struct {
 	Transformers map[Type]NodeTransformer
+	// KnownTypes are the types of the directives handled
+	// without transformer, i.e. by the renderers or by a
+	// later rendering stage
+	KnownTypes []Type
 }
 
 type TransformerOptionFunc func(opts *TransformerOptions)
@@ -115,4 +168,10 @@ func WithTransformerFunc(directiveType Type, transform NodeTransformerFunc) Tran
 	}
 }
 
+func WithKnownTypes(directiveTypes ...Type) TransformerOptionFunc {
+	return func(opts *TransformerOptions) {
+		opts.KnownTypes = append(opts.KnownTypes, directiveTypes...)
+	}
+}
+
 var _ parser.ASTTransformer = &Transformer{}
@@ -0,0 +1,90 @@
+package math
+
+import (
+	"bytes"
+
+	"github.com/yuin/goldmark/ast"
+)
+
+// KindInline is the node kind of an [Inline] node
+var KindInline = ast.NewNodeKind("MathInline")
+
+// Inline is a formula delimited by $ or, if displayed, by $$ in
+// a paragraph. Its children are the raw text segments of the formula.
+type Inline struct {
+	ast.BaseInline
+	Display bool
+}
+
+// Formula returns the LaTeX source of the formula
+func (n *Inline) Formula(source []byte) []byte {
+	var formula bytes.Buffer
+
+	for c := n.FirstChild(); c != nil; c = c.NextSibling() {
+		if text, ok := c.(*ast.Text); ok {
+			formula.Write(text.Segment.Value(source))
+		}
+	}
+
+	return formula.Bytes()
+}
+
+// Delimiter returns the delimiter of the formula
+func (n *Inline) Delimiter() []byte {
+	if n.Display {
+		return []byte("$$")
+	}
+
+	return []byte("$")
+}
+
+// Kind implements ast.Node.
+func (*Inline) Kind() ast.NodeKind { return KindInline }
+
+// Dump implements ast.Node.
+func (n *Inline) Dump(source []byte, level int) {
+	ast.DumpHelper(n, source, level, map[string]string{
+		"Formula": string(n.Formula(source)),
+	}, nil)
+}
+
+var _ ast.Node = &Inline{}
+
+// KindBlock is the node kind of a [Block] node
+var KindBlock = ast.NewNodeKind("MathBlock")
+
+// Block is a displayed formula delimited by lines containing only $$.
+// Its raw contents are the lines of the formula.
+//
+//	$$
+//	e^{i\pi} + 1 = 0
+//	$$
+type Block struct {
+	ast.BaseBlock
+}
+
+// Formula returns the LaTeX source of the formula
+func (b *Block) Formula(source []byte) []byte {
+	var formula bytes.Buffer
+
+	lines := b.Lines()
+	for i := 0; i < lines.Len(); i++ {
+		line := lines.At(i)
+		formula.Write(line.Value(source))
+	}
+
+	return formula.Bytes()
+}
+
+// IsRaw implements ast.Node.
+func (*Block) IsRaw() bool { return true }
+
+// Kind implements ast.Node.
+func (*Block) Kind() ast.NodeKind { return KindBlock }
+
+// Dump implements ast.Node.
+func (b *Block) Dump(source []byte, level int) {
+	ast.DumpHelper(b, source, level, nil, nil)
+}
+
+var _ ast.Node = &Block{}
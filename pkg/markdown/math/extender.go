@@ -0,0 +1,71 @@
+package math
+
+import (
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/renderer"
+	"github.com/yuin/goldmark/util"
+)
+
+// Extender adds the parsing of the $ and $$ delimited formulas
+// and their rendering as MathML
+type Extender struct {
+}
+
+// Extend implements goldmark.Extender.
+func (e *Extender) Extend(m goldmark.Markdown) {
+	m.Parser().AddOptions(
+		parser.WithBlockParsers(
+			util.Prioritized(&BlockParser{}, 690),
+		),
+		parser.WithInlineParsers(
+			util.Prioritized(&InlineParser{}, 500),
+		),
+	)
+
+	m.Renderer().AddOptions(
+		renderer.WithNodeRenderers(
+			util.Prioritized(&HTMLRenderer{}, 500),
+		),
+	)
+}
+
+var _ goldmark.Extender = &Extender{}
+
+// HTMLRenderer renders the formulas as MathML
+type HTMLRenderer struct {
+}
+
+// RegisterFuncs implements renderer.NodeRenderer.
+func (r *HTMLRenderer) RegisterFuncs(reg renderer.NodeRendererFuncRegisterer) {
+	reg.Register(KindInline, r.renderInline)
+	reg.Register(KindBlock, r.renderBlock)
+}
+
+func (r *HTMLRenderer) renderInline(w util.BufWriter, source []byte, node ast.Node, entering bool) (ast.WalkStatus, error) {
+	if !entering {
+		return ast.WalkSkipChildren, nil
+	}
+
+	inline := node.(*Inline)
+
+	_, _ = w.WriteString(ToMathML(string(inline.Formula(source)), inline.Display))
+
+	return ast.WalkSkipChildren, nil
+}
+
+func (r *HTMLRenderer) renderBlock(w util.BufWriter, source []byte, node ast.Node, entering bool) (ast.WalkStatus, error) {
+	if !entering {
+		return ast.WalkSkipChildren, nil
+	}
+
+	block := node.(*Block)
+
+	_, _ = w.WriteString(ToMathML(string(block.Formula(source)), true))
+	_, _ = w.WriteString("\n")
+
+	return ast.WalkSkipChildren, nil
+}
+
+var _ renderer.NodeRenderer = &HTMLRenderer{}
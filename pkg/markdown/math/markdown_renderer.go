@@ -0,0 +1,69 @@
+package math
+
+import (
+	"bytes"
+
+	"github.com/Bornholm/amatl/pkg/markdown/renderer/markdown"
+	"github.com/Bornholm/amatl/pkg/markdown/renderer/markdown/node"
+	"github.com/pkg/errors"
+	"github.com/yuin/goldmark/ast"
+)
+
+// MarkdownRenderers returns the renderers writing back
+// the formulas with their original delimiters
+func MarkdownRenderers() map[ast.NodeKind]markdown.NodeRenderer {
+	return map[ast.NodeKind]markdown.NodeRenderer{
+		KindInline: &InlineMarkdownRenderer{},
+		KindBlock:  node.WithLineSpacingBefore(&BlockMarkdownRenderer{}, 2),
+	}
+}
+
+type InlineMarkdownRenderer struct{}
+
+// Render implements markdown.NodeRenderer.
+func (*InlineMarkdownRenderer) Render(r *markdown.Render, n ast.Node, entering bool) (ast.WalkStatus, error) {
+	inline, ok := n.(*Inline)
+	if !ok {
+		return ast.WalkStop, errors.Errorf("expected *math.Inline, got '%T'", n)
+	}
+
+	if !entering {
+		return ast.WalkContinue, nil
+	}
+
+	_, _ = r.Writer().Write(inline.Delimiter())
+	_, _ = r.Writer().Write(inline.Formula(r.Source()))
+	_, _ = r.Writer().Write(inline.Delimiter())
+
+	return ast.WalkSkipChildren, nil
+}
+
+var _ markdown.NodeRenderer = &InlineMarkdownRenderer{}
+
+type BlockMarkdownRenderer struct{}
+
+// Render implements markdown.NodeRenderer.
+func (*BlockMarkdownRenderer) Render(r *markdown.Render, n ast.Node, entering bool) (ast.WalkStatus, error) {
+	block, ok := n.(*Block)
+	if !ok {
+		return ast.WalkStop, errors.Errorf("expected *math.Block, got '%T'", n)
+	}
+
+	if !entering {
+		return ast.WalkContinue, nil
+	}
+
+	formula := block.Formula(r.Source())
+	if len(formula) > 0 && !bytes.HasSuffix(formula, markdown.NewLineChar) {
+		formula = append(formula, markdown.NewLineChar...)
+	}
+
+	_, _ = r.Writer().Write(blockDelimiter)
+	_, _ = r.Writer().Write(markdown.NewLineChar)
+	_, _ = r.Writer().Write(formula)
+	_, _ = r.Writer().Write(blockDelimiter)
+
+	return ast.WalkSkipChildren, nil
+}
+
+var _ markdown.NodeRenderer = &BlockMarkdownRenderer{}
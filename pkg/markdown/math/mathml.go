@@ -0,0 +1,671 @@
+package math
+
+import (
+	"fmt"
+	"html"
+	"strings"
+	"unicode"
+)
+
+// ToMathML converts the given LaTeX formula to a MathML element, the
+// original formula being kept as an annotation. The commands of the
+// usual formulas are supported, the unknown ones are rendered as errors.
+func ToMathML(formula string, display bool) string {
+	p := &texParser{src: []rune(formula)}
+	nodes, _ := p.parseExpression()
+
+	var sb strings.Builder
+
+	if display {
+		sb.WriteString(`<math display="block">`)
+	} else {
+		sb.WriteString(`<math>`)
+	}
+
+	sb.WriteString("<semantics>")
+	sb.WriteString(mrow(nodes...))
+	sb.WriteString(`<annotation encoding="application/x-tex">`)
+	sb.WriteString(html.EscapeString(strings.TrimSpace(formula)))
+	sb.WriteString("</annotation></semantics></math>")
+
+	return sb.String()
+}
+
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenChar
+	tokenCommand
+	tokenOpenGroup
+	tokenCloseGroup
+	tokenSuperscript
+	tokenSubscript
+	tokenColumn
+	tokenRow
+)
+
+type token struct {
+	kind  tokenKind
+	value string
+}
+
+func (t token) is(kind tokenKind, value string) bool {
+	return t.kind == kind && t.value == value
+}
+
+// atom is a node of the formula and whether its scripts are
+// placed under and over it, as with the large operators
+type atom struct {
+	markup string
+	limits bool
+}
+
+type texParser struct {
+	src []rune
+	pos int
+	// variant is the unicode mathematical alphanumeric
+	// style applied to the letters and digits, if any
+	variant string
+}
+
+func (p *texParser) skipSpaces() {
+	for p.pos < len(p.src) {
+		switch r := p.src[p.pos]; {
+		case unicode.IsSpace(r):
+			p.pos++
+		case r == '%':
+			for p.pos < len(p.src) && p.src[p.pos] != '\n' {
+				p.pos++
+			}
+		default:
+			return
+		}
+	}
+}
+
+func (p *texParser) next() token {
+	p.skipSpaces()
+
+	if p.pos >= len(p.src) {
+		return token{kind: tokenEOF}
+	}
+
+	r := p.src[p.pos]
+	p.pos++
+
+	switch r {
+	case '{':
+		return token{kind: tokenOpenGroup}
+	case '}':
+		return token{kind: tokenCloseGroup}
+	case '^':
+		return token{kind: tokenSuperscript}
+	case '_':
+		return token{kind: tokenSubscript}
+	case '&':
+		return token{kind: tokenColumn}
+	case '\\':
+		if p.pos >= len(p.src) {
+			return token{kind: tokenChar, value: `\`}
+		}
+
+		start := p.pos
+		if !isASCIILetter(p.src[p.pos]) {
+			p.pos++
+			if p.src[start] == '\\' {
+				return token{kind: tokenRow}
+			}
+			return token{kind: tokenCommand, value: string(p.src[start:p.pos])}
+		}
+
+		for p.pos < len(p.src) && isASCIILetter(p.src[p.pos]) {
+			p.pos++
+		}
+
+		name := string(p.src[start:p.pos])
+
+		// Starred variants, i.e. \operatorname*
+		if p.pos < len(p.src) && p.src[p.pos] == '*' {
+			p.pos++
+			name += "*"
+		}
+
+		return token{kind: tokenCommand, value: name}
+	}
+
+	return token{kind: tokenChar, value: string(r)}
+}
+
+func (p *texParser) peek() token {
+	pos := p.pos
+	t := p.next()
+	p.pos = pos
+	return t
+}
+
+// parseExpression parses the nodes up to the end of the formula, the end of
+// the current group or one of the given terminators, which is not consumed
+func (p *texParser) parseExpression(terminators ...token) ([]string, token) {
+	nodes := make([]string, 0)
+
+	for {
+		t := p.peek()
+
+		if t.kind == tokenEOF || t.kind == tokenCloseGroup {
+			return nodes, t
+		}
+
+		for _, terminator := range terminators {
+			if t == terminator {
+				return nodes, t
+			}
+		}
+
+		if t.kind == tokenColumn || t.kind == tokenRow {
+			p.next()
+			nodes = append(nodes, merror(`\\`))
+			continue
+		}
+
+		if t.kind == tokenCommand && (t.value == "displaystyle" || t.value == "textstyle" || t.value == "nonumber" || t.value == "notag") {
+			p.next()
+			continue
+		}
+
+		base := p.parseAtom(false)
+		nodes = append(nodes, p.parseScripts(base))
+	}
+}
+
+// parseGroup parses the content of a group, the opening brace being consumed
+func (p *texParser) parseGroup() string {
+	nodes, end := p.parseExpression()
+	if end.kind == tokenCloseGroup {
+		p.next()
+	}
+
+	return mrow(nodes...)
+}
+
+// parseArgument parses a command argument, either a group or a single token
+func (p *texParser) parseArgument() string {
+	if p.peek().kind == tokenOpenGroup {
+		p.next()
+		return p.parseGroup()
+	}
+
+	return p.parseAtom(true).markup
+}
+
+// parseRawArgument returns the raw content of a group argument
+func (p *texParser) parseRawArgument() string {
+	p.skipSpaces()
+
+	if p.pos >= len(p.src) || p.src[p.pos] != '{' {
+		return p.next().value
+	}
+
+	depth := 0
+	start := p.pos + 1
+
+	for ; p.pos < len(p.src); p.pos++ {
+		switch p.src[p.pos] {
+		case '\\':
+			p.pos++
+		case '{':
+			depth++
+		case '}':
+			depth--
+			if depth == 0 {
+				raw := string(p.src[start:p.pos])
+				p.pos++
+				return raw
+			}
+		}
+	}
+
+	return string(p.src[start:])
+}
+
+// parseOptionalArgument returns the raw content of an
+// optional [...] argument, if any
+func (p *texParser) parseOptionalArgument() (string, bool) {
+	p.skipSpaces()
+
+	if p.pos >= len(p.src) || p.src[p.pos] != '[' {
+		return "", false
+	}
+
+	depth := 0
+	start := p.pos + 1
+
+	for ; p.pos < len(p.src); p.pos++ {
+		switch p.src[p.pos] {
+		case '{':
+			depth++
+		case '}':
+			depth--
+		case ']':
+			if depth == 0 {
+				raw := string(p.src[start:p.pos])
+				p.pos++
+				return raw, true
+			}
+		}
+	}
+
+	return string(p.src[start:]), true
+}
+
+func (p *texParser) parseScripts(base atom) string {
+	var (
+		sub, sup       string
+		hasSub, hasSup bool
+		primes         string
+	)
+
+	for {
+		t := p.peek()
+
+		switch {
+		case t.is(tokenChar, "'"):
+			p.next()
+			primes += "′"
+			continue
+		case t.is(tokenCommand, "limits"):
+			p.next()
+			base.limits = true
+			continue
+		case t.is(tokenCommand, "nolimits"):
+			p.next()
+			base.limits = false
+			continue
+		case t.kind == tokenSuperscript && !hasSup:
+			p.next()
+			sup, hasSup = p.parseArgument(), true
+			continue
+		case t.kind == tokenSubscript && !hasSub:
+			p.next()
+			sub, hasSub = p.parseArgument(), true
+			continue
+		}
+
+		break
+	}
+
+	if primes != "" {
+		if hasSup {
+			sup = mrow(mo(primes), sup)
+		} else {
+			sup, hasSup = mo(primes), true
+		}
+	}
+
+	switch {
+	case hasSub && hasSup && base.limits:
+		return "<munderover>" + base.markup + sub + sup + "</munderover>"
+	case hasSub && hasSup:
+		return "<msubsup>" + base.markup + sub + sup + "</msubsup>"
+	case hasSub && base.limits:
+		return "<munder>" + base.markup + sub + "</munder>"
+	case hasSub:
+		return "<msub>" + base.markup + sub + "</msub>"
+	case hasSup && base.limits:
+		return "<mover>" + base.markup + sup + "</mover>"
+	case hasSup:
+		return "<msup>" + base.markup + sup + "</msup>"
+	}
+
+	return base.markup
+}
+
+// parseAtom parses the next node, without its scripts. If single is true,
+// only one digit is consumed, as in \frac12.
+func (p *texParser) parseAtom(single bool) atom {
+	t := p.next()
+
+	switch t.kind {
+	case tokenOpenGroup:
+		return atom{markup: p.parseGroup()}
+	case tokenSuperscript, tokenSubscript:
+		// Scripts without base
+		p.pos--
+		return atom{markup: "<mrow></mrow>"}
+	case tokenCommand:
+		return p.parseCommand(t.value)
+	case tokenChar:
+		return atom{markup: p.parseChar(t.value, single)}
+	}
+
+	return atom{markup: merror(t.value)}
+}
+
+func (p *texParser) parseChar(value string, single bool) string {
+	r := []rune(value)[0]
+
+	switch {
+	case isDigit(r):
+		number := value
+		for !single && p.pos < len(p.src) {
+			c := p.src[p.pos]
+			if isDigit(c) || (c == '.' && p.pos+1 < len(p.src) && isDigit(p.src[p.pos+1])) {
+				number += string(c)
+				p.pos++
+				continue
+			}
+			break
+		}
+		return mn(p.styled(number))
+	case r == '~':
+		return "<mtext> </mtext>"
+	case unicode.IsLetter(r):
+		return mi(p.styled(value))
+	}
+
+	if symbol, exists := charOperators[r]; exists {
+		return mo(symbol)
+	}
+
+	return mo(value)
+}
+
+func (p *texParser) styled(value string) string {
+	if p.variant == "" {
+		return value
+	}
+
+	return mathVariant(p.variant, value)
+}
+
+func (p *texParser) parseCommand(name string) atom {
+	if symbol, exists := identifiers[name]; exists {
+		return atom{markup: mi(symbol)}
+	}
+
+	if symbol, exists := uprightIdentifiers[name]; exists {
+		return atom{markup: `<mi mathvariant="normal">` + symbol + "</mi>"}
+	}
+
+	if symbol, exists := operators[name]; exists {
+		return atom{markup: mo(symbol)}
+	}
+
+	if symbol, exists := largeOperators[name]; exists {
+		return atom{markup: mo(symbol), limits: true}
+	}
+
+	if symbol, exists := integrals[name]; exists {
+		return atom{markup: mo(symbol)}
+	}
+
+	if _, exists := functions[name]; exists {
+		return atom{markup: mi(name)}
+	}
+
+	if _, exists := limitFunctions[name]; exists {
+		return atom{markup: `<mo form="prefix" movablelimits="true">` + limitFunctions[name] + "</mo>", limits: true}
+	}
+
+	if width, exists := spaces[name]; exists {
+		return atom{markup: `<mspace width="` + width + `"></mspace>`}
+	}
+
+	if variant, exists := fonts[name]; exists {
+		previous := p.variant
+		p.variant = variant
+		defer func() { p.variant = previous }()
+
+		if variant == "normal" {
+			return atom{markup: p.parseUprightArgument()}
+		}
+
+		return atom{markup: p.parseArgument()}
+	}
+
+	if accent, exists := accents[name]; exists {
+		return atom{
+			markup: "<mover accent=\"true\">" + p.parseArgument() + `<mo stretchy="` + fmt.Sprint(accent.stretchy) + `">` + accent.symbol + "</mo></mover>",
+			limits: name == "overbrace",
+		}
+	}
+
+	if accent, exists := underAccents[name]; exists {
+		return atom{markup: "<munder accentunder=\"true\">" + p.parseArgument() + `<mo stretchy="true">` + accent + "</mo></munder>", limits: true}
+	}
+
+	if size, exists := delimiterSizes[name]; exists {
+		delimiter := p.parseDelimiter()
+		return atom{markup: `<mo minsize="` + size + `" maxsize="` + size + `">` + delimiter + "</mo>"}
+	}
+
+	switch name {
+	case "frac", "dfrac", "tfrac", "cfrac":
+		numerator := p.parseArgument()
+		denominator := p.parseArgument()
+		return atom{markup: "<mfrac>" + numerator + denominator + "</mfrac>"}
+
+	case "binom", "dbinom", "tbinom":
+		n := p.parseArgument()
+		k := p.parseArgument()
+		return atom{markup: "<mrow>" + mo("(") + `<mfrac linethickness="0">` + n + k + "</mfrac>" + mo(")") + "</mrow>"}
+
+	case "sqrt":
+		index, hasIndex := p.parseOptionalArgument()
+		radicand := p.parseArgument()
+		if hasIndex {
+			indexParser := &texParser{src: []rune(index)}
+			nodes, _ := indexParser.parseExpression()
+			return atom{markup: "<mroot>" + radicand + mrow(nodes...) + "</mroot>"}
+		}
+		return atom{markup: "<msqrt>" + radicand + "</msqrt>"}
+
+	case "text", "textrm", "textnormal", "mbox", "textit", "textbf", "texttt":
+		return atom{markup: mtext(name, p.parseRawArgument())}
+
+	case "operatorname", "operatorname*":
+		previous := p.variant
+		p.variant = ""
+		defer func() { p.variant = previous }()
+		content := strings.TrimSpace(p.parseRawArgument())
+		if name == "operatorname*" {
+			return atom{markup: `<mo form="prefix" movablelimits="true">` + html.EscapeString(content) + "</mo>", limits: true}
+		}
+		return atom{markup: mi(html.EscapeString(content))}
+
+	case "left":
+		open := p.parseDelimiter()
+		nodes, end := p.parseExpression(token{kind: tokenCommand, value: "right"})
+		closing := ""
+		if end.is(tokenCommand, "right") {
+			p.next()
+			closing = p.parseDelimiter()
+		}
+		return atom{markup: "<mrow>" + fence(open) + strings.Join(nodes, "") + fence(closing) + "</mrow>"}
+
+	case "middle":
+		return atom{markup: `<mo stretchy="true">` + p.parseDelimiter() + "</mo>"}
+
+	case "begin":
+		return atom{markup: p.parseEnvironment(strings.TrimSpace(p.parseRawArgument()))}
+	}
+
+	return atom{markup: merror(`\` + name)}
+}
+
+// parseUprightArgument parses the argument of \mathrm, the
+// identifiers of which are not italic
+func (p *texParser) parseUprightArgument() string {
+	argument := p.parseArgument()
+	return strings.ReplaceAll(argument, "<mi>", `<mi mathvariant="normal">`)
+}
+
+// parseDelimiter returns the symbol following
+// \left, \right, \middle or \big
+func (p *texParser) parseDelimiter() string {
+	t := p.next()
+
+	switch t.kind {
+	case tokenChar:
+		if t.value == "." {
+			return ""
+		}
+		return html.EscapeString(t.value)
+	case tokenCommand:
+		if symbol, exists := delimiters[t.value]; exists {
+			return symbol
+		}
+		if symbol, exists := operators[t.value]; exists {
+			return symbol
+		}
+	}
+
+	return ""
+}
+
+type environment struct {
+	open, close string
+	columnAlign string
+}
+
+var environments = map[string]environment{
+	"matrix":      {},
+	"smallmatrix": {},
+	"pmatrix":     {open: "(", close: ")"},
+	"bmatrix":     {open: "[", close: "]"},
+	"Bmatrix":     {open: "{", close: "}"},
+	"vmatrix":     {open: "|", close: "|"},
+	"Vmatrix":     {open: "‖", close: "‖"},
+	"cases":       {open: "{", columnAlign: "left left"},
+	"array":       {},
+	"aligned":     {columnAlign: "right left"},
+	"align":       {columnAlign: "right left"},
+	"align*":      {columnAlign: "right left"},
+	"split":       {columnAlign: "right left"},
+	"gathered":    {},
+	"gather":      {},
+	"gather*":     {},
+	"equation":    {},
+	"equation*":   {},
+}
+
+func (p *texParser) parseEnvironment(name string) string {
+	env, exists := environments[name]
+	if !exists {
+		return merror(`\begin{` + name + `}`)
+	}
+
+	if name == "array" {
+		// Ignore the columns specification
+		p.parseRawArgument()
+	}
+
+	end := token{kind: tokenCommand, value: "end"}
+	column := token{kind: tokenColumn}
+	row := token{kind: tokenRow}
+
+	var table strings.Builder
+
+	table.WriteString("<mtable")
+	if env.columnAlign != "" {
+		table.WriteString(` columnalign="` + env.columnAlign + `"`)
+	}
+	table.WriteString("><mtr>")
+
+	for {
+		nodes, terminator := p.parseExpression(end, column, row)
+		table.WriteString("<mtd>" + mrow(nodes...) + "</mtd>")
+
+		if terminator.kind == tokenColumn {
+			p.next()
+			continue
+		}
+
+		if terminator.kind == tokenRow {
+			p.next()
+			// Ignore the trailing row separator
+			if p.peek() == end {
+				continue
+			}
+			table.WriteString("</mtr><mtr>")
+			continue
+		}
+
+		if terminator == end {
+			p.next()
+			p.parseRawArgument()
+		} else if terminator.kind == tokenCloseGroup {
+			p.next()
+		}
+
+		break
+	}
+
+	table.WriteString("</mtr></mtable>")
+
+	if name == "equation" || name == "equation*" {
+		return strings.TrimSuffix(strings.TrimPrefix(table.String(), "<mtable><mtr><mtd>"), "</mtd></mtr></mtable>")
+	}
+
+	if env.open == "" && env.close == "" {
+		return table.String()
+	}
+
+	return "<mrow>" + fence(env.open) + table.String() + fence(env.close) + "</mrow>"
+}
+
+func mrow(nodes ...string) string {
+	if len(nodes) == 1 {
+		return nodes[0]
+	}
+
+	return "<mrow>" + strings.Join(nodes, "") + "</mrow>"
+}
+
+func mi(value string) string {
+	return "<mi>" + html.EscapeString(value) + "</mi>"
+}
+
+func mn(value string) string {
+	return "<mn>" + html.EscapeString(value) + "</mn>"
+}
+
+func mo(value string) string {
+	return "<mo>" + html.EscapeString(value) + "</mo>"
+}
+
+func mtext(command string, value string) string {
+	value = strings.NewReplacer(`\{`, "{", `\}`, "}", "{", "", "}", "", "~", " ").Replace(value)
+	value = html.EscapeString(value)
+
+	switch command {
+	case "textbf":
+		return `<mtext style="font-weight:bold">` + value + "</mtext>"
+	case "textit":
+		return `<mtext style="font-style:italic">` + value + "</mtext>"
+	case "texttt":
+		return `<mtext style="font-family:monospace">` + value + "</mtext>"
+	}
+
+	return "<mtext>" + value + "</mtext>"
+}
+
+func fence(symbol string) string {
+	if symbol == "" {
+		return ""
+	}
+
+	return `<mo fence="true" stretchy="true" symmetric="true">` + symbol + "</mo>"
+}
+
+func merror(source string) string {
+	return "<merror><mtext>" + html.EscapeString(source) + "</mtext></merror>"
+}
+
+func isASCIILetter(r rune) bool {
+	return (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z')
+}
+
+func isDigit(r rune) bool {
+	return r >= '0' && r <= '9'
+}
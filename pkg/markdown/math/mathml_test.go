@@ -0,0 +1,67 @@
+package math
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestToMathML(t *testing.T) {
+	type testCase struct {
+		Formula  string
+		Expected string
+	}
+
+	testCases := []testCase{
+		{
+			Formula:  `E = mc^2`,
+			Expected: `<mrow><mi>E</mi><mo>=</mo><mi>m</mi><msup><mi>c</mi><mn>2</mn></msup></mrow>`,
+		},
+		{
+			Formula:  `\frac{-b \pm \sqrt{b^2 - 4ac}}{2a}`,
+			Expected: `<mfrac><mrow><mo>−</mo><mi>b</mi><mo>±</mo><msqrt><mrow><msup><mi>b</mi><mn>2</mn></msup><mo>−</mo><mn>4</mn><mi>a</mi><mi>c</mi></mrow></msqrt></mrow><mrow><mn>2</mn><mi>a</mi></mrow></mfrac>`,
+		},
+		{
+			Formula:  `\sum_{i=1}^{n} x_i`,
+			Expected: `<mrow><munderover><mo>∑</mo><mrow><mi>i</mi><mo>=</mo><mn>1</mn></mrow><mi>n</mi></munderover><msub><mi>x</mi><mi>i</mi></msub></mrow>`,
+		},
+		{
+			Formula:  `\int_0^1 f'(x) \, dx`,
+			Expected: `<mrow><msubsup><mo>∫</mo><mn>0</mn><mn>1</mn></msubsup><msup><mi>f</mi><mo>′</mo></msup><mo>(</mo><mi>x</mi><mo>)</mo><mspace width="0.1667em"></mspace><mi>d</mi><mi>x</mi></mrow>`,
+		},
+		{
+			Formula:  `\frac12 + 3.14`,
+			Expected: `<mrow><mfrac><mn>1</mn><mn>2</mn></mfrac><mo>+</mo><mn>3.14</mn></mrow>`,
+		},
+		{
+			Formula:  `\mathbb{R}^n \mathrm{d}x`,
+			Expected: `<mrow><msup><mi>ℝ</mi><mi>n</mi></msup><mi mathvariant="normal">d</mi><mi>x</mi></mrow>`,
+		},
+		{
+			Formula:  `\left\{ \text{if } x < 0 \right.`,
+			Expected: `<mrow><mo fence="true" stretchy="true" symmetric="true">{</mo><mtext>if </mtext><mi>x</mi><mo>&lt;</mo><mn>0</mn></mrow>`,
+		},
+		{
+			Formula:  `\begin{pmatrix} a & b \\ c & d \end{pmatrix}`,
+			Expected: `<mrow><mo fence="true" stretchy="true" symmetric="true">(</mo><mtable><mtr><mtd><mi>a</mi></mtd><mtd><mi>b</mi></mtd></mtr><mtr><mtd><mi>c</mi></mtd><mtd><mi>d</mi></mtd></mtr></mtable><mo fence="true" stretchy="true" symmetric="true">)</mo></mrow>`,
+		},
+		{
+			Formula:  `\sqrt[3]{8} \unknown`,
+			Expected: `<mrow><mroot><mn>8</mn><mn>3</mn></mroot><merror><mtext>\unknown</mtext></merror></mrow>`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Formula, func(t *testing.T) {
+			mathML := ToMathML(tc.Formula, false)
+
+			expected := "<math><semantics>" + tc.Expected + `<annotation encoding="application/x-tex">`
+			if !strings.HasPrefix(mathML, expected) {
+				t.Errorf("ToMathML(%q): expected '%s', got '%s'", tc.Formula, expected, mathML)
+			}
+		})
+	}
+
+	if mathML := ToMathML(`x`, true); !strings.HasPrefix(mathML, `<math display="block">`) {
+		t.Errorf("expected displayed formula, got '%s'", mathML)
+	}
+}
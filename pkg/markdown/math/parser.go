@@ -0,0 +1,148 @@
+package math
+
+import (
+	"bytes"
+
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/text"
+	"github.com/yuin/goldmark/util"
+)
+
+var blockDelimiter = []byte("$$")
+
+// InlineParser parses the formulas delimited by $ or $$ in a paragraph.
+// As with Pandoc, the opening $ must be followed by a non space character
+// and the closing $ must be preceded by a non space character and not be
+// followed by a digit, i.e. "from $5 to $10" is not a formula.
+type InlineParser struct {
+}
+
+// Trigger implements parser.InlineParser.
+func (p *InlineParser) Trigger() []byte {
+	return []byte{'$'}
+}
+
+// Parse implements parser.InlineParser.
+func (p *InlineParser) Parse(parent ast.Node, block text.Reader, pc parser.Context) ast.Node {
+	line, _ := block.PeekLine()
+
+	opener := 0
+	for ; opener < len(line) && line[opener] == '$'; opener++ {
+	}
+
+	if opener > 2 || opener >= len(line) || util.IsSpace(line[opener]) {
+		return nil
+	}
+
+	l, pos := block.Position()
+	block.Advance(opener)
+
+	node := &Inline{Display: opener == 2}
+
+	for {
+		line, segment := block.PeekLine()
+		if line == nil {
+			block.SetPosition(l, pos)
+			return nil
+		}
+
+		for i := 0; i < len(line); i++ {
+			switch c := line[i]; {
+			case c == '\\':
+				i++
+			case c == '$' && p.isCloser(line, i, opener):
+				if i > 0 {
+					node.AppendChild(node, ast.NewRawTextSegment(segment.WithStop(segment.Start+i)))
+				}
+
+				block.Advance(i + opener)
+
+				if node.ChildCount() == 0 {
+					block.SetPosition(l, pos)
+					return nil
+				}
+
+				return node
+			}
+		}
+
+		node.AppendChild(node, ast.NewRawTextSegment(segment))
+		block.AdvanceLine()
+	}
+}
+
+func (p *InlineParser) isCloser(line []byte, i int, opener int) bool {
+	if !bytes.HasPrefix(line[i:], blockDelimiter[:opener]) {
+		return false
+	}
+
+	if next := i + opener; next < len(line) && (line[next] == '$' || (opener == 1 && util.IsNumeric(line[next]))) {
+		return false
+	}
+
+	return i > 0 && !util.IsSpace(line[i-1])
+}
+
+var _ parser.InlineParser = &InlineParser{}
+
+// BlockParser parses the formulas delimited by lines containing only $$
+type BlockParser struct {
+}
+
+// Trigger implements parser.BlockParser.
+func (p *BlockParser) Trigger() []byte {
+	return []byte{'$'}
+}
+
+// Open implements parser.BlockParser.
+func (p *BlockParser) Open(parent ast.Node, reader text.Reader, pc parser.Context) (ast.Node, parser.State) {
+	line, _ := reader.PeekLine()
+
+	pos := pc.BlockOffset()
+	if pos < 0 || !isBlockDelimiter(line[pos:]) {
+		return nil, parser.NoChildren
+	}
+
+	return &Block{}, parser.NoChildren
+}
+
+// Continue implements parser.BlockParser.
+func (p *BlockParser) Continue(node ast.Node, reader text.Reader, pc parser.Context) parser.State {
+	line, segment := reader.PeekLine()
+
+	if isBlockDelimiter(util.TrimLeftSpace(line)) {
+		newline := 0
+		if line[len(line)-1] == '\n' {
+			newline = 1
+		}
+		reader.Advance(segment.Stop - segment.Start - newline + segment.Padding)
+		return parser.Close
+	}
+
+	segment.ForceNewline = true
+	node.Lines().Append(segment)
+	reader.Advance(segment.Len() - 1)
+
+	return parser.Continue | parser.NoChildren
+}
+
+// Close implements parser.BlockParser.
+func (p *BlockParser) Close(node ast.Node, reader text.Reader, pc parser.Context) {
+}
+
+// CanInterruptParagraph implements parser.BlockParser.
+func (p *BlockParser) CanInterruptParagraph() bool {
+	return true
+}
+
+// CanAcceptIndentedLine implements parser.BlockParser.
+func (p *BlockParser) CanAcceptIndentedLine() bool {
+	return false
+}
+
+func isBlockDelimiter(line []byte) bool {
+	return bytes.Equal(util.TrimRightSpace(line), blockDelimiter)
+}
+
+var _ parser.BlockParser = &BlockParser{}
@@ -0,0 +1,62 @@
+package math
+
+import (
+	"testing"
+
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/text"
+)
+
+func TestParser(t *testing.T) {
+	type testCase struct {
+		Source   string
+		Formulas []string
+	}
+
+	testCases := []testCase{
+		{Source: "$x^2$ and $$y$$", Formulas: []string{"x^2", "y"}},
+		{Source: "from $5 to $10", Formulas: []string{}},
+		{Source: "a \\$x$ b", Formulas: []string{}},
+		{Source: "$ x$ and $x $", Formulas: []string{}},
+		{Source: "$a +\nb$", Formulas: []string{"a +\nb"}},
+		{Source: "$$\n\\frac{a}{b}\n$$\n", Formulas: []string{"\\frac{a}{b}\n"}},
+		{Source: "```\n$x$\n```\n", Formulas: []string{}},
+	}
+
+	markdown := goldmark.New(goldmark.WithExtensions(&Extender{}))
+
+	for _, tc := range testCases {
+		t.Run(tc.Source, func(t *testing.T) {
+			source := []byte(tc.Source)
+			document := markdown.Parser().Parse(text.NewReader(source))
+
+			formulas := make([]string, 0)
+
+			_ = ast.Walk(document, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+				if !entering {
+					return ast.WalkContinue, nil
+				}
+
+				switch node := n.(type) {
+				case *Inline:
+					formulas = append(formulas, string(node.Formula(source)))
+				case *Block:
+					formulas = append(formulas, string(node.Formula(source)))
+				}
+
+				return ast.WalkContinue, nil
+			})
+
+			if e, g := len(tc.Formulas), len(formulas); e != g {
+				t.Fatalf("len(formulas): expected '%d', got '%d' (%q)", e, g, formulas)
+			}
+
+			for i := range tc.Formulas {
+				if e, g := tc.Formulas[i], formulas[i]; e != g {
+					t.Errorf("formulas[%d]: expected '%s', got '%s'", i, e, g)
+				}
+			}
+		})
+	}
+}
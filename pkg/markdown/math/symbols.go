@@ -0,0 +1,205 @@
+package math
+
+import "strings"
+
+// identifiers are the commands rendered as <mi>
+var identifiers = map[string]string{
+	"alpha": "α", "beta": "β", "gamma": "γ", "delta": "δ", "epsilon": "ϵ",
+	"varepsilon": "ε", "zeta": "ζ", "eta": "η", "theta": "θ", "vartheta": "ϑ",
+	"iota": "ι", "kappa": "κ", "lambda": "λ", "mu": "μ", "nu": "ν", "xi": "ξ",
+	"omicron": "ο", "pi": "π", "varpi": "ϖ", "rho": "ρ", "varrho": "ϱ",
+	"sigma": "σ", "varsigma": "ς", "tau": "τ", "upsilon": "υ", "phi": "ϕ",
+	"varphi": "φ", "chi": "χ", "psi": "ψ", "omega": "ω",
+	"infty": "∞", "partial": "∂", "nabla": "∇", "ell": "ℓ", "hbar": "ℏ",
+	"imath": "ı", "jmath": "ȷ", "aleph": "ℵ", "beth": "ℶ", "wp": "℘",
+	"Re": "ℜ", "Im": "ℑ", "emptyset": "∅", "varnothing": "∅",
+	"angle": "∠", "triangle": "△", "top": "⊤", "bot": "⊥",
+	"%": "%", "$": "$", "#": "#", "&": "&", "_": "_",
+}
+
+// uprightIdentifiers are the commands rendered as upright <mi>
+var uprightIdentifiers = map[string]string{
+	"Gamma": "Γ", "Delta": "Δ", "Theta": "Θ", "Lambda": "Λ", "Xi": "Ξ",
+	"Pi": "Π", "Sigma": "Σ", "Upsilon": "Υ", "Phi": "Φ", "Psi": "Ψ",
+	"Omega": "Ω",
+}
+
+// operators are the commands rendered as <mo>
+var operators = map[string]string{
+	// Binary operators
+	"pm": "±", "mp": "∓", "times": "×", "div": "÷", "cdot": "⋅", "ast": "∗",
+	"star": "⋆", "circ": "∘", "bullet": "∙", "oplus": "⊕", "ominus": "⊖",
+	"otimes": "⊗", "oslash": "⊘", "odot": "⊙", "cap": "∩", "cup": "∪",
+	"wedge": "∧", "land": "∧", "vee": "∨", "lor": "∨", "setminus": "∖",
+	"sqcup": "⊔", "sqcap": "⊓", "uplus": "⊎", "amalg": "⨿", "dagger": "†",
+	"ddagger": "‡",
+	// Relations
+	"leq": "≤", "le": "≤", "geq": "≥", "ge": "≥", "neq": "≠", "ne": "≠",
+	"leqslant": "⩽", "geqslant": "⩾", "approx": "≈", "equiv": "≡", "sim": "∼",
+	"simeq": "≃", "cong": "≅", "propto": "∝", "ll": "≪", "gg": "≫",
+	"prec": "≺", "succ": "≻", "preceq": "⪯", "succeq": "⪰",
+	"subset": "⊂", "supset": "⊃", "subseteq": "⊆", "supseteq": "⊇",
+	"subsetneq": "⊊", "supsetneq": "⊋", "in": "∈", "notin": "∉", "ni": "∋",
+	"mid": "∣", "nmid": "∤", "parallel": "∥", "perp": "⊥", "models": "⊨",
+	"vdash": "⊢", "dashv": "⊣", "doteq": "≐", "asymp": "≍", "coloneqq": "≔",
+	// Logic
+	"forall": "∀", "exists": "∃", "nexists": "∄", "neg": "¬", "lnot": "¬",
+	"therefore": "∴", "because": "∵",
+	// Arrows
+	"to": "→", "rightarrow": "→", "leftarrow": "←", "gets": "←",
+	"leftrightarrow": "↔", "Rightarrow": "⇒", "Leftarrow": "⇐",
+	"Leftrightarrow": "⇔", "implies": "⟹", "impliedby": "⟸", "iff": "⟺",
+	"mapsto": "↦", "longrightarrow": "⟶", "longleftarrow": "⟵",
+	"longleftrightarrow": "⟷", "Longrightarrow": "⟹", "Longleftarrow": "⟸",
+	"longmapsto": "⟼", "uparrow": "↑", "downarrow": "↓", "updownarrow": "↕",
+	"Uparrow": "⇑", "Downarrow": "⇓", "nearrow": "↗", "searrow": "↘",
+	"swarrow": "↙", "nwarrow": "↖", "hookrightarrow": "↪", "hookleftarrow": "↩",
+	"rightharpoonup": "⇀", "leftharpoonup": "↼", "rightleftharpoons": "⇌",
+	// Dots
+	"dots": "…", "ldots": "…", "cdots": "⋯", "vdots": "⋮", "ddots": "⋱",
+	"prime": "′",
+	// Delimiters
+	"{": "{", "}": "}", "|": "‖", "lbrace": "{", "rbrace": "}",
+	"langle": "⟨", "rangle": "⟩", "lfloor": "⌊", "rfloor": "⌋",
+	"lceil": "⌈", "rceil": "⌉", "vert": "|", "Vert": "‖",
+	"lvert": "|", "rvert": "|", "lVert": "‖", "rVert": "‖",
+	"backslash": "\\",
+}
+
+// delimiters are the commands allowed after \left, \right, \middle and \big
+var delimiters = map[string]string{
+	"{": "{", "}": "}", "|": "‖", "lbrace": "{", "rbrace": "}",
+	"langle": "⟨", "rangle": "⟩", "lfloor": "⌊", "rfloor": "⌋",
+	"lceil": "⌈", "rceil": "⌉", "vert": "|", "Vert": "‖",
+	"lvert": "|", "rvert": "|", "lVert": "‖", "rVert": "‖",
+	"backslash": "\\", "uparrow": "↑", "downarrow": "↓", "Uparrow": "⇑",
+	"Downarrow": "⇓",
+}
+
+// largeOperators are the operators with their scripts under and over them
+var largeOperators = map[string]string{
+	"sum": "∑", "prod": "∏", "coprod": "∐", "bigcup": "⋃", "bigcap": "⋂",
+	"bigoplus": "⨁", "bigotimes": "⨂", "bigodot": "⨀", "bigvee": "⋁",
+	"bigwedge": "⋀", "bigsqcup": "⨆", "biguplus": "⨄",
+}
+
+// integrals are the large operators with their scripts beside them
+var integrals = map[string]string{
+	"int": "∫", "iint": "∬", "iiint": "∭", "oint": "∮",
+}
+
+// functions are the upright function names
+var functions = map[string]struct{}{
+	"sin": {}, "cos": {}, "tan": {}, "cot": {}, "sec": {}, "csc": {},
+	"arcsin": {}, "arccos": {}, "arctan": {}, "sinh": {}, "cosh": {},
+	"tanh": {}, "coth": {}, "log": {}, "ln": {}, "lg": {}, "exp": {},
+	"dim": {}, "ker": {}, "deg": {}, "arg": {}, "hom": {},
+}
+
+// limitFunctions are the function names with their scripts under them
+var limitFunctions = map[string]string{
+	"lim": "lim", "limsup": "lim sup", "liminf": "lim inf", "max": "max",
+	"min": "min", "sup": "sup", "inf": "inf", "det": "det", "gcd": "gcd",
+	"Pr": "Pr", "argmax": "arg max", "argmin": "arg min",
+}
+
+// spaces are the widths of the spacing commands
+var spaces = map[string]string{
+	",": "0.1667em", "thinspace": "0.1667em", ":": "0.2222em", ">": "0.2222em",
+	"medspace": "0.2222em", ";": "0.2778em", "thickspace": "0.2778em",
+	"!": "-0.1667em", "negthinspace": "-0.1667em", " ": "0.25em",
+	"quad": "1em", "qquad": "2em", "enspace": "0.5em",
+}
+
+type accent struct {
+	symbol   string
+	stretchy bool
+}
+
+// accents are the commands placing a symbol over their argument
+var accents = map[string]accent{
+	"hat": {"^", false}, "widehat": {"^", true}, "tilde": {"~", false},
+	"widetilde": {"~", true}, "bar": {"¯", false}, "overline": {"‾", true},
+	"vec": {"→", false}, "overrightarrow": {"→", true},
+	"overleftarrow": {"←", true}, "dot": {"˙", false}, "ddot": {"¨", false},
+	"check": {"ˇ", false}, "breve": {"˘", false}, "acute": {"´", false},
+	"grave": {"`", false}, "mathring": {"˚", false}, "overbrace": {"⏞", true},
+}
+
+// underAccents are the commands placing a symbol under their argument
+var underAccents = map[string]string{
+	"underline": "_", "underbrace": "⏟",
+}
+
+// delimiterSizes are the sizes of the delimiters following \big and its variants
+var delimiterSizes = map[string]string{
+	"big": "1.2em", "bigl": "1.2em", "bigr": "1.2em", "bigm": "1.2em",
+	"Big": "1.623em", "Bigl": "1.623em", "Bigr": "1.623em", "Bigm": "1.623em",
+	"bigg": "2.047em", "biggl": "2.047em", "biggr": "2.047em", "biggm": "2.047em",
+	"Bigg": "2.470em", "Biggl": "2.470em", "Biggr": "2.470em", "Biggm": "2.470em",
+}
+
+// charOperators are the characters replaced by another symbol
+var charOperators = map[rune]string{
+	'-': "−", '*': "∗", '\'': "′",
+}
+
+// fonts are the commands changing the style of the letters and digits
+// of their argument to the given mathematical alphanumeric style
+var fonts = map[string]string{
+	"mathrm": "normal", "mathup": "normal", "mathbf": "bold",
+	"mathit": "italic", "boldsymbol": "bold-italic", "bm": "bold-italic",
+	"mathbb": "double-struck", "mathcal": "script", "mathscr": "script",
+	"mathfrak": "fraktur", "mathsf": "sans-serif", "mathtt": "monospace",
+}
+
+type alphanumericStyle struct {
+	upper, lower, digits rune
+	exceptions           map[rune]rune
+}
+
+// alphanumericStyles are the first code points of the Unicode
+// mathematical alphanumeric symbols of each style
+var alphanumericStyles = map[string]alphanumericStyle{
+	"bold":        {upper: 0x1D400, lower: 0x1D41A, digits: 0x1D7CE},
+	"italic":      {upper: 0x1D434, lower: 0x1D44E, exceptions: map[rune]rune{'h': 'ℎ'}},
+	"bold-italic": {upper: 0x1D468, lower: 0x1D482, digits: 0x1D7CE},
+	"script": {upper: 0x1D49C, lower: 0x1D4B6, exceptions: map[rune]rune{
+		'B': 'ℬ', 'E': 'ℰ', 'F': 'ℱ', 'H': 'ℋ', 'I': 'ℐ', 'L': 'ℒ', 'M': 'ℳ',
+		'R': 'ℛ', 'e': 'ℯ', 'g': 'ℊ', 'o': 'ℴ',
+	}},
+	"fraktur": {upper: 0x1D504, lower: 0x1D51E, exceptions: map[rune]rune{
+		'C': 'ℭ', 'H': 'ℌ', 'I': 'ℑ', 'R': 'ℜ', 'Z': 'ℨ',
+	}},
+	"double-struck": {upper: 0x1D538, lower: 0x1D552, digits: 0x1D7D8, exceptions: map[rune]rune{
+		'C': 'ℂ', 'H': 'ℍ', 'N': 'ℕ', 'P': 'ℙ', 'Q': 'ℚ', 'R': 'ℝ', 'Z': 'ℤ',
+	}},
+	"sans-serif": {upper: 0x1D5A0, lower: 0x1D5BA, digits: 0x1D7E2},
+	"monospace":  {upper: 0x1D670, lower: 0x1D68A, digits: 0x1D7F6},
+}
+
+// mathVariant converts the ASCII letters and digits of the given
+// value to the mathematical alphanumeric symbols of the given style
+func mathVariant(variant string, value string) string {
+	style, exists := alphanumericStyles[variant]
+	if !exists {
+		return value
+	}
+
+	return strings.Map(func(r rune) rune {
+		if replacement, exists := style.exceptions[r]; exists {
+			return replacement
+		}
+
+		switch {
+		case r >= 'A' && r <= 'Z':
+			return style.upper + r - 'A'
+		case r >= 'a' && r <= 'z':
+			return style.lower + r - 'a'
+		case r >= '0' && r <= '9' && style.digits != 0:
+			return style.digits + r - '0'
+		}
+
+		return r
+	}, value)
+}
@@ -1,6 +1,7 @@
 package node
 
 import (
+	"github.com/Bornholm/amatl/pkg/markdown/alert"
 	"github.com/Bornholm/amatl/pkg/markdown/renderer/markdown"
 	"github.com/pkg/errors"
 	"github.com/yuin/goldmark/ast"
@@ -22,6 +23,10 @@ func (*BlockquoteRenderer) Render(r *markdown.Render, node ast.Node, entering bo
 			node.PreviousSibling() == nil {
 			_, _ = r.Writer().Write(markdown.BlockquoteChars)
 		}
+
+		if alert, ok := alert.Get(node); ok {
+			writeAlertMarker(r, node, alert)
+		}
 	} else {
 		r.Writer().PopIndent()
 	}
@@ -29,4 +34,25 @@ func (*BlockquoteRenderer) Render(r *markdown.Render, node ast.Node, entering bo
 	return ast.WalkContinue, nil
 }
 
+// writeAlertMarker writes the [!TYPE] marker and the title of the alert,
+// separated from its content as in the source document
+func writeAlertMarker(r *markdown.Render, node ast.Node, alert *alert.Alert) {
+	_, _ = r.Writer().Write([]byte("[!" + alert.Type + "]"))
+
+	if alert.Title != "" {
+		_, _ = r.Writer().Write(markdown.SpaceChar)
+		_, _ = r.Writer().Write([]byte(alert.Title))
+	}
+
+	if !node.HasChildren() {
+		return
+	}
+
+	_, _ = r.Writer().Write(markdown.NewLineChar)
+
+	if alert.Separated {
+		_, _ = r.Writer().Write(markdown.NewLineChar)
+	}
+}
+
 var _ markdown.NodeRenderer = &BlockquoteRenderer{}
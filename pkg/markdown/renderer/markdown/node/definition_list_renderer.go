@@ -0,0 +1,57 @@
+package node
+
+import (
+	"github.com/Bornholm/amatl/pkg/markdown/renderer/markdown"
+	"github.com/pkg/errors"
+	"github.com/yuin/goldmark/ast"
+	extAST "github.com/yuin/goldmark/extension/ast"
+)
+
+type DefinitionTermRenderer struct {
+}
+
+// Render implements NodeRenderer.
+func (*DefinitionTermRenderer) Render(r *markdown.Render, node ast.Node, entering bool) (ast.WalkStatus, error) {
+	if _, ok := node.(*extAST.DefinitionTerm); !ok {
+		return ast.WalkStop, errors.Errorf("expected *ast.DefinitionTerm, got '%T'", node)
+	}
+
+	if !entering {
+		return ast.WalkContinue, nil
+	}
+
+	if previous := node.PreviousSibling(); previous != nil {
+		_, _ = r.Writer().Write(markdown.NewLineChar)
+
+		// Separate the term from the description of the previous one
+		if previous.Kind() == extAST.KindDefinitionDescription {
+			_, _ = r.Writer().Write(markdown.NewLineChar)
+		}
+	}
+
+	return ast.WalkContinue, nil
+}
+
+var _ markdown.NodeRenderer = &DefinitionTermRenderer{}
+
+type DefinitionDescriptionRenderer struct {
+}
+
+// Render implements NodeRenderer.
+func (*DefinitionDescriptionRenderer) Render(r *markdown.Render, node ast.Node, entering bool) (ast.WalkStatus, error) {
+	if _, ok := node.(*extAST.DefinitionDescription); !ok {
+		return ast.WalkStop, errors.Errorf("expected *ast.DefinitionDescription, got '%T'", node)
+	}
+
+	if entering {
+		_, _ = r.Writer().Write(markdown.NewLineChar)
+		_, _ = r.Writer().Write(markdown.DefinitionChars)
+		r.Writer().PushIndent(markdown.DefinitionIndentChars)
+	} else {
+		r.Writer().PopIndent()
+	}
+
+	return ast.WalkContinue, nil
+}
+
+var _ markdown.NodeRenderer = &DefinitionDescriptionRenderer{}
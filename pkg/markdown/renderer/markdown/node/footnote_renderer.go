@@ -0,0 +1,81 @@
+package node
+
+import (
+	"strconv"
+
+	"github.com/Bornholm/amatl/pkg/markdown/renderer/markdown"
+	"github.com/pkg/errors"
+	"github.com/yuin/goldmark/ast"
+	extAST "github.com/yuin/goldmark/extension/ast"
+)
+
+type FootnoteLinkRenderer struct {
+}
+
+// Render implements NodeRenderer.
+func (*FootnoteLinkRenderer) Render(r *markdown.Render, node ast.Node, entering bool) (ast.WalkStatus, error) {
+	link, ok := node.(*extAST.FootnoteLink)
+	if !ok {
+		return ast.WalkStop, errors.Errorf("expected *ast.FootnoteLink, got '%T'", node)
+	}
+
+	if !entering {
+		return ast.WalkContinue, nil
+	}
+
+	_, _ = r.Writer().Write([]byte("[^"))
+	_, _ = r.Writer().Write(footnoteLabel(link))
+	_, _ = r.Writer().Write([]byte("]"))
+
+	return ast.WalkSkipChildren, nil
+}
+
+var _ markdown.NodeRenderer = &FootnoteLinkRenderer{}
+
+// footnoteLabel returns the label of the footnote referenced by the given
+// link, the links only holding the index of the footnote in the document
+func footnoteLabel(link *extAST.FootnoteLink) []byte {
+	var root ast.Node = link
+	for root.Parent() != nil {
+		root = root.Parent()
+	}
+
+	for child := root.LastChild(); child != nil; child = child.PreviousSibling() {
+		list, ok := child.(*extAST.FootnoteList)
+		if !ok {
+			continue
+		}
+
+		for footnote := list.FirstChild(); footnote != nil; footnote = footnote.NextSibling() {
+			if footnote, ok := footnote.(*extAST.Footnote); ok && footnote.Index == link.Index {
+				return footnote.Ref
+			}
+		}
+	}
+
+	return []byte(strconv.Itoa(link.Index))
+}
+
+type FootnoteRenderer struct {
+}
+
+// Render implements NodeRenderer.
+func (*FootnoteRenderer) Render(r *markdown.Render, node ast.Node, entering bool) (ast.WalkStatus, error) {
+	footnote, ok := node.(*extAST.Footnote)
+	if !ok {
+		return ast.WalkStop, errors.Errorf("expected *ast.Footnote, got '%T'", node)
+	}
+
+	if entering {
+		_, _ = r.Writer().Write([]byte("[^"))
+		_, _ = r.Writer().Write(footnote.Ref)
+		_, _ = r.Writer().Write([]byte("]: "))
+		r.Writer().PushIndent(markdown.FourSpacesChars)
+	} else {
+		r.Writer().PopIndent()
+	}
+
+	return ast.WalkContinue, nil
+}
+
+var _ markdown.NodeRenderer = &FootnoteRenderer{}
@@ -8,31 +8,38 @@ import (
 
 func Renderers() map[ast.NodeKind]markdown.NodeRenderer {
 	return map[ast.NodeKind]markdown.NodeRenderer{
-		ast.KindDocument:         &DocumentRenderer{},
-		ast.KindText:             &TextRenderer{},
-		ast.KindString:           &StringRenderer{},
-		ast.KindHeading:          WithLineSpacingBefore(&HeadingRenderer{}, 2),
-		ast.KindAutoLink:         &AutoLinkRenderer{},
-		ast.KindCodeBlock:        &CodeBlockRenderer{},
-		ast.KindFencedCodeBlock:  WithLineSpacingBefore(&CodeBlockRenderer{}, 2),
-		ast.KindHTMLBlock:        WithListOrHTMLBlockSpacing(&HTMLBlockRenderer{}),
-		ast.KindRawHTML:          WithListOrHTMLBlockSpacing(&RawHTMLRenderer{}),
-		ast.KindThematicBreak:    WithLineSpacingBefore(&ThematicBreakRenderer{}, 2),
-		ast.KindListItem:         &ListItemRenderer{},
-		ast.KindLink:             &LinkRenderer{},
-		ast.KindImage:            &ImageRenderer{},
-		ast.KindEmphasis:         &EmphasisRenderer{},
-		ast.KindCodeSpan:         &CodeSpanRenderer{},
-		ast.KindBlockquote:       WithLineSpacingBefore(&BlockquoteRenderer{}, 2),
-		ast.KindParagraph:        WithLineSpacingBefore(&DummyRenderer{}, 2),
-		ast.KindTextBlock:        WithLineSpacingBefore(&DummyRenderer{}, 2),
-		ast.KindList:             WithListOrHTMLBlockSpacing(&DummyRenderer{}),
-		extAST.KindTableCell:     &DummyRenderer{},
-		extAST.KindTableHeader:   &DummyRenderer{},
-		extAST.KindTableRow:      &DummyRenderer{},
-		extAST.KindStrikethrough: &StrikethroughRenderer{},
-		extAST.KindTaskCheckBox:  &TaskCheckboxRenderer{},
-		extAST.KindTable:         WithLineSpacingBefore(&TableRenderer{}, 2),
+		ast.KindDocument:                 &DocumentRenderer{},
+		ast.KindText:                     &TextRenderer{},
+		ast.KindString:                   &StringRenderer{},
+		ast.KindHeading:                  WithLineSpacingBefore(&HeadingRenderer{}, 2),
+		ast.KindAutoLink:                 &AutoLinkRenderer{},
+		ast.KindCodeBlock:                &CodeBlockRenderer{},
+		ast.KindFencedCodeBlock:          WithLineSpacingBefore(&CodeBlockRenderer{}, 2),
+		ast.KindHTMLBlock:                WithListOrHTMLBlockSpacing(&HTMLBlockRenderer{}),
+		ast.KindRawHTML:                  WithListOrHTMLBlockSpacing(&RawHTMLRenderer{}),
+		ast.KindThematicBreak:            WithLineSpacingBefore(&ThematicBreakRenderer{}, 2),
+		ast.KindListItem:                 &ListItemRenderer{},
+		ast.KindLink:                     &LinkRenderer{},
+		ast.KindImage:                    &ImageRenderer{},
+		ast.KindEmphasis:                 &EmphasisRenderer{},
+		ast.KindCodeSpan:                 &CodeSpanRenderer{},
+		ast.KindBlockquote:               WithLineSpacingBefore(&BlockquoteRenderer{}, 2),
+		ast.KindParagraph:                WithLineSpacingBefore(&DummyRenderer{}, 2),
+		ast.KindTextBlock:                WithLineSpacingBefore(&DummyRenderer{}, 2),
+		ast.KindList:                     WithListOrHTMLBlockSpacing(&DummyRenderer{}),
+		extAST.KindTableCell:             &DummyRenderer{},
+		extAST.KindTableHeader:           &DummyRenderer{},
+		extAST.KindTableRow:              &DummyRenderer{},
+		extAST.KindStrikethrough:         &StrikethroughRenderer{},
+		extAST.KindTaskCheckBox:          &TaskCheckboxRenderer{},
+		extAST.KindTable:                 WithLineSpacingBefore(&TableRenderer{}, 2),
+		extAST.KindFootnoteLink:          &FootnoteLinkRenderer{},
+		extAST.KindFootnoteBacklink:      &DummyRenderer{},
+		extAST.KindFootnoteList:          WithLineSpacingBefore(&DummyRenderer{}, 2),
+		extAST.KindFootnote:              WithLineSpacingBefore(&FootnoteRenderer{}, 2),
+		extAST.KindDefinitionList:        WithLineSpacingBefore(&DummyRenderer{}, 2),
+		extAST.KindDefinitionTerm:        &DefinitionTermRenderer{},
+		extAST.KindDefinitionDescription: &DefinitionDescriptionRenderer{},
 	}
 }
 
@@ -23,6 +23,8 @@ var (
 	Heading1UnderlineChar   = []byte{'='}
 	Heading2UnderlineChar   = []byte{'-'}
 	FourSpacesChars         = bytes.Repeat([]byte{' '}, 4)
+	DefinitionChars         = []byte{':', ' '}
+	DefinitionIndentChars   = bytes.Repeat([]byte{' '}, 2)
 )
 
 // Ensure compatibility with Goldmark parser.
@@ -8,8 +8,10 @@ import (
 	"strings"
 	"testing"
 
+	"github.com/Bornholm/amatl/pkg/markdown/alert"
 	"github.com/Bornholm/amatl/pkg/markdown/directive"
 	"github.com/Bornholm/amatl/pkg/markdown/directive/include"
+	"github.com/Bornholm/amatl/pkg/markdown/math"
 	"github.com/Bornholm/amatl/pkg/markdown/renderer/markdown"
 	"github.com/Bornholm/amatl/pkg/markdown/renderer/markdown/node"
 	"github.com/Bornholm/amatl/pkg/transform"
@@ -49,6 +51,10 @@ func TestMarkdonwRenderer(t *testing.T) {
 			gm := goldmark.New(
 				goldmark.WithExtensions(
 					extension.GFM,
+					extension.Footnote,
+					extension.DefinitionList,
+					&math.Extender{},
+					&alert.Extender{},
 				),
 			)
 
@@ -75,6 +81,7 @@ func TestMarkdonwRenderer(t *testing.T) {
 					),
 				),
 				markdown.WithNodeRenderers(node.Renderers()),
+				markdown.WithNodeRenderers(math.MarkdownRenderers()),
 				markdown.WithNodeRenderer(
 					directive.KindDirective,
 					directive.NewMarkdownNodeRenderer(
@@ -0,0 +1,66 @@
+package pdf
+
+import (
+	"strings"
+)
+
+// Locate returns a copy of the given bookmarks with the pages of the bookmarks
+// of the reference outline having the same titles, both outlines being walked
+// through in document order. A bookmark without match is located on the page
+// of the previous one.
+func Locate(bookmarks []Bookmark, reference []Bookmark) []Bookmark {
+	type entry struct {
+		title string
+		page  int
+	}
+
+	entries := make([]entry, 0)
+
+	var flatten func(bookmarks []Bookmark)
+	flatten = func(bookmarks []Bookmark) {
+		for _, b := range bookmarks {
+			entries = append(entries, entry{title: normalizeTitle(b.Title), page: b.Page})
+			flatten(b.Children)
+		}
+	}
+
+	flatten(reference)
+
+	next, page := 0, 1
+
+	var locate func(bookmarks []Bookmark) []Bookmark
+	locate = func(bookmarks []Bookmark) []Bookmark {
+		if len(bookmarks) == 0 {
+			return nil
+		}
+
+		located := make([]Bookmark, 0, len(bookmarks))
+
+		for _, b := range bookmarks {
+			title := normalizeTitle(b.Title)
+
+			for i := next; i < len(entries); i++ {
+				if entries[i].title == title {
+					page = max(entries[i].page, page)
+					next = i + 1
+					break
+				}
+			}
+
+			located = append(located, Bookmark{
+				Title: b.Title,
+				Page:  page,
+			})
+
+			located[len(located)-1].Children = locate(b.Children)
+		}
+
+		return located
+	}
+
+	return locate(bookmarks)
+}
+
+func normalizeTitle(title string) string {
+	return strings.Join(strings.Fields(title), " ")
+}
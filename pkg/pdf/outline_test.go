@@ -0,0 +1,40 @@
+package pdf
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestLocate(t *testing.T) {
+	headings := []Bookmark{
+		{Title: "Introduction"},
+		{Title: "Usage", Children: []Bookmark{
+			{Title: "Install"},
+			{Title: "Not  in   reference"},
+		}},
+		{Title: "Reference  guide"},
+	}
+
+	reference := []Bookmark{
+		{Title: "Introduction", Page: 1},
+		{Title: "Usage", Page: 2, Children: []Bookmark{
+			{Title: "Install", Page: 3},
+		}},
+		{Title: "Reference guide", Page: 5},
+	}
+
+	expected := []Bookmark{
+		{Title: "Introduction", Page: 1},
+		{Title: "Usage", Page: 2, Children: []Bookmark{
+			{Title: "Install", Page: 3},
+			{Title: "Not  in   reference", Page: 3},
+		}},
+		{Title: "Reference  guide", Page: 5},
+	}
+
+	located := Locate(headings, reference)
+
+	if e, g := fmt.Sprintf("%+v", expected), fmt.Sprintf("%+v", located); e != g {
+		t.Errorf("expected '%s', got '%s'", e, g)
+	}
+}
@@ -0,0 +1,264 @@
+package pdf
+
+import (
+	"bytes"
+	"io"
+	"sync"
+
+	"github.com/pdfcpu/pdfcpu/pkg/api"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/model"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/types"
+	"github.com/pkg/errors"
+)
+
+// Bookmark is an entry of the outline of a PDF document
+type Bookmark struct {
+	Title    string
+	Page     int
+	Children []Bookmark
+}
+
+// Outline returns the bookmarks of the given PDF document
+func Outline(data []byte) ([]Bookmark, error) {
+	var bookmarks []pdfcpu.Bookmark
+
+	err := withConfiguration(func(conf *model.Configuration) error {
+		var err error
+		bookmarks, err = api.Bookmarks(bytes.NewReader(data), conf)
+		return err
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "could not read pdf outline")
+	}
+
+	return fromPDFCPU(bookmarks), nil
+}
+
+// PageCount returns the number of pages of the given PDF document
+func PageCount(data []byte) (int, error) {
+	var count int
+
+	err := withConfiguration(func(conf *model.Configuration) error {
+		var err error
+		count, err = api.PageCount(bytes.NewReader(data), conf)
+		return err
+	})
+	if err != nil {
+		return 0, errors.Wrap(err, "could not count pdf pages")
+	}
+
+	return count, nil
+}
+
+// PageSize returns the width and the height in points
+// of the first page of the given PDF document
+func PageSize(data []byte) (float64, float64, error) {
+	var dims []types.Dim
+
+	err := withConfiguration(func(conf *model.Configuration) error {
+		var err error
+		dims, err = api.PageDims(bytes.NewReader(data), conf)
+		return err
+	})
+	if err != nil {
+		return 0, 0, errors.Wrap(err, "could not read pdf page size")
+	}
+
+	if len(dims) == 0 {
+		return 0, 0, errors.New("pdf document has no page")
+	}
+
+	return dims[0].Width, dims[0].Height, nil
+}
+
+// Merge concatenates the pages of the given PDF documents
+func Merge(documents ...[]byte) ([]byte, error) {
+	switch len(documents) {
+	case 0:
+		return nil, errors.New("no pdf document to merge")
+	case 1:
+		return documents[0], nil
+	}
+
+	readers := make([]io.ReadSeeker, 0, len(documents))
+	for _, d := range documents {
+		readers = append(readers, bytes.NewReader(d))
+	}
+
+	var merged bytes.Buffer
+
+	err := withConfiguration(func(conf *model.Configuration) error {
+		return api.MergeRaw(readers, &merged, false, conf)
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "could not merge pdf documents")
+	}
+
+	return merged.Bytes(), nil
+}
+
+// Stamp draws the pages of the given overlay over the pages of the given
+// PDF document, the first page of the overlay over its first page
+func Stamp(data []byte, overlay []byte) ([]byte, error) {
+	var stamped bytes.Buffer
+
+	err := withConfiguration(func(conf *model.Configuration) error {
+		wm, err := api.PDFMultiWatermarkForReadSeeker(bytes.NewReader(overlay), 1, 1, "scalefactor:1 abs, rotation:0", true, false, types.POINTS)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+
+		return api.AddWatermarks(bytes.NewReader(data), &stamped, nil, wm, conf)
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "could not stamp pdf document")
+	}
+
+	return stamped.Bytes(), nil
+}
+
+// Info is the document information of a PDF document
+type Info struct {
+	Title    string
+	Author   string
+	Subject  string
+	Keywords string
+	Creator  string
+}
+
+// isCreatorOnly returns true if the creator is the only
+// field of the document information, if any
+func (i Info) isCreatorOnly() bool {
+	return i.Title == "" && i.Author == "" && i.Subject == "" && i.Keywords == ""
+}
+
+func (i Info) properties() map[string]string {
+	properties := map[string]string{}
+
+	for key, value := range map[string]string{
+		"Title":    i.Title,
+		"Author":   i.Author,
+		"Subject":  i.Subject,
+		"Keywords": i.Keywords,
+		"Creator":  i.Creator,
+	} {
+		if value != "" {
+			properties[key] = value
+		}
+	}
+
+	return properties
+}
+
+// Update replaces the outline of the given PDF document, if not empty,
+// and sets the non empty fields of its document information. The document
+// is returned as is if there is neither outline nor information other
+// than its creator.
+func Update(data []byte, outline []Bookmark, info Info) ([]byte, error) {
+	if len(outline) == 0 && info.isCreatorOnly() {
+		return data, nil
+	}
+
+	var updated bytes.Buffer
+
+	err := withConfiguration(func(conf *model.Configuration) error {
+		return update(data, &updated, outline, info.properties(), conf)
+	})
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	return updated.Bytes(), nil
+}
+
+func update(data []byte, w io.Writer, outline []Bookmark, properties map[string]string, conf *model.Configuration) error {
+	ctx, err := api.ReadValidateAndOptimize(bytes.NewReader(data), conf)
+	if err != nil {
+		return errors.Wrap(err, "could not read pdf document")
+	}
+
+	if len(outline) > 0 {
+		if err := pdfcpu.AddBookmarks(ctx, toPDFCPU(outline), true); err != nil {
+			return errors.Wrap(err, "could not write pdf outline")
+		}
+
+		// Display the outline when the document is opened
+		rootDict, err := ctx.Catalog()
+		if err != nil {
+			return errors.WithStack(err)
+		}
+
+		rootDict["PageMode"] = types.Name("UseOutlines")
+	}
+
+	if len(properties) > 0 {
+		if err := pdfcpu.PropertiesAdd(ctx, properties); err != nil {
+			return errors.Wrap(err, "could not write pdf document information")
+		}
+	}
+
+	if err := api.WriteContext(ctx, w); err != nil {
+		return errors.Wrap(err, "could not write pdf document")
+	}
+
+	return nil
+}
+
+func toPDFCPU(bookmarks []Bookmark) []pdfcpu.Bookmark {
+	if len(bookmarks) == 0 {
+		return nil
+	}
+
+	converted := make([]pdfcpu.Bookmark, 0, len(bookmarks))
+	for _, b := range bookmarks {
+		converted = append(converted, pdfcpu.Bookmark{
+			Title:    b.Title,
+			PageFrom: max(b.Page, 1),
+			Kids:     toPDFCPU(b.Children),
+		})
+	}
+
+	return converted
+}
+
+func fromPDFCPU(bookmarks []pdfcpu.Bookmark) []Bookmark {
+	if len(bookmarks) == 0 {
+		return nil
+	}
+
+	converted := make([]Bookmark, 0, len(bookmarks))
+	for _, b := range bookmarks {
+		converted = append(converted, Bookmark{
+			Title:    b.Title,
+			Page:     b.PageFrom,
+			Children: fromPDFCPU(b.Kids),
+		})
+	}
+
+	return converted
+}
+
+// configurationMutex serializes the calls to pdfcpu,
+// its configuration directory being disabled during them
+var configurationMutex sync.Mutex
+
+// withConfiguration calls the given function with a pdfcpu configuration,
+// preventing pdfcpu from creating its configuration directory in the user's
+// home directory during the call
+func withConfiguration(fn func(conf *model.Configuration) error) error {
+	configurationMutex.Lock()
+	defer configurationMutex.Unlock()
+
+	configPath := model.ConfigPath
+	model.ConfigPath = "disable"
+
+	defer func() {
+		model.ConfigPath = configPath
+	}()
+
+	conf := model.NewDefaultConfiguration()
+	conf.ValidationMode = model.ValidationRelaxed
+
+	return fn(conf)
+}
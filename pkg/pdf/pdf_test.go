@@ -0,0 +1,171 @@
+package pdf
+
+import (
+	"bytes"
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/pdfcpu/pdfcpu/pkg/api"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/model"
+	"github.com/pkg/errors"
+)
+
+func TestMerge(t *testing.T) {
+	merged, err := Merge(newTestDocument(t, 2), newTestDocument(t, 3))
+	if err != nil {
+		t.Fatalf("%+v", errors.WithStack(err))
+	}
+
+	count, err := PageCount(merged)
+	if err != nil {
+		t.Fatalf("%+v", errors.WithStack(err))
+	}
+
+	if e, g := 5, count; e != g {
+		t.Errorf("page count: expected '%d', got '%d'", e, g)
+	}
+}
+
+func TestUpdate(t *testing.T) {
+	outline := []Bookmark{
+		{Title: "Introduction", Page: 1},
+		{Title: "Usage", Page: 2, Children: []Bookmark{
+			{Title: "Install", Page: 3},
+		}},
+	}
+
+	info := Info{
+		Title:    "My document",
+		Author:   "John Doe",
+		Keywords: "foo, bar",
+	}
+
+	updated, err := Update(newTestDocument(t, 3), outline, info)
+	if err != nil {
+		t.Fatalf("%+v", errors.WithStack(err))
+	}
+
+	updatedOutline, err := Outline(updated)
+	if err != nil {
+		t.Fatalf("%+v", errors.WithStack(err))
+	}
+
+	if e, g := fmt.Sprintf("%+v", outline), fmt.Sprintf("%+v", updatedOutline); e != g {
+		t.Errorf("outline: expected '%s', got '%s'", e, g)
+	}
+
+	var documentInfo *pdfcpu.PDFInfo
+
+	err = withConfiguration(func(conf *model.Configuration) error {
+		var err error
+		documentInfo, err = api.PDFInfo(bytes.NewReader(updated), "", nil, false, conf)
+		return err
+	})
+	if err != nil {
+		t.Fatalf("%+v", errors.WithStack(err))
+	}
+
+	if e, g := info.Title, documentInfo.Title; e != g {
+		t.Errorf("title: expected '%s', got '%s'", e, g)
+	}
+
+	if e, g := info.Author, documentInfo.Author; e != g {
+		t.Errorf("author: expected '%s', got '%s'", e, g)
+	}
+
+	if e, g := "UseOutlines", documentInfo.PageMode; e != g {
+		t.Errorf("page mode: expected '%s', got '%s'", e, g)
+	}
+
+	if e, g := []string{"foo", "bar"}, documentInfo.Keywords; !slices.Equal(e, g) {
+		t.Errorf("keywords: expected '%v', got '%v'", e, g)
+	}
+}
+
+func TestUpdateCreatorOnly(t *testing.T) {
+	document := newTestDocument(t, 1)
+
+	updated, err := Update(document, nil, Info{Creator: "amatl"})
+	if err != nil {
+		t.Fatalf("%+v", errors.WithStack(err))
+	}
+
+	if !bytes.Equal(document, updated) {
+		t.Errorf("expected document to be left as is")
+	}
+}
+
+func TestStamp(t *testing.T) {
+	stamped, err := Stamp(newTestDocument(t, 3), newTestDocument(t, 3))
+	if err != nil {
+		t.Fatalf("%+v", errors.WithStack(err))
+	}
+
+	count, err := PageCount(stamped)
+	if err != nil {
+		t.Fatalf("%+v", errors.WithStack(err))
+	}
+
+	if e, g := 3, count; e != g {
+		t.Errorf("page count: expected '%d', got '%d'", e, g)
+	}
+
+	width, height, err := PageSize(stamped)
+	if err != nil {
+		t.Fatalf("%+v", errors.WithStack(err))
+	}
+
+	if e, g := "595x842", fmt.Sprintf("%gx%g", width, height); e != g {
+		t.Errorf("page size: expected '%s', got '%s'", e, g)
+	}
+
+	if !bytes.Contains(stamped, []byte("/XObject")) {
+		t.Errorf("expected overlay to be stamped as a form xobject")
+	}
+}
+
+// newTestDocument returns a minimal PDF document with the given number of blank pages
+func newTestDocument(t *testing.T, pages int) []byte {
+	t.Helper()
+
+	objects := []string{
+		"<< /Type /Catalog /Pages 2 0 R >>",
+	}
+
+	kids := make([]string, 0, pages)
+	for i := 0; i < pages; i++ {
+		kids = append(kids, fmt.Sprintf("%d 0 R", i+3))
+	}
+
+	objects = append(objects, fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), pages))
+
+	for i := 0; i < pages; i++ {
+		objects = append(objects, "<< /Type /Page /Parent 2 0 R /MediaBox [0 0 595 842] >>")
+	}
+
+	var (
+		buf     bytes.Buffer
+		offsets []int
+	)
+
+	buf.WriteString("%PDF-1.7\n")
+
+	for i, o := range objects {
+		offsets = append(offsets, buf.Len())
+		fmt.Fprintf(&buf, "%d 0 obj\n%s\nendobj\n", i+1, o)
+	}
+
+	xref := buf.Len()
+
+	fmt.Fprintf(&buf, "xref\n0 %d\n0000000000 65535 f \n", len(objects)+1)
+	for _, o := range offsets {
+		fmt.Fprintf(&buf, "%010d 00000 n \n", o)
+	}
+
+	fmt.Fprintf(&buf, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(objects)+1, xref)
+
+	return buf.Bytes()
+}
@@ -0,0 +1,76 @@
+package pdf
+
+import (
+	"slices"
+)
+
+// Section is a range of pages sharing the same running headings
+type Section struct {
+	// Headings are the titles of the current heading and of
+	// its ancestors in the outline, outermost first
+	Headings []string
+	FromPage int
+	ToPage   int
+}
+
+// Sections splits the pages of a document in ranges sharing the same current
+// heading, considering the outline up to the given depth. The current heading of
+// a page is the first heading starting on it or, if none, the last heading of
+// the previous pages.
+func Sections(outline []Bookmark, pageCount int, depth int) []Section {
+	type entry struct {
+		page     int
+		headings []string
+	}
+
+	entries := make([]entry, 0)
+
+	var walk func(bookmarks []Bookmark, parents []string)
+	walk = func(bookmarks []Bookmark, parents []string) {
+		if len(parents) >= depth {
+			return
+		}
+
+		for _, b := range bookmarks {
+			headings := append(slices.Clone(parents), b.Title)
+			entries = append(entries, entry{page: b.Page, headings: headings})
+			walk(b.Children, headings)
+		}
+	}
+
+	walk(outline, nil)
+
+	slices.SortStableFunc(entries, func(a, b entry) int {
+		return a.page - b.page
+	})
+
+	sections := make([]Section, 0)
+
+	var current []string
+
+	for page, i := 1, 0; page <= pageCount; page++ {
+		headings := current
+
+		for first := true; i < len(entries) && entries[i].page <= page; i++ {
+			if first && entries[i].page == page {
+				headings = entries[i].headings
+				first = false
+			}
+
+			current = entries[i].headings
+		}
+
+		if last := len(sections) - 1; last >= 0 && slices.Equal(sections[last].Headings, headings) {
+			sections[last].ToPage = page
+			continue
+		}
+
+		sections = append(sections, Section{
+			Headings: headings,
+			FromPage: page,
+			ToPage:   page,
+		})
+	}
+
+	return sections
+}
@@ -0,0 +1,61 @@
+package pdf
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestSections(t *testing.T) {
+	outline := []Bookmark{
+		{Title: "Introduction", Page: 1},
+		{Title: "Usage", Page: 2, Children: []Bookmark{
+			{Title: "Install", Page: 2},
+			{Title: "Configure", Page: 4, Children: []Bookmark{
+				{Title: "Flags", Page: 5},
+			}},
+		}},
+		{Title: "Reference", Page: 6},
+	}
+
+	type testCase struct {
+		Depth    int
+		Expected []Section
+	}
+
+	testCases := []testCase{
+		{
+			Depth: 1,
+			Expected: []Section{
+				{Headings: []string{"Introduction"}, FromPage: 1, ToPage: 1},
+				{Headings: []string{"Usage"}, FromPage: 2, ToPage: 5},
+				{Headings: []string{"Reference"}, FromPage: 6, ToPage: 7},
+			},
+		},
+		{
+			Depth: 2,
+			Expected: []Section{
+				{Headings: []string{"Introduction"}, FromPage: 1, ToPage: 1},
+				{Headings: []string{"Usage"}, FromPage: 2, ToPage: 2},
+				{Headings: []string{"Usage", "Install"}, FromPage: 3, ToPage: 3},
+				{Headings: []string{"Usage", "Configure"}, FromPage: 4, ToPage: 5},
+				{Headings: []string{"Reference"}, FromPage: 6, ToPage: 7},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		sections := Sections(outline, 7, tc.Depth)
+
+		if e, g := fmt.Sprintf("%+v", tc.Expected), fmt.Sprintf("%+v", sections); e != g {
+			t.Errorf("depth %d: expected '%s', got '%s'", tc.Depth, e, g)
+		}
+	}
+}
+
+func TestSectionsWithoutOutline(t *testing.T) {
+	sections := Sections(nil, 3, 2)
+
+	if e, g := fmt.Sprintf("%+v", []Section{{FromPage: 1, ToPage: 3}}), fmt.Sprintf("%+v", sections); e != g {
+		t.Errorf("expected '%s', got '%s'", e, g)
+	}
+}
@@ -4,6 +4,7 @@ import (
 	// Import resolvers
 	"github.com/Bornholm/amatl/pkg/resolver"
 	"github.com/Bornholm/amatl/pkg/resolver/file"
+	_ "github.com/Bornholm/amatl/pkg/resolver/git"
 	_ "github.com/Bornholm/amatl/pkg/resolver/http"
 	_ "github.com/Bornholm/amatl/pkg/resolver/stdin"
 )
@@ -10,6 +10,8 @@ type contextKey string
 const (
 	contextKeyWorkDir  contextKey = "workdir"
 	contextKeyResolver contextKey = "resolver"
+	contextKeyTracker  contextKey = "tracker"
+	contextKeyPolicy   contextKey = "policy"
 )
 
 func WithWorkDir(ctx context.Context, path Path) context.Context {
@@ -38,3 +40,29 @@ func ContextResolver(ctx context.Context) Resolver {
 
 	return resolver
 }
+
+func WithTracker(ctx context.Context, tracker *Tracker) context.Context {
+	return context.WithValue(ctx, contextKeyTracker, tracker)
+}
+
+func ContextTracker(ctx context.Context) *Tracker {
+	tracker, ok := ctx.Value(contextKeyTracker).(*Tracker)
+	if !ok {
+		return nil
+	}
+
+	return tracker
+}
+
+func WithPolicy(ctx context.Context, policy *Policy) context.Context {
+	return context.WithValue(ctx, contextKeyPolicy, policy)
+}
+
+func ContextPolicy(ctx context.Context) *Policy {
+	policy, ok := ctx.Value(contextKeyPolicy).(*Policy)
+	if !ok {
+		return nil
+	}
+
+	return policy
+}
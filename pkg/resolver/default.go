@@ -25,3 +25,12 @@ func Resolve(ctx context.Context, path string) (io.ReadCloser, error) {
 
 	return reader, nil
 }
+
+func Glob(ctx context.Context, pattern string) ([]Path, error) {
+	paths, err := DefaultResolver.Glob(ctx, Path(pattern))
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	return paths, nil
+}
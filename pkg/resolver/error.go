@@ -4,4 +4,7 @@ import "errors"
 
 var (
 	ErrSchemeNotRegistered = errors.New("scheme not registered")
+	ErrOfflineCacheMiss    = errors.New("resource not available in cache in offline mode")
+	ErrPolicyViolation     = errors.New("resolver policy violation")
+	ErrListNotSupported    = errors.New("listing not supported")
 )
@@ -17,7 +17,51 @@ type Resolver struct {
 
 // Resolve implements layout.Resolver.
 func (*Resolver) Resolve(ctx context.Context, path resolver.Path) (io.ReadCloser, error) {
-	// Get the actual file path, handling file:// URLs
+	filePath := getFilePath(path)
+
+	if policy := resolver.ContextPolicy(ctx); policy != nil && policy.Root != "" {
+		file, err := openInRoot(policy.Root, filePath)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+
+		return file, nil
+	}
+
+	file, err := os.Open(filePath)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	return file, nil
+}
+
+// Glob implements resolver.Lister.
+func (*Resolver) Glob(ctx context.Context, pattern resolver.Path) ([]resolver.Path, error) {
+	matches, err := filepath.Glob(getFilePath(pattern))
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	paths := make([]resolver.Path, 0, len(matches))
+	for _, m := range matches {
+		info, err := os.Stat(m)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+
+		if info.IsDir() {
+			continue
+		}
+
+		paths = append(paths, resolver.Path(m))
+	}
+
+	return paths, nil
+}
+
+// getFilePath returns the local file path of the given path, handling file:// URLs
+func getFilePath(path resolver.Path) string {
 	filePath := path.String()
 	scheme := path.Scheme()
 
@@ -48,7 +92,39 @@ func (*Resolver) Resolve(ctx context.Context, path resolver.Path) (io.ReadCloser
 		filePath = strings.ReplaceAll(filePath, "\\", string(os.PathSeparator))
 	}
 
-	file, err := os.Open(filePath)
+	return filePath
+}
+
+// openInRoot opens the file through an os.Root, preventing
+// any escape from the root directory, i.e. with symbolic links
+func openInRoot(rootDir string, filePath string) (*os.File, error) {
+	rootDir, err := filepath.Abs(rootDir)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	filePath, err = filepath.Abs(filePath)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	rel, err := filepath.Rel(rootDir, filePath)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	root, err := os.OpenRoot(rootDir)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	defer func() {
+		if err := root.Close(); err != nil {
+			panic(errors.WithStack(err))
+		}
+	}()
+
+	file, err := root.Open(rel)
 	if err != nil {
 		return nil, errors.WithStack(err)
 	}
@@ -60,4 +136,7 @@ func NewResolver() *Resolver {
 	return &Resolver{}
 }
 
-var _ resolver.Resolver = &Resolver{}
+var (
+	_ resolver.Resolver = &Resolver{}
+	_ resolver.Lister   = &Resolver{}
+)
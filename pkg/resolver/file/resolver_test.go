@@ -61,3 +61,20 @@ func TestResolver(t *testing.T) {
 		resolve(rawURL)
 	}
 }
+
+func TestResolverGlob(t *testing.T) {
+	res := NewResolver()
+
+	paths, err := res.Glob(context.Background(), resolver.Path("testdata/*.txt"))
+	if err != nil {
+		t.Fatalf("%+v", errors.WithStack(err))
+	}
+
+	if e, g := 1, len(paths); e != g {
+		t.Fatalf("len(paths): expected '%v', got '%v'", e, g)
+	}
+
+	if e, g := filepath.Join("testdata", "test.txt"), paths[0].String(); e != g {
+		t.Errorf("paths[0]: expected '%v', got '%v'", e, g)
+	}
+}
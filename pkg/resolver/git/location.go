@@ -0,0 +1,161 @@
+package git
+
+import (
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/Bornholm/amatl/pkg/resolver"
+	"github.com/pkg/errors"
+)
+
+const (
+	// repositorySeparator separates the repository from the file path in git urls,
+	// i.e. git+https://example.com/org/repo//docs/intro.md
+	repositorySeparator = "//"
+	paramRef            = "ref"
+	defaultRef          = "HEAD"
+)
+
+// location is a file inside a git repository at a given ref
+type location struct {
+	// Remote is the url, or the local path, of the repository given to git
+	Remote string
+	// Local is true if the repository is on the local filesystem
+	Local bool
+	// Path is the path of the file inside the repository
+	Path string
+	// Ref is the commit, tag or branch to read the file from
+	Ref string
+}
+
+// parseLocation extracts the repository, the file path and the ref of the given url.
+//
+// Paths derived from a git url (i.e. with resolver.Path.Dir() and JoinPath()) lose the
+// repository separator, so the repository is then searched in the previously resolved
+// repositories, then in the path segments ending with '.git' and finally, for local
+// repositories, on the filesystem.
+func (r *Resolver) parseLocation(path resolver.Path) (*location, error) {
+	u, err := path.URL()
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	transport := strings.TrimPrefix(u.Scheme, "git+")
+
+	ref := u.Query().Get(paramRef)
+	if ref == "" {
+		ref = defaultRef
+	}
+
+	if strings.HasPrefix(ref, "-") {
+		return nil, errors.Errorf("invalid ref '%s'", ref)
+	}
+
+	local := transport == "file"
+
+	repoPath, filePath, err := r.splitPath(transport, u.Host, u.Path)
+	if err != nil {
+		return nil, errors.Wrapf(err, "could not find repository in url '%s'", path)
+	}
+
+	loc := &location{
+		Local: local,
+		Path:  filePath,
+		Ref:   ref,
+	}
+
+	if local {
+		loc.Remote = filepath.FromSlash(repoPath)
+	} else {
+		remote := &url.URL{
+			Scheme: transport,
+			User:   u.User,
+			Host:   u.Host,
+			Path:   repoPath,
+		}
+		loc.Remote = remote.String()
+	}
+
+	return loc, nil
+}
+
+func (r *Resolver) splitPath(transport string, host string, urlPath string) (string, string, error) {
+	if index := strings.Index(urlPath, repositorySeparator); index > 0 {
+		repoPath := urlPath[:index]
+		filePath := strings.TrimLeft(urlPath[index+len(repositorySeparator):], "/")
+
+		r.rememberRepository(transport, host, repoPath)
+
+		return repoPath, filePath, nil
+	}
+
+	segments := strings.Split(strings.Trim(urlPath, "/"), "/")
+
+	// Search in previously resolved repositories, longest path first
+	for i := len(segments) - 1; i > 0; i-- {
+		repoPath := "/" + strings.Join(segments[:i], "/")
+		if r.isKnownRepository(transport, host, repoPath) {
+			return repoPath, strings.Join(segments[i:], "/"), nil
+		}
+	}
+
+	for i := 1; i < len(segments); i++ {
+		if strings.HasSuffix(segments[i-1], ".git") {
+			repoPath := "/" + strings.Join(segments[:i], "/")
+			return repoPath, strings.Join(segments[i:], "/"), nil
+		}
+	}
+
+	if transport == "file" {
+		for i := len(segments) - 1; i > 0; i-- {
+			repoPath := "/" + strings.Join(segments[:i], "/")
+			if isRepository(filepath.FromSlash(repoPath)) {
+				r.rememberRepository(transport, host, repoPath)
+				return repoPath, strings.Join(segments[i:], "/"), nil
+			}
+		}
+	}
+
+	return "", "", errors.Errorf("separate the repository from the file path with '%s'", repositorySeparator)
+}
+
+func (r *Resolver) rememberRepository(transport string, host string, repoPath string) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.repositories[repositoryKey(transport, host, repoPath)] = struct{}{}
+}
+
+func (r *Resolver) isKnownRepository(transport string, host string, repoPath string) bool {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	_, exists := r.repositories[repositoryKey(transport, host, repoPath)]
+
+	return exists
+}
+
+func repositoryKey(transport string, host string, repoPath string) string {
+	return transport + "://" + host + repoPath
+}
+
+// isRepository returns true if the given directory is a bare
+// repository or a working tree
+func isRepository(dir string) bool {
+	if _, err := os.Stat(filepath.Join(dir, ".git")); err == nil {
+		return true
+	}
+
+	if _, err := os.Stat(filepath.Join(dir, "HEAD")); err != nil {
+		return false
+	}
+
+	info, err := os.Stat(filepath.Join(dir, "objects"))
+	if err != nil {
+		return false
+	}
+
+	return info.IsDir()
+}
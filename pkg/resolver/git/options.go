@@ -0,0 +1,58 @@
+package git
+
+import (
+	"os"
+	"path/filepath"
+)
+
+type Options struct {
+	// Command is the git executable used to read repositories
+	Command string
+	// CacheDir is the directory where remote repositories are mirrored
+	CacheDir string
+	// Offline restricts the resolver to the local and already mirrored
+	// repositories, without fetching them
+	Offline bool
+}
+
+type OptionFunc func(opts *Options)
+
+const DefaultCommand = "git"
+
+func NewOptions(funcs ...OptionFunc) *Options {
+	opts := &Options{
+		Command:  DefaultCommand,
+		CacheDir: defaultCacheDir(),
+	}
+	for _, fn := range funcs {
+		fn(opts)
+	}
+	return opts
+}
+
+func WithCommand(command string) OptionFunc {
+	return func(opts *Options) {
+		opts.Command = command
+	}
+}
+
+func WithCacheDir(cacheDir string) OptionFunc {
+	return func(opts *Options) {
+		opts.CacheDir = cacheDir
+	}
+}
+
+func WithOffline(offline bool) OptionFunc {
+	return func(opts *Options) {
+		opts.Offline = offline
+	}
+}
+
+func defaultCacheDir() string {
+	cacheDir, err := os.UserCacheDir()
+	if err != nil {
+		cacheDir = os.TempDir()
+	}
+
+	return filepath.Join(cacheDir, "amatl", "git")
+}
@@ -0,0 +1,21 @@
+package git
+
+import "github.com/Bornholm/amatl/pkg/resolver"
+
+const (
+	Scheme      = "git"
+	SchemeFile  = "git+file"
+	SchemeHTTP  = "git+http"
+	SchemeHTTPS = "git+https"
+	SchemeSSH   = "git+ssh"
+)
+
+// Schemes are the url schemes handled by the git resolver
+var Schemes = []string{Scheme, SchemeFile, SchemeHTTP, SchemeHTTPS, SchemeSSH}
+
+func init() {
+	gitResolver := NewResolver()
+	for _, scheme := range Schemes {
+		resolver.Register(scheme, gitResolver)
+	}
+}
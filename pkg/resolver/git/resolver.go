@@ -0,0 +1,135 @@
+package git
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"log/slog"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/Bornholm/amatl/pkg/resolver"
+	"github.com/pkg/errors"
+)
+
+// Resolver reads files from git repositories at a given ref,
+// without checking them out. Remote repositories are mirrored
+// in the cache directory.
+type Resolver struct {
+	opts *Options
+
+	mutex        sync.RWMutex
+	repositories map[string]struct{}
+
+	syncMutex sync.Mutex
+	synced    map[string]struct{}
+}
+
+// Resolve implements resolver.Resolver.
+func (r *Resolver) Resolve(ctx context.Context, path resolver.Path) (io.ReadCloser, error) {
+	loc, err := r.parseLocation(path)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	repoDir := loc.Remote
+	if !loc.Local {
+		repoDir, err = r.syncMirror(ctx, loc.Remote)
+		if err != nil {
+			return nil, errors.Wrapf(err, "could not retrieve repository '%s'", loc.Remote)
+		}
+	}
+
+	data, err := r.git(ctx, repoDir, "cat-file", "blob", loc.Ref+":"+loc.Path)
+	if err != nil {
+		return nil, errors.Wrapf(err, "could not read '%s' at ref '%s' in repository '%s'", loc.Path, loc.Ref, loc.Remote)
+	}
+
+	return io.NopCloser(bytes.NewReader(data)), nil
+}
+
+// syncMirror clones the given remote repository as a mirror in the cache directory
+// or, if it already exists, fetches it once per resolver. In offline mode, only
+// the existing mirror is used.
+func (r *Resolver) syncMirror(ctx context.Context, remote string) (string, error) {
+	r.syncMutex.Lock()
+	defer r.syncMutex.Unlock()
+
+	hash := sha256.Sum256([]byte(remote))
+	mirrorDir := filepath.Join(r.opts.CacheDir, hex.EncodeToString(hash[:]))
+
+	if _, synced := r.synced[mirrorDir]; synced {
+		return mirrorDir, nil
+	}
+
+	_, err := os.Stat(mirrorDir)
+	mirrored := err == nil
+
+	if r.opts.Offline {
+		if !mirrored {
+			return "", errors.WithStack(resolver.ErrOfflineCacheMiss)
+		}
+
+		return mirrorDir, nil
+	}
+
+	if mirrored {
+		slog.DebugContext(ctx, "fetching git repository", slog.String("remote", remote), slog.String("mirror", mirrorDir))
+
+		if _, err := r.git(ctx, mirrorDir, "fetch", "--quiet", "--prune"); err != nil {
+			return "", errors.WithStack(err)
+		}
+	} else {
+		slog.DebugContext(ctx, "cloning git repository", slog.String("remote", remote), slog.String("mirror", mirrorDir))
+
+		if err := os.MkdirAll(r.opts.CacheDir, 0o755); err != nil {
+			return "", errors.WithStack(err)
+		}
+
+		if _, err := r.git(ctx, "", "clone", "--quiet", "--mirror", "--", remote, mirrorDir); err != nil {
+			return "", errors.WithStack(err)
+		}
+	}
+
+	r.synced[mirrorDir] = struct{}{}
+
+	return mirrorDir, nil
+}
+
+func (r *Resolver) git(ctx context.Context, dir string, args ...string) ([]byte, error) {
+	if dir != "" {
+		args = append([]string{"-C", dir}, args...)
+	}
+
+	cmd := exec.CommandContext(ctx, r.opts.Command, args...)
+	cmd.Env = append(os.Environ(), "GIT_TERMINAL_PROMPT=0")
+
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		if message := strings.TrimSpace(stderr.String()); message != "" {
+			return nil, errors.Wrap(err, message)
+		}
+
+		return nil, errors.WithStack(err)
+	}
+
+	return stdout.Bytes(), nil
+}
+
+func NewResolver(funcs ...OptionFunc) *Resolver {
+	return &Resolver{
+		opts:         NewOptions(funcs...),
+		repositories: make(map[string]struct{}),
+		synced:       make(map[string]struct{}),
+	}
+}
+
+var _ resolver.Resolver = &Resolver{}
@@ -0,0 +1,143 @@
+package git
+
+import (
+	"context"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/Bornholm/amatl/pkg/resolver"
+	"github.com/pkg/errors"
+)
+
+func TestResolver(t *testing.T) {
+	repoDir := createBareRepository(t)
+
+	res := NewResolver(WithCacheDir(t.TempDir()))
+
+	registry := resolver.NewRegistry()
+	registry.Register(SchemeFile, res)
+
+	ctx := context.Background()
+
+	type testCase struct {
+		Path     string
+		WorkDir  string
+		Expected string
+	}
+
+	testCases := []testCase{
+		{
+			Path:     "git+file://" + filepath.ToSlash(repoDir) + "//docs/intro.md?ref=v1.0.0",
+			Expected: "intro v1\n",
+		},
+		{
+			Path:     "git+file://" + filepath.ToSlash(repoDir) + "//docs/intro.md",
+			Expected: "intro v2\n",
+		},
+		{
+			Path:     "git+file://" + filepath.ToSlash(repoDir) + "//docs/intro.md?ref=main",
+			Expected: "intro v2\n",
+		},
+		{
+			// Relative path from a previously resolved file
+			Path:     "./chapter.md",
+			WorkDir:  "git+file://" + filepath.ToSlash(repoDir) + "//docs?ref=v1.0.0",
+			Expected: "chapter v1\n",
+		},
+		{
+			// Relative path in a parent directory
+			Path:     "../README.md",
+			WorkDir:  "git+file://" + filepath.ToSlash(repoDir) + "//docs?ref=v1.0.0",
+			Expected: "readme\n",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Path, func(t *testing.T) {
+			ctx := ctx
+			if tc.WorkDir != "" {
+				workDir := resolver.Path(tc.WorkDir)
+				// Simulate a working directory derived from a resolved file
+				ctx = resolver.WithWorkDir(ctx, workDir.JoinPath("intro.md").Dir())
+			}
+
+			reader, err := registry.Resolve(ctx, resolver.Path(tc.Path))
+			if err != nil {
+				t.Fatalf("%+v", errors.WithStack(err))
+			}
+
+			defer reader.Close()
+
+			data, err := io.ReadAll(reader)
+			if err != nil {
+				t.Fatalf("%+v", errors.WithStack(err))
+			}
+
+			if e, g := tc.Expected, string(data); e != g {
+				t.Errorf("data: expected '%s', got '%s'", e, g)
+			}
+		})
+	}
+}
+
+func TestResolverUnknownFile(t *testing.T) {
+	repoDir := createBareRepository(t)
+
+	res := NewResolver(WithCacheDir(t.TempDir()))
+
+	path := resolver.Path("git+file://" + filepath.ToSlash(repoDir) + "//docs/unknown.md")
+
+	if _, err := res.Resolve(context.Background(), path); err == nil {
+		t.Errorf("expected error for unknown file")
+	}
+}
+
+// createBareRepository creates a bare repository with a 'v1.0.0' tag
+// and a second commit on the 'main' branch
+func createBareRepository(t *testing.T) string {
+	if _, err := exec.LookPath(DefaultCommand); err != nil {
+		t.Skip("git executable not found")
+	}
+
+	workDir := t.TempDir()
+	bareDir := filepath.Join(t.TempDir(), "repo.git")
+
+	runGit(t, workDir, "init", "--quiet", "--initial-branch=main")
+
+	writeFile(t, filepath.Join(workDir, "README.md"), "readme\n")
+	writeFile(t, filepath.Join(workDir, "docs", "intro.md"), "intro v1\n")
+	writeFile(t, filepath.Join(workDir, "docs", "chapter.md"), "chapter v1\n")
+
+	runGit(t, workDir, "add", "-A")
+	runGit(t, workDir, "commit", "--quiet", "-m", "v1")
+	runGit(t, workDir, "tag", "v1.0.0")
+
+	writeFile(t, filepath.Join(workDir, "docs", "intro.md"), "intro v2\n")
+
+	runGit(t, workDir, "commit", "--quiet", "-am", "v2")
+	runGit(t, workDir, "clone", "--quiet", "--bare", workDir, bareDir)
+
+	return bareDir
+}
+
+func runGit(t *testing.T, dir string, args ...string) {
+	args = append([]string{"-C", dir, "-c", "user.name=amatl", "-c", "user.email=amatl@localhost", "-c", "commit.gpgsign=false"}, args...)
+
+	cmd := exec.Command(DefaultCommand, args...)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("git %v: %s: %+v", args, output, errors.WithStack(err))
+	}
+}
+
+func writeFile(t *testing.T, filename string, content string) {
+	if err := os.MkdirAll(filepath.Dir(filename), 0o755); err != nil {
+		t.Fatalf("%+v", errors.WithStack(err))
+	}
+
+	if err := os.WriteFile(filename, []byte(content), 0o644); err != nil {
+		t.Fatalf("%+v", errors.WithStack(err))
+	}
+}
@@ -0,0 +1,233 @@
+package http
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// CacheEntry describes a resource stored in the cache
+type CacheEntry struct {
+	URL          string    `json:"url"`
+	ETag         string    `json:"etag,omitempty"`
+	LastModified string    `json:"lastModified,omitempty"`
+	StoredAt     time.Time `json:"storedAt"`
+	// Expires is the time after which the resource must be revalidated
+	Expires time.Time `json:"expires"`
+	// MustRevalidate forbids the use of the stale resource when
+	// the revalidation fails
+	MustRevalidate bool `json:"mustRevalidate,omitempty"`
+}
+
+// IsFresh returns true if the resource can be used without revalidation
+func (e *CacheEntry) IsFresh(now time.Time) bool {
+	return now.Before(e.Expires)
+}
+
+// Update updates the validators and the freshness of the entry
+// with the given response headers
+func (e *CacheEntry) Update(header http.Header, now time.Time) {
+	if etag := header.Get("ETag"); etag != "" {
+		e.ETag = etag
+	}
+
+	if lastModified := header.Get("Last-Modified"); lastModified != "" {
+		e.LastModified = lastModified
+	}
+
+	cacheControl := parseCacheControl(header)
+
+	e.StoredAt = now
+	e.Expires = expiresAt(header, cacheControl, now)
+	e.MustRevalidate = cacheControl.Has("must-revalidate")
+}
+
+func NewCacheEntry(url string, header http.Header, now time.Time) *CacheEntry {
+	entry := &CacheEntry{
+		URL: url,
+	}
+
+	entry.Update(header, now)
+
+	return entry
+}
+
+// Cache stores fetched resources on disk, each resource being
+// stored as a metadata file and a body file named after the hash of its url
+type Cache struct {
+	dir string
+}
+
+// Get returns the cached entry and body associated with the given url,
+// or a nil entry if the url is not cached
+func (c *Cache) Get(url string) (*CacheEntry, []byte, error) {
+	metadataFile, bodyFile := c.files(url)
+
+	data, err := os.ReadFile(metadataFile)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil, nil
+		}
+
+		return nil, nil, errors.WithStack(err)
+	}
+
+	var entry CacheEntry
+	if err := json.Unmarshal(data, &entry); err != nil {
+		return nil, nil, errors.Wrapf(err, "could not decode cache entry '%s'", metadataFile)
+	}
+
+	body, err := os.ReadFile(bodyFile)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil, nil
+		}
+
+		return nil, nil, errors.WithStack(err)
+	}
+
+	return &entry, body, nil
+}
+
+// Put stores the given entry and, if not nil, its body
+func (c *Cache) Put(entry *CacheEntry, body []byte) error {
+	if err := os.MkdirAll(c.dir, 0o755); err != nil {
+		return errors.WithStack(err)
+	}
+
+	metadataFile, bodyFile := c.files(entry.URL)
+
+	if body != nil {
+		if err := writeFileAtomic(bodyFile, body); err != nil {
+			return errors.WithStack(err)
+		}
+	}
+
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	if err := writeFileAtomic(metadataFile, data); err != nil {
+		return errors.WithStack(err)
+	}
+
+	return nil
+}
+
+// Delete removes the resource associated with the given url
+func (c *Cache) Delete(url string) error {
+	metadataFile, bodyFile := c.files(url)
+
+	for _, filename := range []string{metadataFile, bodyFile} {
+		if err := os.Remove(filename); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return errors.WithStack(err)
+		}
+	}
+
+	return nil
+}
+
+func (c *Cache) files(url string) (string, string) {
+	hash := sha256.Sum256([]byte(url))
+	key := hex.EncodeToString(hash[:])
+
+	return filepath.Join(c.dir, key+".json"), filepath.Join(c.dir, key+".body")
+}
+
+func NewCache(dir string) *Cache {
+	return &Cache{
+		dir: dir,
+	}
+}
+
+// writeFileAtomic writes the file through a temporary file so that
+// concurrent renderings never read a partially written file
+func writeFileAtomic(filename string, data []byte) error {
+	file, err := os.CreateTemp(filepath.Dir(filename), filepath.Base(filename)+".*.tmp")
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	if _, err := file.Write(data); err != nil {
+		_ = file.Close()
+		_ = os.Remove(file.Name())
+		return errors.WithStack(err)
+	}
+
+	if err := file.Close(); err != nil {
+		_ = os.Remove(file.Name())
+		return errors.WithStack(err)
+	}
+
+	if err := os.Rename(file.Name(), filename); err != nil {
+		_ = os.Remove(file.Name())
+		return errors.WithStack(err)
+	}
+
+	return nil
+}
+
+type cacheControl map[string]string
+
+func (c cacheControl) Has(directive string) bool {
+	_, exists := c[directive]
+	return exists
+}
+
+func parseCacheControl(header http.Header) cacheControl {
+	directives := cacheControl{}
+
+	for _, value := range header.Values("Cache-Control") {
+		for _, directive := range strings.Split(value, ",") {
+			name, arg, _ := strings.Cut(strings.TrimSpace(directive), "=")
+			if name == "" {
+				continue
+			}
+
+			directives[strings.ToLower(name)] = strings.Trim(arg, `"`)
+		}
+	}
+
+	return directives
+}
+
+// expiresAt computes the expiration time of a response from its
+// Cache-Control max-age directive or, by default, its Expires header.
+// A response without explicit freshness expires immediately and is
+// revalidated on each use.
+func expiresAt(header http.Header, cacheControl cacheControl, now time.Time) time.Time {
+	if cacheControl.Has("no-cache") {
+		return now
+	}
+
+	if rawMaxAge, exists := cacheControl["max-age"]; exists {
+		maxAge, err := strconv.Atoi(rawMaxAge)
+		if err != nil || maxAge < 0 {
+			return now
+		}
+
+		age, _ := strconv.Atoi(header.Get("Age"))
+
+		return now.Add(time.Duration(maxAge-age) * time.Second)
+	}
+
+	if rawExpires := header.Get("Expires"); rawExpires != "" {
+		expires, err := http.ParseTime(rawExpires)
+		if err != nil {
+			return now
+		}
+
+		return expires
+	}
+
+	return now
+}
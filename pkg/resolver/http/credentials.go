@@ -0,0 +1,76 @@
+package http
+
+import (
+	"net/http"
+	"path"
+	"strings"
+)
+
+// Credentials are applied to the requests sent to the hosts matching Host
+type Credentials struct {
+	// Host is a hostname, optionally with a port, or a glob
+	// pattern (i.e. *.example.com)
+	Host string
+	// Username and Password are sent with basic authentication
+	Username string
+	Password string
+	// Token is sent as a bearer token
+	Token string
+	// Headers are added to the requests
+	Headers map[string]string
+}
+
+// Match returns true if the credentials apply to the given host,
+// with or without port
+func (c *Credentials) Match(host string) bool {
+	hostname, _, _ := strings.Cut(host, ":")
+
+	for _, candidate := range []string{host, hostname} {
+		if strings.EqualFold(c.Host, candidate) {
+			return true
+		}
+
+		if matched, err := path.Match(strings.ToLower(c.Host), strings.ToLower(candidate)); err == nil && matched {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Apply adds the credentials to the given request
+func (c *Credentials) Apply(req *http.Request) {
+	if c.Username != "" || c.Password != "" {
+		req.SetBasicAuth(c.Username, c.Password)
+	}
+
+	if c.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.Token)
+	}
+
+	for name, value := range c.Headers {
+		req.Header.Set(name, value)
+	}
+}
+
+// Remove removes the credentials from the given request
+func (c *Credentials) Remove(req *http.Request) {
+	if c.Username != "" || c.Password != "" || c.Token != "" {
+		req.Header.Del("Authorization")
+	}
+
+	for name := range c.Headers {
+		req.Header.Del(name)
+	}
+}
+
+// findCredentials returns the first credentials matching the given host
+func findCredentials(credentials []Credentials, host string) *Credentials {
+	for i := range credentials {
+		if credentials[i].Match(host) {
+			return &credentials[i]
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,133 @@
+package http
+
+import (
+	"bufio"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// ParseNetrc reads the machine entries of a netrc file as credentials.
+// The 'default' entry, if any, is returned last and matches every host.
+func ParseNetrc(r io.Reader) ([]Credentials, error) {
+	credentials := make([]Credentials, 0)
+
+	var (
+		current      *Credentials
+		defaultEntry *Credentials
+	)
+
+	flush := func() {
+		if current == nil {
+			return
+		}
+
+		if current.Host == "*" {
+			defaultEntry = current
+		} else {
+			credentials = append(credentials, *current)
+		}
+
+		current = nil
+	}
+
+	scanner := bufio.NewScanner(r)
+
+	inMacro := false
+
+	for scanner.Scan() {
+		line := scanner.Text()
+
+		// Macro definitions end with an empty line
+		if inMacro {
+			if strings.TrimSpace(line) == "" {
+				inMacro = false
+			}
+			continue
+		}
+
+		if strings.HasPrefix(strings.TrimSpace(line), "#") {
+			continue
+		}
+
+		fields := strings.Fields(line)
+
+		for i := 0; i < len(fields); i++ {
+			token := fields[i]
+
+			value := ""
+			if i+1 < len(fields) {
+				value = fields[i+1]
+			}
+
+			switch token {
+			case "machine":
+				flush()
+				current = &Credentials{Host: value}
+				i++
+
+			case "default":
+				flush()
+				current = &Credentials{Host: "*"}
+
+			case "login":
+				if current != nil {
+					current.Username = value
+				}
+				i++
+
+			case "password":
+				if current != nil {
+					current.Password = value
+				}
+				i++
+
+			case "account":
+				i++
+
+			case "macdef":
+				flush()
+				inMacro = true
+				i = len(fields)
+
+			default:
+				return nil, errors.Errorf("unexpected netrc token '%s'", token)
+			}
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	flush()
+
+	if defaultEntry != nil {
+		credentials = append(credentials, *defaultEntry)
+	}
+
+	return credentials, nil
+}
+
+// ReadNetrc reads the credentials of the given netrc file
+func ReadNetrc(filename string) ([]Credentials, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	defer func() {
+		if err := file.Close(); err != nil {
+			panic(errors.WithStack(err))
+		}
+	}()
+
+	credentials, err := ParseNetrc(file)
+	if err != nil {
+		return nil, errors.Wrapf(err, "could not parse netrc file '%s'", filename)
+	}
+
+	return credentials, nil
+}
@@ -0,0 +1,51 @@
+package http
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/pkg/errors"
+)
+
+func TestParseNetrc(t *testing.T) {
+	netrc := `
+# Comment
+machine gitlab.example.com
+  login oauth2
+  password gitlab-token
+
+machine wiki.example.com login bob password secret account ignored
+
+macdef init
+cd /pub
+bin
+
+default login anonymous password guest
+`
+
+	credentials, err := ParseNetrc(strings.NewReader(netrc))
+	if err != nil {
+		t.Fatalf("%+v", errors.WithStack(err))
+	}
+
+	expected := []Credentials{
+		{Host: "gitlab.example.com", Username: "oauth2", Password: "gitlab-token"},
+		{Host: "wiki.example.com", Username: "bob", Password: "secret"},
+		{Host: "*", Username: "anonymous", Password: "guest"},
+	}
+
+	if e, g := len(expected), len(credentials); e != g {
+		t.Fatalf("len(credentials): expected '%d', got '%d'", e, g)
+	}
+
+	for i := range expected {
+		e, g := expected[i], credentials[i]
+		if e.Host != g.Host || e.Username != g.Username || e.Password != g.Password {
+			t.Errorf("credentials[%d]: expected '%+v', got '%+v'", i, e, g)
+		}
+	}
+
+	if found := findCredentials(credentials, "unknown.example.com:8080"); found == nil || found.Username != "anonymous" {
+		t.Errorf("expected default credentials to match any host, got '%+v'", found)
+	}
+}
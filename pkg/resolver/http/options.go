@@ -0,0 +1,119 @@
+package http
+
+import (
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+type Options struct {
+	// Client is the http client used to fetch resources,
+	// a client with the configured timeout is used if nil
+	Client *http.Client
+	// Timeout is the maximum duration of a request
+	Timeout time.Duration
+	// CacheDir is the directory where fetched resources are stored,
+	// the cache is disabled if empty
+	CacheDir string
+	// Offline restricts the resolver to the cached resources
+	Offline bool
+	// Credentials are applied to the requests sent to the matching hosts,
+	// the first matching credentials are used
+	Credentials []Credentials
+	// Headers are added to every request
+	Headers map[string]string
+	// UserAgent is the User-Agent header of the requests
+	UserAgent string
+	// Retries is the number of times a failed request is retried
+	Retries int
+	// RetryBackoff is the delay before the first retry, doubled on each retry
+	RetryBackoff time.Duration
+}
+
+type OptionFunc func(opts *Options)
+
+const (
+	DefaultTimeout      = 30 * time.Second
+	DefaultUserAgent    = "amatl"
+	DefaultRetries      = 2
+	DefaultRetryBackoff = 500 * time.Millisecond
+)
+
+func NewOptions(funcs ...OptionFunc) *Options {
+	opts := &Options{
+		Timeout:      DefaultTimeout,
+		CacheDir:     DefaultCacheDir(),
+		Credentials:  []Credentials{},
+		Headers:      map[string]string{},
+		UserAgent:    DefaultUserAgent,
+		Retries:      DefaultRetries,
+		RetryBackoff: DefaultRetryBackoff,
+	}
+	for _, fn := range funcs {
+		fn(opts)
+	}
+	return opts
+}
+
+func WithClient(client *http.Client) OptionFunc {
+	return func(opts *Options) {
+		opts.Client = client
+	}
+}
+
+func WithTimeout(timeout time.Duration) OptionFunc {
+	return func(opts *Options) {
+		opts.Timeout = timeout
+	}
+}
+
+func WithCacheDir(cacheDir string) OptionFunc {
+	return func(opts *Options) {
+		opts.CacheDir = cacheDir
+	}
+}
+
+func WithOffline(offline bool) OptionFunc {
+	return func(opts *Options) {
+		opts.Offline = offline
+	}
+}
+
+func WithCredentials(credentials ...Credentials) OptionFunc {
+	return func(opts *Options) {
+		opts.Credentials = append(opts.Credentials, credentials...)
+	}
+}
+
+func WithHeaders(headers map[string]string) OptionFunc {
+	return func(opts *Options) {
+		for name, value := range headers {
+			opts.Headers[name] = value
+		}
+	}
+}
+
+func WithUserAgent(userAgent string) OptionFunc {
+	return func(opts *Options) {
+		opts.UserAgent = userAgent
+	}
+}
+
+func WithRetries(retries int, backoff time.Duration) OptionFunc {
+	return func(opts *Options) {
+		opts.Retries = retries
+		opts.RetryBackoff = backoff
+	}
+}
+
+// DefaultCacheDir returns the directory used to store fetched
+// resources by default, in the user cache directory
+func DefaultCacheDir() string {
+	cacheDir, err := os.UserCacheDir()
+	if err != nil {
+		cacheDir = os.TempDir()
+	}
+
+	return filepath.Join(cacheDir, "amatl", "http")
+}
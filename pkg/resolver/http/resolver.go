@@ -4,18 +4,25 @@ import (
 	"bytes"
 	"context"
 	"io"
+	"log/slog"
 	"net/http"
 	"os"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/Bornholm/amatl/pkg/resolver"
 	"github.com/pkg/errors"
 )
 
 type Resolver struct {
+	opts   *Options
+	client *http.Client
+	cache  *Cache
 }
 
 // Resolve implements layout.Resolver.
-func (*Resolver) Resolve(ctx context.Context, path resolver.Path) (io.ReadCloser, error) {
+func (r *Resolver) Resolve(ctx context.Context, path resolver.Path) (io.ReadCloser, error) {
 	scheme := path.Scheme()
 
 	// Only handle HTTP/HTTPS schemes
@@ -23,36 +30,270 @@ func (*Resolver) Resolve(ctx context.Context, path resolver.Path) (io.ReadCloser
 		return nil, errors.Errorf("http resolver can only handle http/https schemes, got: %s", scheme)
 	}
 
-	username := os.Getenv("AMATL_HTTP_BASIC_AUTH_USERNAME")
-	password := os.Getenv("AMATL_HTTP_BASIC_AUTH_PASSWORD")
+	url := path.String()
+	now := time.Now()
 
-	// Use Path's WithAuth method to handle authentication
-	requestPath := path
-	if username != "" || password != "" {
-		requestPath = path.WithAuth(username, password)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, errors.WithStack(err)
 	}
 
-	var buff bytes.Buffer
+	cache := r.cache
 
-	req, err := http.NewRequestWithContext(ctx, "GET", requestPath.String(), &buff)
-	if err != nil {
-		return nil, errors.WithStack(err)
+	// The authenticated resources are not cached, the cache
+	// being shared by all the credentials
+	if authenticated := r.authenticate(req, true); authenticated && cache != nil {
+		slog.DebugContext(ctx, "bypassing cache for authenticated request", slog.String("url", url))
+		cache = nil
+	}
+
+	var (
+		entry  *CacheEntry
+		cached []byte
+	)
+
+	if cache != nil {
+		entry, cached, err = cache.Get(url)
+		if err != nil {
+			slog.WarnContext(ctx, "could not read http cache", slog.String("url", url), slog.Any("error", errors.WithStack(err)))
+		}
+	}
+
+	if r.opts.Offline {
+		if entry == nil {
+			return nil, errors.Wrapf(resolver.ErrOfflineCacheMiss, "could not resolve '%s'", url)
+		}
+
+		slog.DebugContext(ctx, "using cached resource (offline)", slog.String("url", url))
+
+		return newReader(cached), nil
 	}
 
-	res, err := http.DefaultClient.Do(req)
+	if entry != nil && entry.IsFresh(now) {
+		slog.DebugContext(ctx, "using cached resource", slog.String("url", url))
+
+		return newReader(cached), nil
+	}
+
+	if entry != nil {
+		if entry.ETag != "" {
+			req.Header.Set("If-None-Match", entry.ETag)
+		}
+
+		if entry.LastModified != "" {
+			req.Header.Set("If-Modified-Since", entry.LastModified)
+		}
+	}
+
+	res, err := r.do(ctx, req)
 	if err != nil {
+		if entry != nil && !entry.MustRevalidate {
+			slog.WarnContext(ctx, "could not revalidate resource, using stale cached resource", slog.String("url", url), slog.Any("error", errors.WithStack(err)))
+			return newReader(cached), nil
+		}
+
 		return nil, errors.WithStack(err)
 	}
 
+	if cache == nil {
+		if res.StatusCode < http.StatusOK || res.StatusCode >= http.StatusBadRequest {
+			_ = res.Body.Close()
+			return nil, errors.Errorf("unexpected http response status '%s' (%d)", res.Status, res.StatusCode)
+		}
+
+		return res.Body, nil
+	}
+
+	defer func() {
+		if err := res.Body.Close(); err != nil {
+			slog.WarnContext(ctx, "could not close response body", slog.String("url", url), slog.Any("error", errors.WithStack(err)))
+		}
+	}()
+
+	if res.StatusCode == http.StatusNotModified && entry != nil {
+		slog.DebugContext(ctx, "cached resource not modified", slog.String("url", url))
+
+		entry.Update(res.Header, now)
+
+		if err := cache.Put(entry, nil); err != nil {
+			slog.WarnContext(ctx, "could not update http cache", slog.String("url", url), slog.Any("error", errors.WithStack(err)))
+		}
+
+		return newReader(cached), nil
+	}
+
+	if res.StatusCode >= http.StatusInternalServerError && entry != nil && !entry.MustRevalidate {
+		slog.WarnContext(ctx, "could not revalidate resource, using stale cached resource", slog.String("url", url), slog.Int("status", res.StatusCode))
+		return newReader(cached), nil
+	}
+
 	if res.StatusCode < http.StatusOK || res.StatusCode >= http.StatusBadRequest {
 		return nil, errors.Errorf("unexpected http response status '%s' (%d)", res.Status, res.StatusCode)
 	}
 
-	return res.Body, nil
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	if parseCacheControl(res.Header).Has("no-store") {
+		if err := cache.Delete(url); err != nil {
+			slog.WarnContext(ctx, "could not delete http cache entry", slog.String("url", url), slog.Any("error", errors.WithStack(err)))
+		}
+	} else {
+		if err := cache.Put(NewCacheEntry(url, res.Header, now), body); err != nil {
+			slog.WarnContext(ctx, "could not write http cache", slog.String("url", url), slog.Any("error", errors.WithStack(err)))
+		}
+	}
+
+	return newReader(body), nil
 }
 
-func NewResolver() *Resolver {
-	return &Resolver{}
+// authenticate adds the headers and the credentials associated with the
+// requested host to the request, the credentials from the environment being
+// added if withEnv is true. It returns true if the request is authenticated.
+func (r *Resolver) authenticate(req *http.Request, withEnv bool) bool {
+	if r.opts.UserAgent != "" {
+		req.Header.Set("User-Agent", r.opts.UserAgent)
+	}
+
+	for name, value := range r.opts.Headers {
+		req.Header.Set(name, value)
+	}
+
+	if credentials := findCredentials(r.opts.Credentials, req.URL.Host); credentials != nil {
+		credentials.Apply(req)
+		return true
+	}
+
+	if withEnv {
+		// Credentials from the environment are only sent
+		// to the originally requested host
+		username := os.Getenv("AMATL_HTTP_BASIC_AUTH_USERNAME")
+		password := os.Getenv("AMATL_HTTP_BASIC_AUTH_PASSWORD")
+
+		if username != "" || password != "" {
+			req.SetBasicAuth(username, password)
+		}
+	}
+
+	return req.Header.Get("Authorization") != ""
+}
+
+// do sends the request, retrying with an exponential backoff
+// on network errors and on server errors
+func (r *Resolver) do(ctx context.Context, req *http.Request) (*http.Response, error) {
+	backoff := r.opts.RetryBackoff
+
+	for attempt := 0; ; attempt++ {
+		res, err := r.client.Do(req)
+
+		retryable := err != nil || isRetryableStatus(res.StatusCode)
+		if !retryable || attempt >= r.opts.Retries || ctx.Err() != nil {
+			return res, errors.WithStack(err)
+		}
+
+		delay := backoff
+		if err == nil {
+			if retryAfter := parseRetryAfter(res.Header.Get("Retry-After")); retryAfter > 0 {
+				delay = retryAfter
+			}
+
+			_ = res.Body.Close()
+		}
+
+		slog.DebugContext(ctx, "retrying request", slog.String("url", req.URL.Redacted()), slog.Int("attempt", attempt+1), slog.Duration("delay", delay), slog.Any("error", err))
+
+		timer := time.NewTimer(delay)
+
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, errors.WithStack(ctx.Err())
+		case <-timer.C:
+		}
+
+		backoff *= 2
+	}
+}
+
+func isRetryableStatus(status int) bool {
+	return status == http.StatusTooManyRequests || status >= http.StatusInternalServerError
+}
+
+func parseRetryAfter(value string) time.Duration {
+	if value == "" {
+		return 0
+	}
+
+	if seconds, err := strconv.Atoi(value); err == nil {
+		return time.Duration(seconds) * time.Second
+	}
+
+	if date, err := http.ParseTime(value); err == nil {
+		return time.Until(date)
+	}
+
+	return 0
+}
+
+// checkRedirect applies the resolver policy of the request context, if any,
+// to the redirection target and replaces the credentials of the previous
+// hosts, copied from the original request, by the ones of the target host.
+// The credentials from the environment are only kept on the original host.
+func (r *Resolver) checkRedirect(req *http.Request, via []*http.Request) error {
+	if len(via) >= 10 {
+		return errors.New("stopped after 10 redirects")
+	}
+
+	if policy := resolver.ContextPolicy(req.Context()); policy != nil {
+		if err := policy.Check(resolver.Path(req.URL.String())); err != nil {
+			return errors.Wrap(err, "could not follow redirect")
+		}
+	}
+
+	sameHost := strings.EqualFold(req.URL.Host, via[0].URL.Host)
+	if !sameHost {
+		req.Header.Del("Authorization")
+	}
+
+	for _, previous := range via {
+		if credentials := findCredentials(r.opts.Credentials, previous.URL.Host); credentials != nil {
+			credentials.Remove(req)
+		}
+	}
+
+	r.authenticate(req, sameHost)
+
+	return nil
+}
+
+func newReader(data []byte) io.ReadCloser {
+	return io.NopCloser(bytes.NewReader(data))
+}
+
+func NewResolver(funcs ...OptionFunc) *Resolver {
+	opts := NewOptions(funcs...)
+
+	var cache *Cache
+	if opts.CacheDir != "" {
+		cache = NewCache(opts.CacheDir)
+	}
+
+	r := &Resolver{
+		opts:  opts,
+		cache: cache,
+	}
+
+	r.client = opts.Client
+	if r.client == nil {
+		r.client = &http.Client{
+			Timeout:       opts.Timeout,
+			CheckRedirect: r.checkRedirect,
+		}
+	}
+
+	return r
 }
 
 var _ resolver.Resolver = &Resolver{}
@@ -0,0 +1,359 @@
+package http
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Bornholm/amatl/pkg/resolver"
+	"github.com/pkg/errors"
+)
+
+func TestResolverCache(t *testing.T) {
+	var requests, notModified atomic.Int32
+
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("/max-age", func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		w.Header().Set("Cache-Control", "max-age=3600")
+		_, _ = w.Write([]byte("max-age"))
+	})
+
+	mux.HandleFunc("/etag", func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		w.Header().Set("Cache-Control", "no-cache")
+		w.Header().Set("ETag", `"v1"`)
+
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			notModified.Add(1)
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+
+		_, _ = w.Write([]byte("etag"))
+	})
+
+	mux.HandleFunc("/no-store", func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		w.Header().Set("Cache-Control", "no-store")
+		_, _ = w.Write([]byte("no-store"))
+	})
+
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	res := NewResolver(WithCacheDir(t.TempDir()))
+
+	type testCase struct {
+		Path                string
+		ExpectedBody        string
+		ExpectedRequests    int32
+		ExpectedNotModified int32
+	}
+
+	testCases := []testCase{
+		{Path: "/max-age", ExpectedBody: "max-age", ExpectedRequests: 1},
+		{Path: "/max-age", ExpectedBody: "max-age", ExpectedRequests: 0},
+		{Path: "/etag", ExpectedBody: "etag", ExpectedRequests: 1},
+		{Path: "/etag", ExpectedBody: "etag", ExpectedRequests: 1, ExpectedNotModified: 1},
+		{Path: "/no-store", ExpectedBody: "no-store", ExpectedRequests: 1},
+		{Path: "/no-store", ExpectedBody: "no-store", ExpectedRequests: 1},
+	}
+
+	for _, tc := range testCases {
+		requests.Store(0)
+		notModified.Store(0)
+
+		body := resolveString(t, res, server.URL+tc.Path)
+
+		if e, g := tc.ExpectedBody, body; e != g {
+			t.Errorf("%s: body: expected '%s', got '%s'", tc.Path, e, g)
+		}
+
+		if e, g := tc.ExpectedRequests, requests.Load(); e != g {
+			t.Errorf("%s: requests: expected '%d', got '%d'", tc.Path, e, g)
+		}
+
+		if e, g := tc.ExpectedNotModified, notModified.Load(); e != g {
+			t.Errorf("%s: not modified responses: expected '%d', got '%d'", tc.Path, e, g)
+		}
+	}
+}
+
+func TestResolverOffline(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("content"))
+	}))
+
+	cacheDir := t.TempDir()
+
+	online := NewResolver(WithCacheDir(cacheDir), WithRetries(0, 0))
+	resolveString(t, online, server.URL+"/cached")
+
+	server.Close()
+
+	// Stale resources are used when the server is unreachable
+	if e, g := "content", resolveString(t, online, server.URL+"/cached"); e != g {
+		t.Errorf("body: expected '%s', got '%s'", e, g)
+	}
+
+	offline := NewResolver(WithCacheDir(cacheDir), WithOffline(true))
+
+	if e, g := "content", resolveString(t, offline, server.URL+"/cached"); e != g {
+		t.Errorf("body: expected '%s', got '%s'", e, g)
+	}
+
+	_, err := offline.Resolve(context.Background(), resolver.Path(server.URL+"/missing"))
+	if !errors.Is(err, resolver.ErrOfflineCacheMiss) {
+		t.Errorf("expected error '%v', got '%v'", resolver.ErrOfflineCacheMiss, err)
+	}
+}
+
+func resolveString(t *testing.T, res *Resolver, url string) string {
+	reader, err := res.Resolve(context.Background(), resolver.Path(url))
+	if err != nil {
+		t.Fatalf("%+v", errors.WithStack(err))
+	}
+
+	defer reader.Close()
+
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		t.Fatalf("%+v", errors.WithStack(err))
+	}
+
+	return string(data)
+}
+
+func TestResolverCredentials(t *testing.T) {
+	type request struct {
+		Authorization string
+		UserAgent     string
+		Custom        string
+	}
+
+	requests := make(chan request, 1)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests <- request{
+			Authorization: r.Header.Get("Authorization"),
+			UserAgent:     r.Header.Get("User-Agent"),
+			Custom:        r.Header.Get("X-Custom"),
+		}
+		_, _ = w.Write([]byte("content"))
+	}))
+	defer server.Close()
+
+	serverURL, err := url.Parse(server.URL)
+	if err != nil {
+		t.Fatalf("%+v", errors.WithStack(err))
+	}
+
+	type testCase struct {
+		Credentials []Credentials
+		Expected    request
+	}
+
+	testCases := []testCase{
+		{
+			Credentials: []Credentials{
+				{Host: "other.example.com", Token: "other"},
+				{Host: serverURL.Hostname(), Token: "secret"},
+			},
+			Expected: request{Authorization: "Bearer secret", UserAgent: "test"},
+		},
+		{
+			Credentials: []Credentials{
+				{Host: "127.0.0.*", Username: "foo", Password: "bar", Headers: map[string]string{"X-Custom": "custom"}},
+			},
+			Expected: request{Authorization: "Basic Zm9vOmJhcg==", UserAgent: "test", Custom: "custom"},
+		},
+		{
+			Credentials: []Credentials{
+				{Host: "other.example.com", Token: "other"},
+			},
+			Expected: request{UserAgent: "test"},
+		},
+	}
+
+	for i, tc := range testCases {
+		res := NewResolver(
+			WithCacheDir(""),
+			WithUserAgent("test"),
+			WithCredentials(tc.Credentials...),
+		)
+
+		resolveString(t, res, server.URL)
+
+		if e, g := tc.Expected, <-requests; e != g {
+			t.Errorf("#%d: request: expected '%+v', got '%+v'", i, e, g)
+		}
+	}
+}
+
+func TestResolverRedirectCredentials(t *testing.T) {
+	type request struct {
+		Authorization string
+		PrivateToken  string
+	}
+
+	requests := make(chan request, 1)
+
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests <- request{
+			Authorization: r.Header.Get("Authorization"),
+			PrivateToken:  r.Header.Get("PRIVATE-TOKEN"),
+		}
+		_, _ = w.Write([]byte("content"))
+	}))
+	defer target.Close()
+
+	origin := httptest.NewServer(http.RedirectHandler(target.URL, http.StatusFound))
+	defer origin.Close()
+
+	originURL, err := url.Parse(origin.URL)
+	if err != nil {
+		t.Fatalf("%+v", errors.WithStack(err))
+	}
+
+	targetURL, err := url.Parse(target.URL)
+	if err != nil {
+		t.Fatalf("%+v", errors.WithStack(err))
+	}
+
+	type testCase struct {
+		Credentials []Credentials
+		Expected    request
+	}
+
+	testCases := []testCase{
+		{
+			Credentials: []Credentials{
+				{Host: originURL.Host, Headers: map[string]string{"PRIVATE-TOKEN": "secret"}},
+			},
+			Expected: request{},
+		},
+		{
+			Credentials: []Credentials{
+				{Host: originURL.Host, Token: "secret", Headers: map[string]string{"PRIVATE-TOKEN": "secret"}},
+				{Host: targetURL.Host, Headers: map[string]string{"PRIVATE-TOKEN": "target"}},
+			},
+			Expected: request{PrivateToken: "target"},
+		},
+	}
+
+	for i, tc := range testCases {
+		res := NewResolver(
+			WithCacheDir(""),
+			WithCredentials(tc.Credentials...),
+		)
+
+		resolveString(t, res, origin.URL)
+
+		if e, g := tc.Expected, <-requests; e != g {
+			t.Errorf("#%d: request: expected '%+v', got '%+v'", i, e, g)
+		}
+	}
+}
+
+func TestResolverRedirectEnvCredentials(t *testing.T) {
+	t.Setenv("AMATL_HTTP_BASIC_AUTH_USERNAME", "foo")
+	t.Setenv("AMATL_HTTP_BASIC_AUTH_PASSWORD", "bar")
+
+	authorizations := make(chan string, 2)
+
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		authorizations <- r.Header.Get("Authorization")
+		_, _ = w.Write([]byte("content"))
+	}))
+	defer target.Close()
+
+	origin := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		authorizations <- r.Header.Get("Authorization")
+		http.Redirect(w, r, target.URL, http.StatusFound)
+	}))
+	defer origin.Close()
+
+	res := NewResolver(WithCacheDir(""))
+
+	resolveString(t, res, origin.URL)
+
+	if e, g := "Basic Zm9vOmJhcg==", <-authorizations; e != g {
+		t.Errorf("origin authorization: expected '%s', got '%s'", e, g)
+	}
+
+	if e, g := "", <-authorizations; e != g {
+		t.Errorf("target authorization: expected '%s', got '%s'", e, g)
+	}
+}
+
+func TestResolverCacheAuthenticated(t *testing.T) {
+	var requests atomic.Int32
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		w.Header().Set("Cache-Control", "max-age=3600")
+		_, _ = w.Write([]byte(r.Header.Get("Authorization")))
+	}))
+	defer server.Close()
+
+	cacheDir := t.TempDir()
+
+	authenticated := NewResolver(
+		WithCacheDir(cacheDir),
+		WithCredentials(Credentials{Host: "127.0.0.*", Token: "secret"}),
+	)
+
+	for i := 0; i < 2; i++ {
+		if e, g := "Bearer secret", resolveString(t, authenticated, server.URL); e != g {
+			t.Errorf("#%d: body: expected '%s', got '%s'", i, e, g)
+		}
+	}
+
+	if e, g := int32(2), requests.Load(); e != g {
+		t.Errorf("requests: expected %d, got %d", e, g)
+	}
+
+	anonymous := NewResolver(WithCacheDir(cacheDir))
+
+	if e, g := "", resolveString(t, anonymous, server.URL); e != g {
+		t.Errorf("anonymous body: expected '%s', got '%s'", e, g)
+	}
+}
+
+func TestResolverRetries(t *testing.T) {
+	var requests atomic.Int32
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if requests.Add(1) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		_, _ = w.Write([]byte("content"))
+	}))
+	defer server.Close()
+
+	res := NewResolver(WithCacheDir(""), WithRetries(2, time.Millisecond))
+
+	if e, g := "content", resolveString(t, res, server.URL); e != g {
+		t.Errorf("body: expected '%s', got '%s'", e, g)
+	}
+
+	if e, g := int32(3), requests.Load(); e != g {
+		t.Errorf("requests: expected '%d', got '%d'", e, g)
+	}
+
+	requests.Store(0)
+
+	res = NewResolver(WithCacheDir(""), WithRetries(1, time.Millisecond))
+
+	if _, err := res.Resolve(context.Background(), resolver.Path(server.URL)); err == nil {
+		t.Errorf("expected error after retries")
+	}
+}
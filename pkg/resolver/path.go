@@ -83,6 +83,32 @@ func (p Path) URLPath() string {
 	return string(p)
 }
 
+// LocalPath returns the local filesystem path designated by the path, if any,
+// i.e. for paths without scheme and file:// urls
+func (p Path) LocalPath() (string, bool) {
+	u, err := p.URL()
+	if err != nil {
+		return string(p), true
+	}
+
+	if u.Scheme != "file" {
+		return "", false
+	}
+
+	if u.Host != "" && u.Host != "localhost" {
+		// Handle file://host/path format (relative paths like file://testdata/test.txt)
+		return filepath.FromSlash(u.Host + u.Path), true
+	}
+
+	localPath := u.Path
+	// On Windows, convert /C:/path to C:/path
+	if len(localPath) > 2 && localPath[0] == '/' && localPath[2] == ':' {
+		localPath = localPath[1:]
+	}
+
+	return filepath.FromSlash(localPath), true
+}
+
 func (p Path) Join(paths ...Path) Path {
 	strPaths := make([]string, len(paths))
 	for i, s := range paths {
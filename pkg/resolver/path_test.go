@@ -1,6 +1,7 @@
 package resolver
 
 import (
+	"path/filepath"
 	"runtime"
 	"testing"
 )
@@ -122,6 +123,34 @@ func TestPath_Host(t *testing.T) {
 	}
 }
 
+func TestPath_LocalPath(t *testing.T) {
+	tests := []struct {
+		name     string
+		path     Path
+		expected string
+		ok       bool
+	}{
+		{"Unix path", Path("/path/to/file"), "/path/to/file", true},
+		{"Relative path", Path("path/to/file"), "path/to/file", true},
+		{"File URL", Path("file:///path/to/file"), filepath.FromSlash("/path/to/file"), true},
+		{"File URL with localhost", Path("file://localhost/path/to/file"), filepath.FromSlash("/path/to/file"), true},
+		{"Relative file URL", Path("file://testdata/test.txt"), filepath.FromSlash("testdata/test.txt"), true},
+		{"HTTP URL", Path("http://example.com/path"), "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, ok := tt.path.LocalPath()
+			if ok != tt.ok {
+				t.Errorf("LocalPath() ok = %v, want %v", ok, tt.ok)
+			}
+			if result != tt.expected {
+				t.Errorf("LocalPath() = %v, want %v", result, tt.expected)
+			}
+		})
+	}
+}
+
 func TestPath_URLPath(t *testing.T) {
 	tests := []struct {
 		name     string
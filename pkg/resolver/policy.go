@@ -0,0 +1,231 @@
+package resolver
+
+import (
+	"io"
+	"net"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// networkSchemes are the schemes of the urls whose hosts
+// are checked against the allowed and denied hosts
+var networkSchemes = []string{"http", "https", "git", "git+http", "git+https", "git+ssh"}
+
+// Policy restricts the resources which can be resolved
+type Policy struct {
+	// AllowedSchemes are the schemes of the resolvable paths, local paths
+	// having the 'file' scheme. All schemes are allowed if empty.
+	AllowedSchemes []string
+	// AllowedHosts are glob patterns (i.e. *.example.com) matching the hosts
+	// of the resolvable network urls (http, https and remote git urls).
+	// All hosts are allowed if empty.
+	AllowedHosts []string
+	// DeniedHosts are glob patterns matching the hosts of the urls
+	// which can not be resolved, even if allowed.
+	DeniedHosts []string
+	// Root is the directory local paths can not escape.
+	// Local paths are not restricted if empty.
+	Root string
+	// MaxSize is the maximum size in bytes of a resource.
+	// The size is not limited if zero or negative.
+	MaxSize int64
+}
+
+// Check returns an error wrapping ErrPolicyViolation if the
+// given path is not allowed by the policy
+func (p *Policy) Check(path Path) error {
+	scheme := path.Scheme()
+	if scheme == "" {
+		scheme = "file"
+	}
+
+	if len(p.AllowedSchemes) > 0 && !slices.Contains(p.AllowedSchemes, scheme) {
+		return errors.Wrapf(ErrPolicyViolation, "scheme '%s' of '%s' is not allowed", scheme, path)
+	}
+
+	if localPath, ok := path.LocalPath(); ok {
+		if err := p.checkRoot(localPath); err != nil {
+			return errors.Wrapf(err, "path '%s'", path)
+		}
+
+		return nil
+	}
+
+	if scheme == "git+file" {
+		if err := p.checkGitRoot(path); err != nil {
+			return errors.Wrapf(err, "path '%s'", path)
+		}
+
+		return nil
+	}
+
+	// The hosts of the other urls, i.e. amatl://document.html,
+	// are not network hosts
+	if !slices.Contains(networkSchemes, scheme) {
+		return nil
+	}
+
+	host := path.Host()
+	if host == "" {
+		return nil
+	}
+
+	if matchHost(p.DeniedHosts, host) {
+		return errors.Wrapf(ErrPolicyViolation, "host '%s' of '%s' is denied", host, path)
+	}
+
+	if len(p.AllowedHosts) > 0 && !matchHost(p.AllowedHosts, host) {
+		return errors.Wrapf(ErrPolicyViolation, "host '%s' of '%s' is not allowed", host, path)
+	}
+
+	return nil
+}
+
+// checkGitRoot checks that the repository of the given local git url, i.e.
+// git+file:///path/to/repo//docs/intro.md, is in the root directory. If the
+// repository is not separated from the file path, the whole path is checked.
+func (p *Policy) checkGitRoot(path Path) error {
+	if p.Root == "" {
+		return nil
+	}
+
+	u, err := path.URL()
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	if slices.Contains(strings.Split(u.Path, "/"), "..") {
+		return errors.Wrapf(ErrPolicyViolation, "parent directory references are not allowed in git urls with a root directory '%s'", p.Root)
+	}
+
+	repoPath := u.Path
+	if index := strings.Index(repoPath, "//"); index > 0 {
+		repoPath = repoPath[:index]
+	}
+
+	return p.checkRoot(filepath.FromSlash(u.Host + repoPath))
+}
+
+func (p *Policy) checkRoot(localPath string) error {
+	if p.Root == "" {
+		return nil
+	}
+
+	root, err := evalPath(p.Root)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	target, err := evalPath(localPath)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	if !IsWithin(root, target) {
+		return errors.Wrapf(ErrPolicyViolation, "outside of root directory '%s'", p.Root)
+	}
+
+	return nil
+}
+
+// Limit wraps the given reader to fail with an error wrapping
+// ErrPolicyViolation once more than MaxSize bytes are read
+func (p *Policy) Limit(path Path, reader io.ReadCloser) io.ReadCloser {
+	if p.MaxSize <= 0 {
+		return reader
+	}
+
+	return &limitedReader{
+		path:      path,
+		reader:    reader,
+		remaining: p.MaxSize,
+		maxSize:   p.MaxSize,
+	}
+}
+
+type limitedReader struct {
+	path      Path
+	reader    io.ReadCloser
+	remaining int64
+	maxSize   int64
+}
+
+// Read implements io.ReadCloser.
+func (r *limitedReader) Read(p []byte) (int, error) {
+	if int64(len(p)) > r.remaining+1 {
+		p = p[:r.remaining+1]
+	}
+
+	n, err := r.reader.Read(p)
+	r.remaining -= int64(n)
+
+	if r.remaining < 0 {
+		return n + int(r.remaining), errors.Wrapf(ErrPolicyViolation, "resource '%s' exceeds the maximum size of %d bytes", r.path, r.maxSize)
+	}
+
+	return n, err
+}
+
+// Close implements io.ReadCloser.
+func (r *limitedReader) Close() error {
+	return r.reader.Close()
+}
+
+// IsWithin returns true if the given absolute path is the
+// root directory or one of its descendants
+func IsWithin(root string, target string) bool {
+	rel, err := filepath.Rel(root, target)
+	if err != nil {
+		return false
+	}
+
+	return rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) && !filepath.IsAbs(rel)
+}
+
+// evalPath returns the absolute path, with symbolic links evaluated
+// for its existing part
+func evalPath(p string) (string, error) {
+	abs, err := filepath.Abs(p)
+	if err != nil {
+		return "", errors.WithStack(err)
+	}
+
+	existing, rest := abs, ""
+	for {
+		evaluated, err := filepath.EvalSymlinks(existing)
+		if err == nil {
+			return filepath.Join(evaluated, rest), nil
+		}
+
+		parent := filepath.Dir(existing)
+		if parent == existing {
+			return abs, nil
+		}
+
+		rest = filepath.Join(filepath.Base(existing), rest)
+		existing = parent
+	}
+}
+
+func matchHost(patterns []string, host string) bool {
+	hostname := host
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		hostname = h
+	}
+
+	for _, pattern := range patterns {
+		pattern = strings.ToLower(pattern)
+
+		for _, candidate := range []string{strings.ToLower(host), strings.ToLower(hostname)} {
+			if matched, err := path.Match(pattern, candidate); err == nil && matched {
+				return true
+			}
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,117 @@
+package resolver
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestPolicy_Check(t *testing.T) {
+	root := t.TempDir()
+	outside := t.TempDir()
+
+	if err := os.WriteFile(filepath.Join(outside, "secret.txt"), []byte("secret"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.Symlink(filepath.Join(outside, "secret.txt"), filepath.Join(root, "link.txt")); err != nil {
+		t.Skipf("could not create symbolic link: %v", err)
+	}
+
+	policy := &Policy{
+		AllowedSchemes: []string{"file", "git+file", "https", "git+ssh", "amatl"},
+		AllowedHosts:   []string{"*.example.com", "example.com"},
+		DeniedHosts:    []string{"internal.example.com"},
+		Root:           root,
+	}
+
+	testCases := []struct {
+		name    string
+		path    Path
+		allowed bool
+	}{
+		{"local path in root", Path(filepath.Join(root, "doc.md")), true},
+		{"file url in root", Path("file://" + filepath.ToSlash(filepath.Join(root, "doc.md"))), true},
+		{"local path outside root", Path(filepath.Join(outside, "secret.txt")), false},
+		{"relative escape", Path(filepath.Join(root, "..", "secret.txt")), false},
+		{"symbolic link escape", Path(filepath.Join(root, "link.txt")), false},
+		{"git repository in root", Path("git+file://" + filepath.ToSlash(filepath.Join(root, "repo")) + "//doc.md"), true},
+		{"git file in root", Path("git+file://" + filepath.ToSlash(filepath.Join(root, "repo", "doc.md"))), true},
+		{"git repository outside root", Path("git+file://" + filepath.ToSlash(outside) + "//secret.txt"), false},
+		{"git file outside root", Path("git+file://" + filepath.ToSlash(filepath.Join(outside, "secret.txt"))), false},
+		{"git parent directory escape", Path("git+file://" + filepath.ToSlash(filepath.Join(outside, "repo")) + "//../../" + filepath.ToSlash(root) + "/doc.md"), false},
+		{"allowed host", Path("https://docs.example.com/doc.md"), true},
+		{"allowed host with port", Path("https://example.com:8443/doc.md"), true},
+		{"denied host", Path("https://internal.example.com/doc.md"), false},
+		{"unknown host", Path("https://169.254.169.254/latest"), false},
+		{"disallowed scheme", Path("http://docs.example.com/doc.md"), false},
+		{"unknown git host", Path("git+ssh://git@forge.test/repo.git//doc.md"), false},
+		{"embedded layout", Path("amatl://document.html"), true},
+		{"embedded asset", Path("amatl://assets/github-markdown-light.min.css"), true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := policy.Check(tc.path)
+
+			if tc.allowed && err != nil {
+				t.Errorf("expected '%s' to be allowed, got error '%v'", tc.path, err)
+			}
+
+			if !tc.allowed && !errors.Is(err, ErrPolicyViolation) {
+				t.Errorf("expected '%s' to be denied, got error '%v'", tc.path, err)
+			}
+		})
+	}
+}
+
+func TestPolicy_MaxSize(t *testing.T) {
+	registry := NewRegistry()
+	registry.Register("mock", &mockResolver{content: "0123456789"})
+
+	testCases := []struct {
+		maxSize int64
+		allowed bool
+	}{
+		{maxSize: 0, allowed: true},
+		{maxSize: 10, allowed: true},
+		{maxSize: 9, allowed: false},
+	}
+
+	for _, tc := range testCases {
+		ctx := WithPolicy(context.Background(), &Policy{MaxSize: tc.maxSize})
+
+		reader, err := registry.Resolve(ctx, Path("mock://resource"))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		data, err := io.ReadAll(reader)
+
+		if tc.allowed {
+			if err != nil {
+				t.Errorf("max size %d: unexpected error '%v'", tc.maxSize, err)
+			}
+
+			if e, g := "0123456789", string(data); e != g {
+				t.Errorf("max size %d: expected '%s', got '%s'", tc.maxSize, e, g)
+			}
+		} else if !errors.Is(err, ErrPolicyViolation) {
+			t.Errorf("max size %d: expected error '%v', got '%v'", tc.maxSize, ErrPolicyViolation, err)
+		}
+	}
+}
+
+func TestPolicy_Registry(t *testing.T) {
+	registry := NewRegistry()
+	registry.Register("mock", &mockResolver{content: "content"})
+
+	ctx := WithPolicy(context.Background(), &Policy{AllowedSchemes: []string{"file"}})
+
+	if _, err := registry.Resolve(ctx, Path("mock://resource")); !errors.Is(err, ErrPolicyViolation) {
+		t.Errorf("expected error '%v', got '%v'", ErrPolicyViolation, err)
+	}
+}
@@ -26,30 +26,115 @@ func (r *Registry) Resolve(ctx context.Context, path Path) (io.ReadCloser, error
 
 	}
 
-	// Now determine the scheme from the resolved path
-	scheme := resolvedPath.Scheme()
-
-	resolver, exists := r.resolvers[scheme]
-	if !exists {
-		if r.defaultResolver != "" {
-			resolver = r.resolvers[r.defaultResolver]
+	policy := ContextPolicy(ctx)
+	if policy != nil {
+		if err := policy.Check(resolvedPath); err != nil {
+			return nil, errors.WithStack(err)
 		}
+	}
 
-		if resolver == nil {
-			return nil, errors.Wrapf(ErrSchemeNotRegistered, "could not resolve path '%s'", resolvedPath)
-		}
+	// Now determine the scheme from the resolved path
+	resolver, err := r.getResolver(resolvedPath)
+	if err != nil {
+		return nil, errors.WithStack(err)
 	}
 
 	slog.DebugContext(ctx, "resolving path", slog.String("path", resolvedPath.String()))
 
 	reader, err := resolver.Resolve(ctx, resolvedPath)
 	if err != nil {
+		if tracker := ContextTracker(ctx); tracker != nil {
+			tracker.Fail(resolvedPath)
+		}
+
 		return nil, errors.WithStack(err)
 	}
 
+	if policy != nil {
+		reader = policy.Limit(resolvedPath, reader)
+	}
+
+	if tracker := ContextTracker(ctx); tracker != nil {
+		tracked, err := tracker.Track(resolvedPath, reader)
+		if err != nil {
+			_ = reader.Close()
+			return nil, errors.WithStack(err)
+		}
+
+		reader = tracked
+	}
+
 	return reader, nil
 }
 
+// Glob implements Lister.
+func (r *Registry) Glob(ctx context.Context, pattern Path) ([]Path, error) {
+	ctx = WithResolver(ctx, r)
+
+	workDir := ContextWorkDir(ctx)
+	resolvedPattern := pattern
+	if workDir != "" && !pattern.IsAbs() {
+		resolvedPattern = workDir.JoinPath(pattern.String())
+	}
+
+	policy := ContextPolicy(ctx)
+	if policy != nil {
+		if err := policy.Check(resolvedPattern); err != nil {
+			return nil, errors.WithStack(err)
+		}
+	}
+
+	resolver, err := r.getResolver(resolvedPattern)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	lister, ok := resolver.(Lister)
+	if !ok {
+		return nil, errors.Wrapf(ErrListNotSupported, "could not list paths matching '%s'", resolvedPattern)
+	}
+
+	slog.DebugContext(ctx, "listing paths", slog.String("pattern", resolvedPattern.String()))
+
+	// The pattern is tracked even if the listing fails,
+	// to list it again once its directory is created
+	if tracker := ContextTracker(ctx); tracker != nil {
+		tracker.Glob(resolvedPattern)
+	}
+
+	paths, err := lister.Glob(ctx, resolvedPattern)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	if policy != nil {
+		for _, p := range paths {
+			if err := policy.Check(p); err != nil {
+				return nil, errors.WithStack(err)
+			}
+		}
+	}
+
+	return paths, nil
+}
+
+// getResolver returns the resolver of the scheme of
+// the given path, or the default resolver
+func (r *Registry) getResolver(path Path) (Resolver, error) {
+	resolver, exists := r.resolvers[path.Scheme()]
+	if !exists {
+		if r.defaultResolver != "" {
+			resolver = r.resolvers[r.defaultResolver]
+		}
+
+		if resolver == nil {
+			return nil, errors.Wrapf(ErrSchemeNotRegistered, "could not resolve path '%s'", path)
+		}
+	}
+
+	return resolver, nil
+}
+
 func (r *Registry) Register(scheme string, resolver Resolver) {
 	r.resolvers[scheme] = resolver
 }
@@ -80,4 +165,7 @@ func NewRegistry() *Registry {
 	}
 }
 
-var _ Resolver = &Registry{}
+var (
+	_ Resolver = &Registry{}
+	_ Lister   = &Registry{}
+)
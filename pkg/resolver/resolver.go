@@ -8,3 +8,9 @@ import (
 type Resolver interface {
 	Resolve(ctx context.Context, path Path) (io.ReadCloser, error)
 }
+
+// Lister is implemented by the resolvers able to
+// list the paths matching a glob pattern
+type Lister interface {
+	Glob(ctx context.Context, pattern Path) ([]Path, error)
+}
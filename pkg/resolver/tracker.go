@@ -0,0 +1,155 @@
+package resolver
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"slices"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// Dependency describes a resource resolved during a rendering
+type Dependency struct {
+	Path   Path   `json:"path"`
+	Scheme string `json:"scheme"`
+	// Hash is the hex encoded SHA-256 of the resource content
+	Hash string `json:"hash"`
+}
+
+// Tracker records every path successfully resolved through a Registry,
+// in resolution order and without duplicates, the paths which
+// could not be resolved and the listed glob patterns.
+type Tracker struct {
+	mutex        sync.Mutex
+	dependencies []*Dependency
+	index        map[Path]*Dependency
+	failed       []Path
+	patterns     []Path
+}
+
+// Track records the given path and the hash of the resource content,
+// read entirely to do so, and returns a reader of this content.
+func (t *Tracker) Track(path Path, reader io.ReadCloser) (io.ReadCloser, error) {
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, errors.Wrapf(err, "could not read resource '%s'", path)
+	}
+
+	sum := sha256.Sum256(data)
+
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	dependency, exists := t.index[path]
+	if !exists {
+		scheme := path.Scheme()
+		if scheme == "" {
+			scheme = "file"
+		}
+
+		dependency = &Dependency{
+			Path:   path,
+			Scheme: scheme,
+		}
+
+		t.index[path] = dependency
+		t.dependencies = append(t.dependencies, dependency)
+	}
+
+	dependency.Hash = hex.EncodeToString(sum[:])
+
+	return &trackedReader{
+		Reader: bytes.NewReader(data),
+		closer: reader,
+	}, nil
+}
+
+// Fail records the given path as not resolved
+func (t *Tracker) Fail(path Path) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	if slices.Contains(t.failed, path) {
+		return
+	}
+
+	t.failed = append(t.failed, path)
+}
+
+// FailedPaths returns the paths which could not be resolved
+func (t *Tracker) FailedPaths() []Path {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	return slices.Clone(t.failed)
+}
+
+// Glob records the given pattern as listed
+func (t *Tracker) Glob(pattern Path) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	if slices.Contains(t.patterns, pattern) {
+		return
+	}
+
+	t.patterns = append(t.patterns, pattern)
+}
+
+// Patterns returns the listed glob patterns
+func (t *Tracker) Patterns() []Path {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	return slices.Clone(t.patterns)
+}
+
+// Paths returns the tracked paths
+func (t *Tracker) Paths() []Path {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	paths := make([]Path, 0, len(t.dependencies))
+	for _, d := range t.dependencies {
+		paths = append(paths, d.Path)
+	}
+
+	return paths
+}
+
+// Dependencies returns a copy of the tracked dependencies
+func (t *Tracker) Dependencies() []Dependency {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	dependencies := make([]Dependency, 0, len(t.dependencies))
+	for _, d := range t.dependencies {
+		dependencies = append(dependencies, *d)
+	}
+
+	return dependencies
+}
+
+func NewTracker() *Tracker {
+	return &Tracker{
+		dependencies: make([]*Dependency, 0),
+		index:        make(map[Path]*Dependency),
+		failed:       make([]Path, 0),
+		patterns:     make([]Path, 0),
+	}
+}
+
+type trackedReader struct {
+	*bytes.Reader
+	closer io.Closer
+}
+
+// Close implements io.ReadCloser.
+func (r *trackedReader) Close() error {
+	return r.closer.Close()
+}
+
+var _ io.ReadCloser = &trackedReader{}
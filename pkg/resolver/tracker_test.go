@@ -0,0 +1,192 @@
+package resolver
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"slices"
+	"testing"
+)
+
+func TestTracker(t *testing.T) {
+	registry := NewRegistry()
+	registry.Register("mock", &mockResolver{content: "tracked content"})
+	registry.Register("other", &mockResolver{content: "other content"})
+
+	tracker := NewTracker()
+	ctx := WithTracker(context.Background(), tracker)
+
+	paths := []Path{
+		"mock://example.com/first",
+		"other://example.com/second",
+		"mock://example.com/first",
+	}
+
+	for _, p := range paths {
+		reader, err := registry.Resolve(ctx, p)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		if _, err := io.ReadAll(reader); err != nil {
+			t.Fatalf("Failed to read content: %v", err)
+		}
+
+		reader.Close()
+	}
+
+	if _, err := registry.Resolve(ctx, "unknown://example.com"); err == nil {
+		t.Fatalf("Expected error for unregistered scheme")
+	}
+
+	dependencies := tracker.Dependencies()
+
+	if e, g := 2, len(dependencies); e != g {
+		t.Fatalf("len(dependencies): expected %d, got %d", e, g)
+	}
+
+	expected := []struct {
+		path    Path
+		scheme  string
+		content string
+	}{
+		{"mock://example.com/first", "mock", "tracked content"},
+		{"other://example.com/second", "other", "other content"},
+	}
+
+	for i, e := range expected {
+		d := dependencies[i]
+
+		if d.Path != e.path {
+			t.Errorf("dependencies[%d].Path: expected %q, got %q", i, e.path, d.Path)
+		}
+
+		if d.Scheme != e.scheme {
+			t.Errorf("dependencies[%d].Scheme: expected %q, got %q", i, e.scheme, d.Scheme)
+		}
+
+		sum := sha256.Sum256([]byte(e.content))
+		if h := hex.EncodeToString(sum[:]); d.Hash != h {
+			t.Errorf("dependencies[%d].Hash: expected %q, got %q", i, h, d.Hash)
+		}
+	}
+}
+
+func TestTracker_FailedPaths(t *testing.T) {
+	registry := NewRegistry()
+	registry.Register("mock", &mockResolver{content: "tracked content"})
+	registry.Register("missing", &mockResolver{err: errors.New("not found")})
+
+	tracker := NewTracker()
+	ctx := WithTracker(context.Background(), tracker)
+
+	for _, p := range []Path{"mock://example.com/found", "missing://example.com/first", "missing://example.com/first"} {
+		reader, err := registry.Resolve(ctx, p)
+		if err != nil {
+			continue
+		}
+
+		reader.Close()
+	}
+
+	if e, g := []Path{"mock://example.com/found"}, tracker.Paths(); !slices.Equal(e, g) {
+		t.Errorf("Paths(): expected %v, got %v", e, g)
+	}
+
+	if e, g := []Path{"missing://example.com/first"}, tracker.FailedPaths(); !slices.Equal(e, g) {
+		t.Errorf("FailedPaths(): expected %v, got %v", e, g)
+	}
+}
+
+func TestTracker_Patterns(t *testing.T) {
+	registry := NewRegistry()
+	registry.Register("mock", &mockLister{})
+
+	tracker := NewTracker()
+	ctx := WithTracker(context.Background(), tracker)
+
+	pattern := Path("mock://example.com/adr-*.md")
+
+	for i := 0; i < 2; i++ {
+		if _, err := registry.Glob(ctx, pattern); err != nil {
+			t.Fatalf("%+v", err)
+		}
+	}
+
+	if e, g := []Path{pattern}, tracker.Patterns(); !slices.Equal(e, g) {
+		t.Errorf("Patterns(): expected %v, got %v", e, g)
+	}
+}
+
+func TestTracker_DefaultScheme(t *testing.T) {
+	tracker := NewTracker()
+
+	reader, err := tracker.Track(Path("/tmp/document.md"), io.NopCloser(&mockReader{}))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	reader.Close()
+
+	dependencies := tracker.Dependencies()
+	if e, g := 1, len(dependencies); e != g {
+		t.Fatalf("len(dependencies): expected %d, got %d", e, g)
+	}
+
+	if e, g := "file", dependencies[0].Scheme; e != g {
+		t.Errorf("Scheme: expected %q, got %q", e, g)
+	}
+
+	// The hash is computed when the resource is resolved,
+	// even if it is not read
+	sum := sha256.Sum256([]byte{})
+	if e, g := hex.EncodeToString(sum[:]), dependencies[0].Hash; e != g {
+		t.Errorf("Hash: expected %q, got %q", e, g)
+	}
+}
+
+func TestTracker_UnreadContent(t *testing.T) {
+	registry := NewRegistry()
+	registry.Register("mock", &mockResolver{content: "tracked content"})
+
+	tracker := NewTracker()
+	ctx := WithTracker(context.Background(), tracker)
+
+	reader, err := registry.Resolve(ctx, "mock://example.com/partial")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	buff := make([]byte, 7)
+	if _, err := io.ReadFull(reader, buff); err != nil {
+		t.Fatalf("Failed to read content: %v", err)
+	}
+
+	reader.Close()
+
+	if e, g := "tracked", string(buff); e != g {
+		t.Errorf("content: expected %q, got %q", e, g)
+	}
+
+	sum := sha256.Sum256([]byte("tracked content"))
+	if e, g := hex.EncodeToString(sum[:]), tracker.Dependencies()[0].Hash; e != g {
+		t.Errorf("Hash: expected %q, got %q", e, g)
+	}
+}
+
+type mockReader struct{}
+
+func (r *mockReader) Read(p []byte) (int, error) {
+	return 0, io.EOF
+}
+
+type mockLister struct {
+	mockResolver
+}
+
+// Glob implements Lister.
+func (*mockLister) Glob(ctx context.Context, pattern Path) ([]Path, error) {
+	return []Path{}, nil
+}